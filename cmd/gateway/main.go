@@ -22,9 +22,34 @@ func main() {
 
 	metrics := app.NewMetrics(prometheus.DefaultRegisterer)
 
-	svc := app.NewService(cfg, logger, metrics, app.SimulatedModelClient{})
+	discoverCtx, cancelDiscovery := context.WithTimeout(context.Background(), 10*time.Second)
+	cfg, err := app.DiscoverModels(discoverCtx, cfg)
+	cancelDiscovery()
+	if err != nil {
+		logger.Warn("model discovery incomplete", "err", err)
+	}
+
+	router, err := app.NewRouter(cfg, metrics, logger)
+	if err != nil {
+		logger.Error("invalid provider config", "err", err)
+		os.Exit(1)
+	}
+	modelClient := app.NewRetryingClient(router, cfg.Retry, metrics)
+
+	probeCtx, stopProbes := context.WithCancel(context.Background())
+	defer stopProbes()
+	go router.RunHealthChecks(probeCtx)
+
+	svc := app.NewService(cfg, logger, metrics, modelClient)
 	handler := httpapi.NewHandler(logger, svc)
 
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	jobsDone := make(chan struct{})
+	go func() {
+		svc.RunJobs(jobsCtx)
+		close(jobsDone)
+	}()
+
 	server := &http.Server{
 		Addr:              cfg.ListenAddr,
 		Handler:           handler,
@@ -49,5 +74,12 @@ func main() {
 		logger.Error("shutdown failed", "err", err)
 		os.Exit(1)
 	}
+	// Running jobs are interrupted and queued again for the next start.
+	stopJobs()
+	<-jobsDone
+	if err := svc.Shutdown(ctx); err != nil {
+		logger.Error("background work not finished", "err", err)
+		os.Exit(1)
+	}
 	logger.Info("gateway stopped")
 }
@@ -0,0 +1,30 @@
+package app
+
+import "github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+
+// aliasResolver maps virtual model names such as "fast" to real models.
+// Team aliases take precedence over global ones.
+type aliasResolver struct {
+	global map[string]string
+	byTeam map[string]map[string]string
+}
+
+func newAliasResolver(cfg config.Config) aliasResolver {
+	a := aliasResolver{global: cfg.ModelAliases, byTeam: make(map[string]map[string]string)}
+	for _, t := range cfg.Teams {
+		if len(t.ModelAliases) > 0 {
+			a.byTeam[t.Name] = t.ModelAliases
+		}
+	}
+	return a
+}
+
+// resolve returns the model that name stands for in team's requests, or
+// false when name is not an alias. Aliases resolve in a single step.
+func (a aliasResolver) resolve(team, name string) (string, bool) {
+	if model, ok := a.byTeam[team][name]; ok {
+		return model, true
+	}
+	model, ok := a.global[name]
+	return model, ok
+}
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+func TestAliasResolvesBeforePolicyAndIsAudited(t *testing.T) {
+	cfg := config.Default()
+	cfg.ModelAliases["triage"] = "claude-3-5-sonnet"
+	cfg.Teams[0].ModelAliases = map[string]string{"fast": "gpt-4.1-mini"}
+	cfg.Teams[0].AllowedModels = append(cfg.Teams[0].AllowedModels, "triage")
+	svc, principal := newTestService(t, cfg, SimulatedModelClient{})
+	ctx := context.Background()
+
+	resp, appErr := svc.HandleCompletion(ctx, "req-1", principal, contracts.CompletionRequest{Model: "fast", Input: "hi"})
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	if resp.Model != "gpt-4.1-mini" || resp.Alias != "fast" {
+		t.Fatalf("expected the team override to win, got %+v", resp)
+	}
+	if ev := svc.AuditEvents(principal, 1)[0]; ev.Alias != "fast" || ev.RequestedModel != "gpt-4.1-mini" {
+		t.Fatalf("expected alias and resolved model in the audit event, got %+v", ev)
+	}
+
+	// An allowlisted alias admits its model, which is not allowlisted itself.
+	if resp, appErr := svc.HandleCompletion(ctx, "req-2", principal, contracts.CompletionRequest{Model: "triage", Input: "hi"}); appErr != nil || resp.Model != "claude-3-5-sonnet" {
+		t.Fatalf("expected the allowlisted alias to be served, got %+v %v", resp, appErr)
+	}
+	if _, appErr := svc.HandleCompletion(ctx, "req-3", principal, contracts.CompletionRequest{Model: "claude-3-5-sonnet", Input: "hi"}); appErr == nil || appErr.Message != "model_not_allowed_for_team" {
+		t.Fatalf("expected the model itself to stay denied, got %v", appErr)
+	}
+}
@@ -0,0 +1,371 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+const (
+	anthropicVersion          = "2023-06-01"
+	defaultAnthropicMaxTokens = 1024
+)
+
+// AnthropicClient talks to an upstream speaking the Anthropic Messages API.
+type AnthropicClient struct {
+	baseURL      string
+	apiKey       string
+	systemPrompt string
+	maxTokens    int
+	timeout      time.Duration
+	httpClient   *http.Client
+}
+
+func NewAnthropicClient(p config.ProviderConfig) *AnthropicClient {
+	baseURL := strings.TrimRight(p.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com"
+	}
+	maxTokens := p.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultAnthropicMaxTokens
+	}
+	timeout := providerTimeout(p)
+	return &AnthropicClient{
+		baseURL:      baseURL,
+		apiKey:       p.APIKey,
+		systemPrompt: p.SystemPrompt,
+		maxTokens:    maxTokens,
+		timeout:      timeout,
+		httpClient:   newProviderHTTPClient(timeout),
+	}
+}
+
+// anthropicMessage content is either a plain string or a list of
+// anthropicContentBlock when the turn carries tool use or tool results.
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+}
+
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type anthropicRequest struct {
+	Model         string               `json:"model"`
+	System        string               `json:"system,omitempty"`
+	Messages      []anthropicMessage   `json:"messages"`
+	Tools         []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice    *anthropicToolChoice `json:"tool_choice,omitempty"`
+	MaxTokens     int                  `json:"max_tokens"`
+	Temperature   *float64             `json:"temperature,omitempty"`
+	TopP          *float64             `json:"top_p,omitempty"`
+	StopSequences []string             `json:"stop_sequences,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+}
+
+// anthropicContentBlock covers text, tool_use and tool_result blocks in both
+// directions.
+type anthropicContentBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type anthropicResponse struct {
+	Content []anthropicContentBlock `json:"content"`
+	Usage   anthropicUsage          `json:"usage"`
+}
+
+type anthropicErrorResponse struct {
+	Type  string `json:"type"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// anthropicStreamEvent covers the fields used from message_start,
+// content_block_start, content_block_delta, message_delta and error events.
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Message struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	ContentBlock anthropicContentBlock `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+	} `json:"delta"`
+	Usage *anthropicUsage `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (c *AnthropicClient) Complete(ctx context.Context, req ModelRequest) (ModelResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.post(ctx, c.request(req, false))
+	if err != nil {
+		return ModelResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxUpstreamBodyBytes))
+	if err != nil {
+		return ModelResponse{}, upstreamTransportError("anthropic", err)
+	}
+	var out anthropicResponse
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return ModelResponse{}, upstreamDecodeError("anthropic", err)
+	}
+	var text strings.Builder
+	var calls []contracts.ToolCall
+	for _, block := range out.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			calls = append(calls, contracts.ToolCall{ID: block.ID, Name: block.Name, Arguments: string(block.Input)})
+		}
+	}
+	return ModelResponse{
+		Output:       text.String(),
+		ToolCalls:    calls,
+		InputTokens:  out.Usage.InputTokens,
+		OutputTokens: out.Usage.OutputTokens,
+	}, nil
+}
+
+func (c *AnthropicClient) Stream(ctx context.Context, req ModelRequest, onDelta func(string) error) (ModelResponse, error) {
+	resp, err := c.post(ctx, c.request(req, true))
+	if err != nil {
+		return ModelResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	var out ModelResponse
+	var text strings.Builder
+	// tool_use blocks open with id and name; their input arrives as JSON
+	// fragments on the block's index.
+	var calls []contracts.ToolCall
+	callAt := make(map[int]int)
+	err = readSSE(resp.Body, func(_, data string) error {
+		var ev anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return upstreamDecodeError("anthropic", err)
+		}
+		switch ev.Type {
+		case "message_start":
+			out.InputTokens = ev.Message.Usage.InputTokens
+		case "content_block_start":
+			if ev.ContentBlock.Type == "tool_use" {
+				callAt[ev.Index] = len(calls)
+				calls = append(calls, contracts.ToolCall{ID: ev.ContentBlock.ID, Name: ev.ContentBlock.Name})
+			}
+		case "content_block_delta":
+			if ev.Delta.Type == "input_json_delta" {
+				if i, ok := callAt[ev.Index]; ok {
+					calls[i].Arguments += ev.Delta.PartialJSON
+				}
+				return nil
+			}
+			if ev.Delta.Type != "text_delta" || ev.Delta.Text == "" {
+				return nil
+			}
+			text.WriteString(ev.Delta.Text)
+			return onDelta(ev.Delta.Text)
+		case "message_delta":
+			if ev.Usage != nil {
+				out.OutputTokens = ev.Usage.OutputTokens
+			}
+		case "message_stop":
+			return errStreamDone
+		case "error":
+			return upstreamStatusError("anthropic", anthropicErrorStatus(ev.Error.Type, http.StatusBadGateway), ev.Error.Message)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStreamDone) {
+		return ModelResponse{}, streamError("anthropic", err)
+	}
+	out.Output = text.String()
+	for i := range calls {
+		if calls[i].Arguments == "" {
+			calls[i].Arguments = "{}"
+		}
+	}
+	out.ToolCalls = calls
+	return out, nil
+}
+
+// request translates the conversation into the Messages API shape: system
+// turns are lifted into the top-level system prompt (after the configured
+// one), and user/assistant turns are passed through in order. Assistant tool
+// calls become tool_use blocks, and tool turns become tool_result blocks in a
+// user turn (consecutive results share one turn, as the API requires).
+func (c *AnthropicClient) request(req ModelRequest, stream bool) anthropicRequest {
+	var system []string
+	if c.systemPrompt != "" {
+		system = append(system, c.systemPrompt)
+	}
+	messages := make([]anthropicMessage, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		switch {
+		case m.Role == RoleSystem:
+			system = append(system, m.Content)
+		case m.Role == RoleTool:
+			result := anthropicContentBlock{Type: "tool_result", ToolUseID: m.ToolCallID, Content: m.Content}
+			if n := len(messages); n > 0 && messages[n-1].Role == RoleUser {
+				if blocks, ok := messages[n-1].Content.([]anthropicContentBlock); ok {
+					messages[n-1].Content = append(blocks, result)
+					continue
+				}
+			}
+			messages = append(messages, anthropicMessage{Role: RoleUser, Content: []anthropicContentBlock{result}})
+		case len(m.ToolCalls) > 0:
+			blocks := make([]anthropicContentBlock, 0, 1+len(m.ToolCalls))
+			if m.Content != "" {
+				blocks = append(blocks, anthropicContentBlock{Type: "text", Text: m.Content})
+			}
+			for _, call := range m.ToolCalls {
+				input := json.RawMessage(call.Arguments)
+				if !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, anthropicContentBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: input})
+			}
+			messages = append(messages, anthropicMessage{Role: m.Role, Content: blocks})
+		default:
+			messages = append(messages, anthropicMessage{Role: m.Role, Content: m.Content})
+		}
+	}
+	if instruction := formatInstruction(req.ResponseFormat); instruction != "" {
+		system = append(system, instruction)
+	}
+	payload := anthropicRequest{
+		Model:         req.Model,
+		System:        strings.Join(system, "\n\n"),
+		Messages:      messages,
+		MaxTokens:     c.maxTokens,
+		Temperature:   req.Temperature,
+		TopP:          req.TopP,
+		StopSequences: req.Stop,
+		Stream:        stream,
+	}
+	if req.MaxTokens > 0 {
+		payload.MaxTokens = req.MaxTokens
+	}
+	for _, t := range req.Tools {
+		schema := t.Parameters
+		if len(schema) == 0 {
+			schema = json.RawMessage(`{"type":"object"}`)
+		}
+		payload.Tools = append(payload.Tools, anthropicTool{Name: t.Name, Description: t.Description, InputSchema: schema})
+	}
+	switch req.ToolChoice {
+	case "", ToolChoiceAuto:
+	case ToolChoiceNone:
+		payload.ToolChoice = &anthropicToolChoice{Type: "none"}
+	case ToolChoiceRequired:
+		payload.ToolChoice = &anthropicToolChoice{Type: "any"}
+	default:
+		payload.ToolChoice = &anthropicToolChoice{Type: "tool", Name: req.ToolChoice}
+	}
+	return payload
+}
+
+// post sends a Messages API request; do maps non-2xx responses to AppErrors.
+// The caller owns the returned body.
+func (c *AnthropicClient) post(ctx context.Context, payload anthropicRequest) (*http.Response, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(ctx, http.MethodPost, "/v1/messages", bytes.NewReader(body))
+}
+
+// Probe lists a single model as a cheap health check.
+func (c *AnthropicClient) Probe(ctx context.Context) error {
+	resp, err := c.do(ctx, http.MethodGet, "/v1/models?limit=1", nil)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxUpstreamBodyBytes))
+	return resp.Body.Close()
+}
+
+func (c *AnthropicClient) do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+	if c.apiKey != "" {
+		httpReq.Header.Set("x-api-key", c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, upstreamTransportError("anthropic", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxUpstreamBodyBytes))
+		var envelope anthropicErrorResponse
+		_ = json.Unmarshal(raw, &envelope)
+		upstreamErr := upstreamStatusError("anthropic", anthropicErrorStatus(envelope.Error.Type, resp.StatusCode), envelope.Error.Message)
+		upstreamErr.RetryAfter = parseRetryAfter(resp.Header)
+		return nil, upstreamErr
+	}
+	return resp, nil
+}
+
+// anthropicErrorStatus prefers the typed error envelope over the raw status, so
+// e.g. overloaded_error (HTTP 529) is treated like any other 5xx.
+func anthropicErrorStatus(errType string, status int) int {
+	switch errType {
+	case "rate_limit_error":
+		return http.StatusTooManyRequests
+	case "authentication_error", "permission_error":
+		return http.StatusUnauthorized
+	case "invalid_request_error", "not_found_error", "request_too_large":
+		return http.StatusBadRequest
+	case "overloaded_error", "api_error":
+		return http.StatusServiceUnavailable
+	}
+	return status
+}
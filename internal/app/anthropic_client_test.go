@@ -0,0 +1,142 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+func TestAnthropicClientCompleteTranslatesRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "ak-test" || r.Header.Get("anthropic-version") == "" {
+			t.Errorf("missing anthropic headers: %v", r.Header)
+		}
+		var body anthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body.System != "be brief" || body.MaxTokens != 256 || len(body.Messages) != 1 || body.Messages[0].Role != "user" {
+			t.Errorf("unexpected request body: %+v", body)
+		}
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"part one, "},{"type":"text","text":"part two"}],"usage":{"input_tokens":9,"output_tokens":4}}`))
+	}))
+	defer srv.Close()
+
+	client := NewAnthropicClient(config.ProviderConfig{BaseURL: srv.URL, APIKey: "ak-test", SystemPrompt: "be brief", MaxTokens: 256})
+	resp, err := client.Complete(context.Background(), ModelRequest{Model: "claude-3-5-sonnet", Messages: userTurn("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Output != "part one, part two" || resp.InputTokens != 9 || resp.OutputTokens != 4 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestAnthropicClientMapsErrorEnvelope(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(529)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`))
+	}))
+	defer srv.Close()
+
+	client := NewAnthropicClient(config.ProviderConfig{BaseURL: srv.URL})
+	_, err := client.Complete(context.Background(), ModelRequest{Model: "claude-3-5-sonnet", Messages: userTurn("hello")})
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.Code != CodeUpstreamUnavailable {
+		t.Fatalf("expected %s, got %v", CodeUpstreamUnavailable, err)
+	}
+}
+
+func TestAnthropicClientStreamParsesEvents(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":7,\"output_tokens\":1}}}\n\n" +
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi \"}}\n\n" +
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"there\"}}\n\n" +
+			"event: message_delta\ndata: {\"type\":\"message_delta\",\"usage\":{\"output_tokens\":3}}\n\n" +
+			"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"))
+	}))
+	defer srv.Close()
+
+	client := NewAnthropicClient(config.ProviderConfig{BaseURL: srv.URL})
+	var deltas []string
+	resp, err := client.Stream(context.Background(), ModelRequest{Model: "claude-3-5-sonnet", Messages: userTurn("x")}, func(d string) error {
+		deltas = append(deltas, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deltas) != 2 || resp.Output != "Hi there" || resp.InputTokens != 7 || resp.OutputTokens != 3 {
+		t.Fatalf("unexpected stream result: deltas=%v resp=%+v", deltas, resp)
+	}
+}
+
+func TestAnthropicClientLiftsSystemTurns(t *testing.T) {
+	client := NewAnthropicClient(config.ProviderConfig{SystemPrompt: "be brief"})
+	body := client.request(ModelRequest{Model: "claude-3-5-sonnet", Messages: []contracts.Message{
+		{Role: RoleSystem, Content: "answer in French"},
+		{Role: RoleUser, Content: "hello"},
+		{Role: RoleAssistant, Content: "bonjour"},
+		{Role: RoleUser, Content: "again"},
+	}}, false)
+	if body.System != "be brief\n\nanswer in French" {
+		t.Fatalf("unexpected system prompt: %q", body.System)
+	}
+	if len(body.Messages) != 3 || body.Messages[1].Role != RoleAssistant {
+		t.Fatalf("unexpected messages: %+v", body.Messages)
+	}
+}
+
+func TestAnthropicClientMapsToolUseAndResults(t *testing.T) {
+	client := NewAnthropicClient(config.ProviderConfig{})
+	body := client.request(ModelRequest{
+		Model: "claude-3-5-sonnet",
+		Messages: []contracts.Message{
+			{Role: RoleUser, Content: "check both hosts"},
+			{Role: RoleAssistant, ToolCalls: []contracts.ToolCall{
+				{ID: "tu_1", Name: "lookup_ip", Arguments: `{"ip":"10.0.0.8"}`},
+				{ID: "tu_2", Name: "lookup_ip", Arguments: `{"ip":"10.0.0.9"}`},
+			}},
+			{Role: RoleTool, ToolCallID: "tu_1", Content: "db-1"},
+			{Role: RoleTool, ToolCallID: "tu_2", Content: "db-2"},
+		},
+		Tools:      []contracts.Tool{{Name: "lookup_ip"}},
+		ToolChoice: ToolChoiceRequired,
+	}, false)
+	if len(body.Messages) != 3 || body.Messages[2].Role != RoleUser {
+		t.Fatalf("expected tool results merged into one user turn: %+v", body.Messages)
+	}
+	results, ok := body.Messages[2].Content.([]anthropicContentBlock)
+	if !ok || len(results) != 2 || results[1].Type != "tool_result" || results[1].ToolUseID != "tu_2" {
+		t.Fatalf("unexpected tool results: %+v", body.Messages[2].Content)
+	}
+	if len(body.Tools) != 1 || string(body.Tools[0].InputSchema) != `{"type":"object"}` || body.ToolChoice.Type != "any" {
+		t.Fatalf("unexpected tools: %+v %+v", body.Tools, body.ToolChoice)
+	}
+}
+
+func TestAnthropicClientReturnsToolUse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"Checking."},{"type":"tool_use","id":"tu_1","name":"lookup_ip","input":{"ip":"10.0.0.8"}}],"usage":{"input_tokens":20,"output_tokens":12}}`))
+	}))
+	defer srv.Close()
+
+	client := NewAnthropicClient(config.ProviderConfig{BaseURL: srv.URL})
+	resp, err := client.Complete(context.Background(), ModelRequest{Model: "claude-3-5-sonnet", Messages: userTurn("x"), Tools: []contracts.Tool{{Name: "lookup_ip"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Output != "Checking." || len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Arguments != `{"ip":"10.0.0.8"}` {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/auth"
+)
+
+// AutoModel is the model name that lets the gateway pick the model.
+const AutoModel = "auto"
+
+// CodeNoEligibleModel is returned when no allowed model can serve an auto
+// request.
+const CodeNoEligibleModel = "no_eligible_model"
+
+// autoSelection is the model picked for an auto request. Name is the
+// allowlist entry it was picked through, which differs from Model when that
+// entry is an alias.
+type autoSelection struct {
+	Name   string
+	Model  string
+	Reason string
+}
+
+// selectModel picks the cheapest of principal's allowed models that can
+// serve req: routed, healthy, fit for the request per the model catalog and
+// affordable under the pre-call estimate. Ties go to the first model by name.
+// When every candidate is ruled out it fails with budget_exceeded if some
+// were ruled out only by the budget, else with no_eligible_model.
+func (s *Service) selectModel(principal auth.Principal, req ModelRequest, inputTokens int, stream bool) (autoSelection, *AppError) {
+	names := make([]string, 0, len(principal.AllowedModels))
+	for name := range principal.AllowedModels {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var (
+		best      autoSelection
+		bestCost  float64
+		eligible  int
+		overspent bool
+		skipped   []string
+	)
+	for _, name := range names {
+		model := name
+		if resolved, ok := s.aliases.resolve(principal.Team, name); ok {
+			model = resolved
+		}
+		if model == AutoModel {
+			continue
+		}
+		if reason := s.autoSkipReason(model, req, inputTokens, stream); reason != "" {
+			skipped = append(skipped, name+": "+reason)
+			continue
+		}
+		cost := s.billing.EstimateCost(model, inputTokens, outputEstimate(req))
+		if !s.billing.CanAfford(principal.Team, principal.MonthlyBudgetUSD, cost) {
+			overspent = true
+			skipped = append(skipped, name+": estimated_cost_exceeds_budget")
+			continue
+		}
+		eligible++
+		if eligible == 1 || cost < bestCost {
+			best, bestCost = autoSelection{Name: name, Model: model}, cost
+		}
+	}
+
+	if eligible == 0 {
+		if overspent {
+			return autoSelection{}, &AppError{Code: "budget_exceeded", Message: "estimated_cost_exceeds_budget", HTTPStatus: http.StatusPaymentRequired}
+		}
+		return autoSelection{}, &AppError{
+			Code:       CodeNoEligibleModel,
+			Message:    "no allowed model can serve this request (" + strings.Join(skipped, "; ") + ")",
+			HTTPStatus: http.StatusBadRequest,
+		}
+	}
+	best.Reason = fmt.Sprintf("cheapest of %d eligible models at an estimated %.6f USD", eligible, bestCost)
+	if len(skipped) > 0 {
+		best.Reason += "; skipped " + strings.Join(skipped, ", ")
+	}
+	return best, nil
+}
+
+// autoSkipReason says why model cannot serve req, or "" when it can.
+func (s *Service) autoSkipReason(model string, req ModelRequest, inputTokens int, stream bool) string {
+	if router, ok := s.modelClient.(modelSupporter); ok && !router.Supports(model) {
+		return CodeUnknownModel
+	}
+	if reporter, ok := s.modelClient.(availabilityReporter); ok && !reporter.Available(model) {
+		return CodeProviderUnavailable
+	}
+	if appErr := s.checkModelFit(model, req, inputTokens, stream); appErr != nil {
+		return appErr.Code
+	}
+	return ""
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+// unavailableModels reports the listed models as failing health checks.
+type unavailableModels struct {
+	SimulatedModelClient
+	down map[string]bool
+}
+
+func (u unavailableModels) Available(model string) bool { return !u.down[model] }
+
+func TestAutoPicksCheapestEligibleModel(t *testing.T) {
+	svc, principal := newTestService(t, config.Default(), SimulatedModelClient{})
+
+	resp, appErr := svc.HandleCompletion(context.Background(), "req-1", principal, contracts.CompletionRequest{Model: AutoModel, Input: "triage the login burst"})
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	// The embedding model is cheaper but cannot serve completions.
+	if resp.Model != "gpt-4o-mini" || resp.RequestedModel != AutoModel || !strings.Contains(resp.SelectionReason, "text-embedding-3-small: unsupported_feature") {
+		t.Fatalf("unexpected selection: %+v", resp)
+	}
+	if ev := svc.AuditEvents(principal, 1)[0]; ev.SelectionReason != resp.SelectionReason {
+		t.Fatalf("expected the selection reason in the audit event, got %+v", ev)
+	}
+}
+
+func TestAutoSkipsUnhealthyAndTooSmallModels(t *testing.T) {
+	cfg := config.Default()
+	svc, principal := newTestService(t, cfg, unavailableModels{down: map[string]bool{"gpt-4o-mini": true}})
+	resp, appErr := svc.HandleCompletion(context.Background(), "req-1", principal, contracts.CompletionRequest{Model: AutoModel, Input: "hi"})
+	if appErr != nil || resp.Model != "gpt-4.1-mini" {
+		t.Fatalf("expected the healthy model, got %+v %v", resp, appErr)
+	}
+
+	cfg.ModelCatalog["gpt-4o-mini"] = config.ModelInfo{ContextWindow: 5}
+	cfg.ModelCatalog["gpt-4.1-mini"] = config.ModelInfo{ContextWindow: 5}
+	svc, principal = newTestService(t, cfg, SimulatedModelClient{})
+	_, appErr = svc.HandleCompletion(context.Background(), "req-2", principal, contracts.CompletionRequest{Model: AutoModel, Input: "summarize the suspicious login burst from the VPN gateway"})
+	if appErr == nil || appErr.Code != CodeNoEligibleModel {
+		t.Fatalf("expected %s, got %v", CodeNoEligibleModel, appErr)
+	}
+}
+
+func TestAutoRespectsRemainingBudget(t *testing.T) {
+	cfg := config.Default()
+	cfg.Teams[0].MonthlyBudgetUSD = 0.0001
+	svc, principal := newTestService(t, cfg, SimulatedModelClient{})
+
+	_, appErr := svc.HandleCompletion(context.Background(), "req-1", principal, contracts.CompletionRequest{Model: AutoModel, Input: "hi"})
+	if appErr == nil || appErr.Code != "budget_exceeded" {
+		t.Fatalf("expected budget_exceeded, got %v", appErr)
+	}
+}
@@ -0,0 +1,223 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+)
+
+// Load balancing strategies accepted in BalancerConfig.Strategy.
+const (
+	BalanceWeightedRoundRobin = "weighted_round_robin"
+	BalanceLeastOutstanding   = "least_outstanding"
+)
+
+// deployment is one upstream of a Balancer. Counters are guarded by the
+// balancer's mutex.
+type deployment struct {
+	name   string
+	model  string
+	client ModelClient
+	weight int
+
+	current      int
+	outstanding  int
+	failures     int
+	ejectedUntil time.Time
+}
+
+// Balancer is a ModelClient that spreads calls for one model over several
+// deployments. Deployments failing EjectionConfig.ConsecutiveErrors calls in
+// a row with retryable errors are skipped until their ejection expires, as are
+// deployments on providers failing their health checks; when every deployment
+// is skipped, all of them are eligible again rather than failing the call
+// outright.
+type Balancer struct {
+	strategy    string
+	deployments []*deployment
+	ejectAfter  int
+	ejectFor    time.Duration
+	metrics     *Metrics
+	now         func() time.Time
+
+	mu sync.Mutex
+}
+
+// NewBalancer builds a balancer over the configured deployments, resolving
+// each provider name through providers. metrics may be nil.
+func NewBalancer(cfg config.BalancerConfig, providers map[string]ModelClient, ejection config.EjectionConfig, metrics *Metrics) (*Balancer, error) {
+	strategy := cfg.Strategy
+	switch strategy {
+	case "":
+		strategy = BalanceWeightedRoundRobin
+	case BalanceWeightedRoundRobin, BalanceLeastOutstanding:
+	default:
+		return nil, fmt.Errorf("unknown balancing strategy %q", cfg.Strategy)
+	}
+	if len(cfg.Deployments) == 0 {
+		return nil, fmt.Errorf("no deployments configured")
+	}
+	b := &Balancer{
+		strategy:   strategy,
+		ejectAfter: ejection.ConsecutiveErrors,
+		ejectFor:   time.Duration(ejection.EjectMS) * time.Millisecond,
+		metrics:    metrics,
+		now:        time.Now,
+	}
+	seen := make(map[string]struct{}, len(cfg.Deployments))
+	for _, d := range cfg.Deployments {
+		client, ok := providers[d.Provider]
+		if !ok {
+			return nil, fmt.Errorf("deployment targets unknown provider %q", d.Provider)
+		}
+		if d.Weight < 0 {
+			return nil, fmt.Errorf("deployment %q has negative weight", d.Provider)
+		}
+		name := d.Provider
+		if d.Model != "" {
+			name += "/" + d.Model
+		}
+		if _, dup := seen[name]; dup {
+			return nil, fmt.Errorf("duplicate deployment %q", name)
+		}
+		seen[name] = struct{}{}
+		weight := d.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		b.deployments = append(b.deployments, &deployment{name: name, model: d.Model, client: client, weight: weight})
+	}
+	return b, nil
+}
+
+func (b *Balancer) Complete(ctx context.Context, req ModelRequest) (ModelResponse, error) {
+	return balance(ctx, b, req.Model, func(d *deployment) (ModelResponse, error) {
+		return d.client.Complete(ctx, d.request(req))
+	})
+}
+
+func (b *Balancer) Stream(ctx context.Context, req ModelRequest, onDelta func(string) error) (ModelResponse, error) {
+	return balance(ctx, b, req.Model, func(d *deployment) (ModelResponse, error) {
+		return streamCompletion(ctx, d.client, d.request(req), onDelta)
+	})
+}
+
+func (b *Balancer) Embed(ctx context.Context, req EmbeddingRequest) (EmbeddingResponse, error) {
+	return balance(ctx, b, req.Model, func(d *deployment) (EmbeddingResponse, error) {
+		if d.model != "" {
+			req.Model = d.model
+		}
+		return embed(ctx, d.client, req)
+	})
+}
+
+// balance runs call against the next deployment and records the outcome.
+func balance[T any](ctx context.Context, b *Balancer, model string, call func(d *deployment) (T, error)) (T, error) {
+	d := b.pick()
+	resp, err := call(d)
+	b.release(d, model, err, ctx.Err() == nil)
+	return resp, err
+}
+
+// available reports whether the deployment's provider passes its health
+// checks; backends without health checks are always available.
+func (d *deployment) available() bool {
+	if gate, ok := d.client.(interface{ available() bool }); ok {
+		return gate.available()
+	}
+	return true
+}
+
+// available reports whether any deployment's provider passes its health
+// checks, ejections aside.
+func (b *Balancer) available() bool {
+	return slices.ContainsFunc(b.deployments, (*deployment).available)
+}
+
+func (d *deployment) request(req ModelRequest) ModelRequest {
+	if d.model != "" {
+		req.Model = d.model
+	}
+	return req
+}
+
+// pick chooses a deployment and counts the call as outstanding on it.
+func (b *Balancer) pick() *deployment {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	eligible := make([]*deployment, 0, len(b.deployments))
+	for _, d := range b.deployments {
+		if !now.Before(d.ejectedUntil) && d.available() {
+			eligible = append(eligible, d)
+		}
+	}
+	if len(eligible) == 0 {
+		eligible = b.deployments
+	}
+
+	var chosen *deployment
+	if b.strategy == BalanceLeastOutstanding {
+		// Lowest outstanding/weight ratio; cross-multiplied to stay in integers.
+		for _, d := range eligible {
+			if chosen == nil || d.outstanding*chosen.weight < chosen.outstanding*d.weight {
+				chosen = d
+			}
+		}
+	} else {
+		// Smooth weighted round-robin: spreads picks evenly instead of
+		// sending weight-many calls to one deployment in a row.
+		total := 0
+		for _, d := range eligible {
+			d.current += d.weight
+			total += d.weight
+			if chosen == nil || d.current > chosen.current {
+				chosen = d
+			}
+		}
+		chosen.current -= total
+	}
+	chosen.outstanding++
+	return chosen
+}
+
+// release ends an outstanding call. Only retryable failures count towards
+// ejection; calls abandoned by the caller (counted=false) are not judged.
+func (b *Balancer) release(d *deployment, model string, err error, counted bool) {
+	outcome := attemptOutcomeOK
+	ejected := false
+
+	b.mu.Lock()
+	d.outstanding--
+	switch {
+	case err == nil:
+		d.failures = 0
+	case !canFallback(err):
+		outcome = attemptOutcomeError
+	default:
+		outcome = attemptOutcomeRetryable
+		if !counted {
+			break
+		}
+		d.failures++
+		if b.ejectAfter > 0 && d.failures >= b.ejectAfter {
+			d.failures = 0
+			d.ejectedUntil = b.now().Add(b.ejectFor)
+			ejected = true
+		}
+	}
+	b.mu.Unlock()
+
+	if b.metrics == nil {
+		return
+	}
+	b.metrics.DeploymentRequests.WithLabelValues(model, d.name, outcome).Inc()
+	if ejected {
+		b.metrics.DeploymentEjections.WithLabelValues(model, d.name).Inc()
+	}
+}
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newTestBalancer(t *testing.T, strategy string, providers map[string]ModelClient, deployments ...config.DeploymentConfig) (*Balancer, *Metrics) {
+	t.Helper()
+	metrics := NewMetrics(prometheus.NewRegistry())
+	b, err := NewBalancer(config.BalancerConfig{Strategy: strategy, Deployments: deployments}, providers, config.EjectionConfig{ConsecutiveErrors: 2, EjectMS: 1000}, metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b, metrics
+}
+
+func TestBalancerWeightedRoundRobin(t *testing.T) {
+	b, metrics := newTestBalancer(t, BalanceWeightedRoundRobin,
+		map[string]ModelClient{"east": namedClient("east"), "west": namedClient("west")},
+		config.DeploymentConfig{Provider: "east", Weight: 3},
+		config.DeploymentConfig{Provider: "west", Weight: 1},
+	)
+	var order []string
+	for range 8 {
+		resp, err := b.Complete(context.Background(), ModelRequest{Model: "gpt-4o-mini"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		order = append(order, resp.Output)
+	}
+	want := []string{"east", "east", "west", "east", "east", "east", "west", "east"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("unexpected order %v", order)
+		}
+	}
+	if got := testutil.ToFloat64(metrics.DeploymentRequests.WithLabelValues("gpt-4o-mini", "west", attemptOutcomeOK)); got != 2 {
+		t.Fatalf("expected 2 calls labelled west, got %v", got)
+	}
+}
+
+func TestBalancerLeastOutstandingPrefersIdleDeployment(t *testing.T) {
+	b, _ := newTestBalancer(t, BalanceLeastOutstanding,
+		map[string]ModelClient{"east": namedClient("east"), "west": namedClient("west")},
+		config.DeploymentConfig{Provider: "east", Weight: 2},
+		config.DeploymentConfig{Provider: "west"},
+	)
+	first, second, third := b.pick(), b.pick(), b.pick()
+	if first.name != "east" || second.name != "west" || third.name != "east" {
+		t.Fatalf("unexpected picks: %s %s %s", first.name, second.name, third.name)
+	}
+	b.release(first, "m", nil, true)
+	b.release(third, "m", nil, true)
+	if next := b.pick(); next.name != "east" {
+		t.Fatalf("expected the idle deployment, got %s", next.name)
+	}
+}
+
+func TestBalancerEjectsFailingDeployment(t *testing.T) {
+	unavailable := upstreamStatusError("openai", http.StatusServiceUnavailable, "")
+	b, metrics := newTestBalancer(t, BalanceWeightedRoundRobin,
+		map[string]ModelClient{"east": failingModels{"gpt-4o-mini": unavailable}, "west": namedClient("west")},
+		config.DeploymentConfig{Provider: "east"},
+		config.DeploymentConfig{Provider: "west"},
+	)
+	now := time.Unix(0, 0)
+	b.now = func() time.Time { return now }
+
+	for range 4 {
+		_, _ = b.Complete(context.Background(), ModelRequest{Model: "gpt-4o-mini"})
+	}
+	if got := testutil.ToFloat64(metrics.DeploymentEjections.WithLabelValues("gpt-4o-mini", "east")); got != 1 {
+		t.Fatalf("expected east to be ejected once, got %v", got)
+	}
+	for range 3 {
+		resp, err := b.Complete(context.Background(), ModelRequest{Model: "gpt-4o-mini"})
+		if err != nil || resp.Output != "west" {
+			t.Fatalf("expected ejected deployment to be skipped, got %q, %v", resp.Output, err)
+		}
+	}
+
+	now = now.Add(time.Second)
+	if a, c := b.pick(), b.pick(); a.name != "east" && c.name != "east" {
+		t.Fatalf("expected east back in rotation, got %s and %s", a.name, c.name)
+	}
+}
+
+func TestBalancerRewritesDeploymentModel(t *testing.T) {
+	var got string
+	client := modelRecorder(func(model string) { got = model })
+	b, _ := newTestBalancer(t, "", map[string]ModelClient{"azure": client}, config.DeploymentConfig{Provider: "azure", Model: "prod-gpt4o"})
+	if _, err := b.Complete(context.Background(), ModelRequest{Model: "gpt-4o-mini"}); err != nil {
+		t.Fatal(err)
+	}
+	if got != "prod-gpt4o" {
+		t.Fatalf("expected upstream model prod-gpt4o, got %q", got)
+	}
+}
+
+func TestNewRouterBuildsDeployments(t *testing.T) {
+	cfg := config.Config{
+		Providers:   []config.ProviderConfig{{Name: "east", Type: "simulated"}, {Name: "west", Type: "simulated"}},
+		ModelRoutes: map[string]string{"claude-*": "east"},
+		ModelDeployments: map[string]config.BalancerConfig{
+			"gpt-*": {Strategy: BalanceLeastOutstanding, Deployments: []config.DeploymentConfig{{Provider: "east"}, {Provider: "west"}}},
+		},
+	}
+	r, err := NewRouter(cfg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Supports("gpt-4o-mini") || !r.Supports("claude-3-5-sonnet") {
+		t.Fatal("expected both routed and balanced models to be supported")
+	}
+
+	cfg.ModelRoutes["gpt-*"] = "east"
+	if _, err := NewRouter(cfg, nil, nil); err == nil {
+		t.Fatal("expected error for a model with both a route and deployments")
+	}
+	delete(cfg.ModelRoutes, "gpt-*")
+	cfg.ModelDeployments["gpt-*"] = config.BalancerConfig{Strategy: "random", Deployments: []config.DeploymentConfig{{Provider: "east"}}}
+	if _, err := NewRouter(cfg, nil, nil); err == nil {
+		t.Fatal("expected error for an unknown strategy")
+	}
+}
+
+// modelRecorder reports the model name of every call.
+type modelRecorder func(model string)
+
+func (f modelRecorder) Complete(_ context.Context, req ModelRequest) (ModelResponse, error) {
+	f(req.Model)
+	return ModelResponse{Output: "ok"}, nil
+}
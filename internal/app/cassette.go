@@ -0,0 +1,388 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+// CodeCassetteMiss is returned by a ReplayClient for requests its cassette
+// does not contain.
+const CodeCassetteMiss = "cassette_miss"
+
+// cassetteVersion is bumped whenever the input hash or file layout changes.
+const cassetteVersion = 2
+
+// cassetteHeader is the first line of a cassette file. Each following line
+// is one cassetteInteraction, so recording only ever appends.
+type cassetteHeader struct {
+	Version int `json:"version"`
+}
+
+// cassetteInteraction is one upstream call. Exactly one of Response and
+// Error is set; Chunks holds the deltas of a streamed call.
+type cassetteInteraction struct {
+	Model     string            `json:"model"`
+	InputHash string            `json:"input_hash"`
+	Request   cassetteRequest   `json:"request"`
+	Response  *cassetteResponse `json:"response,omitempty"`
+	Chunks    []string          `json:"chunks,omitempty"`
+	Error     *cassetteError    `json:"error,omitempty"`
+	LatencyMS int64             `json:"latency_ms"`
+}
+
+// cassetteRequest is the normalized request the input hash is computed over.
+type cassetteRequest struct {
+	Messages       []contracts.Message       `json:"messages"`
+	Tools          []contracts.Tool          `json:"tools,omitempty"`
+	ToolChoice     string                    `json:"tool_choice,omitempty"`
+	ResponseFormat *contracts.ResponseFormat `json:"response_format,omitempty"`
+	MaxTokens      int                       `json:"max_tokens,omitempty"`
+	Temperature    *float64                  `json:"temperature,omitempty"`
+	TopP           *float64                  `json:"top_p,omitempty"`
+	Stop           []string                  `json:"stop,omitempty"`
+}
+
+type cassetteResponse struct {
+	Output       string               `json:"output"`
+	ToolCalls    []contracts.ToolCall `json:"tool_calls,omitempty"`
+	InputTokens  int                  `json:"input_tokens"`
+	OutputTokens int                  `json:"output_tokens"`
+}
+
+type cassetteError struct {
+	Code         string `json:"code"`
+	Message      string `json:"message"`
+	HTTPStatus   int    `json:"http_status"`
+	RetryAfterMS int64  `json:"retry_after_ms,omitempty"`
+}
+
+// normalizeRequest strips what varies between otherwise identical calls:
+// runs of whitespace, tool call IDs (providers mint new ones on every run)
+// and gateway-side settings such as max_reasks.
+func normalizeRequest(req ModelRequest) cassetteRequest {
+	out := cassetteRequest{ToolChoice: req.ToolChoice, MaxTokens: req.MaxTokens, Temperature: req.Temperature, TopP: req.TopP, Stop: req.Stop}
+	for _, m := range req.Messages {
+		msg := contracts.Message{Role: m.Role, Content: strings.Join(strings.Fields(m.Content), " ")}
+		for _, call := range m.ToolCalls {
+			msg.ToolCalls = append(msg.ToolCalls, contracts.ToolCall{Name: call.Name, Arguments: string(compactJSON(json.RawMessage(call.Arguments)))})
+		}
+		out.Messages = append(out.Messages, msg)
+	}
+	for _, t := range req.Tools {
+		out.Tools = append(out.Tools, contracts.Tool{Name: t.Name, Description: strings.Join(strings.Fields(t.Description), " "), Parameters: compactJSON(t.Parameters)})
+	}
+	if rf := req.ResponseFormat; rf != nil {
+		out.ResponseFormat = &contracts.ResponseFormat{Type: rf.Type, Name: rf.Name, Schema: compactJSON(rf.Schema)}
+	}
+	return out
+}
+
+func compactJSON(raw json.RawMessage) json.RawMessage {
+	var buf bytes.Buffer
+	if len(raw) == 0 || json.Compact(&buf, raw) != nil {
+		return raw
+	}
+	return buf.Bytes()
+}
+
+// inputHash identifies a normalized request.
+func inputHash(req cassetteRequest) string {
+	raw, _ := json.Marshal(req)
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func loadCassette(path string) ([]cassetteInteraction, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	var header cassetteHeader
+	if err := dec.Decode(&header); err != nil {
+		return nil, fmt.Errorf("cassette %s: %w", path, err)
+	}
+	if header.Version != cassetteVersion {
+		return nil, fmt.Errorf("cassette %s has version %d, want %d; re-record it", path, header.Version, cassetteVersion)
+	}
+	var interactions []cassetteInteraction
+	for {
+		var ic cassetteInteraction
+		if err := dec.Decode(&ic); errors.Is(err, io.EOF) {
+			return interactions, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("cassette %s: %w", path, err)
+		}
+		interactions = append(interactions, ic)
+	}
+}
+
+// RecordingClient passes calls through to the wrapped client and appends
+// every completion, streamed or not, to a cassette file. Upstream errors are
+// recorded too; cancellations and aborts by the caller are not. Each call is
+// appended to the file as it completes, so a crash loses at most the call in
+// flight. Prompts and responses are written verbatim, without the redaction
+// applied to the audit log, since replay has to return them unchanged.
+type RecordingClient struct {
+	next   ModelClient
+	path   string
+	logger *slog.Logger
+
+	mu sync.Mutex
+}
+
+// NewRecordingClient records next's calls to path, appending to the cassette
+// already there. Recording failures are logged to logger, which may be nil.
+func NewRecordingClient(next ModelClient, path string, logger *slog.Logger) (*RecordingClient, error) {
+	_, err := loadCassette(path)
+	if errors.Is(err, os.ErrNotExist) {
+		err = appendCassette(path, cassetteHeader{Version: cassetteVersion})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	return &RecordingClient{next: next, path: path, logger: logger}, nil
+}
+
+func (r *RecordingClient) Complete(ctx context.Context, req ModelRequest) (ModelResponse, error) {
+	started := time.Now()
+	resp, err := r.next.Complete(ctx, req)
+	r.record(req, resp, nil, err, time.Since(started))
+	return resp, err
+}
+
+func (r *RecordingClient) Stream(ctx context.Context, req ModelRequest, onDelta func(string) error) (ModelResponse, error) {
+	started := time.Now()
+	var chunks []string
+	var aborted bool
+	resp, err := streamCompletion(ctx, r.next, req, func(delta string) error {
+		if err := onDelta(delta); err != nil {
+			aborted = true
+			return err
+		}
+		chunks = append(chunks, delta)
+		return nil
+	})
+	if !aborted {
+		r.record(req, resp, chunks, err, time.Since(started))
+	}
+	return resp, err
+}
+
+func (r *RecordingClient) Embed(ctx context.Context, req EmbeddingRequest) (EmbeddingResponse, error) {
+	return embed(ctx, r.next, req)
+}
+
+// Probe passes health checks through to the wrapped client.
+func (r *RecordingClient) Probe(ctx context.Context) error {
+	if p, ok := r.next.(prober); ok {
+		return p.Probe(ctx)
+	}
+	return nil
+}
+
+// ListModels passes model discovery through to the wrapped client.
+func (r *RecordingClient) ListModels(ctx context.Context) ([]string, error) {
+	if l, ok := r.next.(modelLister); ok {
+		return l.ListModels(ctx)
+	}
+	return nil, errors.New("wrapped client cannot list models")
+}
+
+func (r *RecordingClient) record(req ModelRequest, resp ModelResponse, chunks []string, err error, latency time.Duration) {
+	normalized := normalizeRequest(req)
+	ic := cassetteInteraction{
+		Model:     req.Model,
+		InputHash: inputHash(normalized),
+		Request:   normalized,
+		Chunks:    chunks,
+		LatencyMS: latency.Milliseconds(),
+	}
+	if err != nil {
+		var appErr *AppError
+		if !errors.As(err, &appErr) || appErr.Code == CodeRequestCanceled {
+			return
+		}
+		ic.Chunks = nil
+		ic.Error = &cassetteError{
+			Code:         appErr.Code,
+			Message:      appErr.Message,
+			HTTPStatus:   appErr.HTTPStatus,
+			RetryAfterMS: appErr.RetryAfter.Milliseconds(),
+		}
+	} else {
+		ic.Response = &cassetteResponse{Output: resp.Output, ToolCalls: resp.ToolCalls, InputTokens: resp.InputTokens, OutputTokens: resp.OutputTokens}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := appendCassette(r.path, ic); err != nil {
+		// Recording must not fail the call it observes.
+		r.logger.Warn("cassette interaction not recorded", "path", r.path, "err", err)
+	}
+}
+
+// appendCassette writes v to path as one JSON line, creating the file if
+// needed.
+func appendCassette(path string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(raw, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReplayClient serves completions from a cassette. Interactions recorded for
+// the same model and input are served in recorded order, the last one
+// repeating once they run out; a request the cassette does not contain fails
+// with cassette_miss instead of reaching any upstream.
+type ReplayClient struct {
+	path     string
+	latency  bool
+	mu       sync.Mutex
+	recorded map[string][]cassetteInteraction
+	served   map[string]int
+}
+
+// NewReplayClient loads the cassette at path. With simulateLatency, each
+// call takes as long as it originally did.
+func NewReplayClient(path string, simulateLatency bool) (*ReplayClient, error) {
+	interactions, err := loadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &ReplayClient{
+		path:     path,
+		latency:  simulateLatency,
+		recorded: make(map[string][]cassetteInteraction),
+		served:   make(map[string]int),
+	}
+	for _, ic := range interactions {
+		key := ic.Model + "\x00" + ic.InputHash
+		r.recorded[key] = append(r.recorded[key], ic)
+	}
+	return r, nil
+}
+
+func (r *ReplayClient) next(req ModelRequest) (cassetteInteraction, error) {
+	hash := inputHash(normalizeRequest(req))
+	key := req.Model + "\x00" + hash
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	recorded := r.recorded[key]
+	if len(recorded) == 0 {
+		return cassetteInteraction{}, &AppError{
+			Code:       CodeCassetteMiss,
+			Message:    fmt.Sprintf("cassette %s has no interaction for model %q with input hash %s", r.path, req.Model, hash),
+			HTTPStatus: http.StatusInternalServerError,
+		}
+	}
+	i := min(r.served[key], len(recorded)-1)
+	r.served[key]++
+	return recorded[i], nil
+}
+
+func (r *ReplayClient) Complete(ctx context.Context, req ModelRequest) (ModelResponse, error) {
+	ic, err := r.next(req)
+	if err != nil {
+		return ModelResponse{}, err
+	}
+	resp, err := ic.result()
+	if err := r.wait(ctx, ic, err); err != nil {
+		return ModelResponse{}, err
+	}
+	return resp, nil
+}
+
+// wait takes the interaction's original latency when it is simulated and
+// then returns err.
+func (r *ReplayClient) wait(ctx context.Context, ic cassetteInteraction, err error) error {
+	if r.latency {
+		if sleepErr := sleepContext(ctx, time.Duration(ic.LatencyMS)*time.Millisecond); sleepErr != nil {
+			return upstreamTransportError("replay", sleepErr)
+		}
+	}
+	return err
+}
+
+// Stream replays the recorded chunks, spread over the original latency when
+// it is simulated. Calls recorded unary are delivered as a single chunk.
+func (r *ReplayClient) Stream(ctx context.Context, req ModelRequest, onDelta func(string) error) (ModelResponse, error) {
+	ic, err := r.next(req)
+	if err != nil {
+		return ModelResponse{}, err
+	}
+	resp, err := ic.result()
+	if err != nil {
+		return ModelResponse{}, r.wait(ctx, ic, err)
+	}
+	chunks := ic.Chunks
+	if len(chunks) == 0 && resp.Output != "" {
+		chunks = []string{resp.Output}
+	}
+	var pause time.Duration
+	if r.latency {
+		pause = time.Duration(ic.LatencyMS) * time.Millisecond / time.Duration(len(chunks)+1)
+	}
+	for _, chunk := range chunks {
+		if err := sleepContext(ctx, pause); err != nil {
+			return ModelResponse{}, upstreamTransportError("replay", err)
+		}
+		if err := onDelta(chunk); err != nil {
+			return ModelResponse{}, err
+		}
+	}
+	if err := sleepContext(ctx, pause); err != nil {
+		return ModelResponse{}, upstreamTransportError("replay", err)
+	}
+	return resp, nil
+}
+
+// Probe always succeeds; a cassette has no upstream to check.
+func (r *ReplayClient) Probe(context.Context) error { return nil }
+
+func (ic cassetteInteraction) result() (ModelResponse, error) {
+	if e := ic.Error; e != nil {
+		return ModelResponse{}, &AppError{
+			Code:       e.Code,
+			Message:    e.Message,
+			HTTPStatus: e.HTTPStatus,
+			RetryAfter: time.Duration(e.RetryAfterMS) * time.Millisecond,
+		}
+	}
+	if ic.Response == nil {
+		return ModelResponse{}, nil
+	}
+	return ModelResponse{
+		Output:       ic.Response.Output,
+		ToolCalls:    ic.Response.ToolCalls,
+		InputTokens:  ic.Response.InputTokens,
+		OutputTokens: ic.Response.OutputTokens,
+	}, nil
+}
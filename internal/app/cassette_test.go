@@ -0,0 +1,155 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+// failingClient fails every call with err after sleeping for delay.
+type failingClient struct {
+	err   error
+	delay time.Duration
+}
+
+func (c failingClient) Complete(context.Context, ModelRequest) (ModelResponse, error) {
+	time.Sleep(c.delay)
+	return ModelResponse{}, c.err
+}
+
+func TestCassetteReplaysRecordedInteractions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	rec, err := NewRecordingClient(SimulatedModelClient{}, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	toolTurns := []contracts.Message{
+		{Role: RoleUser, Content: "look up 10.0.0.1"},
+		{Role: RoleAssistant, ToolCalls: []contracts.ToolCall{{ID: "call_abc", Name: "lookup_ip", Arguments: `{"ip": "10.0.0.1"}`}}},
+		{Role: RoleTool, ToolCallID: "call_abc", Content: "clean"},
+	}
+	want, err := rec.Complete(ctx, ModelRequest{Model: "gpt-4o-mini", Messages: toolTurns})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recordedChunks []string
+	if _, err := rec.Stream(ctx, ModelRequest{Model: "gpt-4o-mini", Messages: userTurn("stream me")}, func(d string) error {
+		recordedChunks = append(recordedChunks, d)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	replay, err := NewReplayClient(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Whitespace and provider-minted tool call IDs do not affect matching.
+	reworded := []contracts.Message{
+		{Role: RoleUser, Content: "  look up\n10.0.0.1 "},
+		{Role: RoleAssistant, ToolCalls: []contracts.ToolCall{{ID: "call_xyz", Name: "lookup_ip", Arguments: `{"ip":"10.0.0.1"}`}}},
+		{Role: RoleTool, ToolCallID: "call_xyz", Content: "clean"},
+	}
+	got, err := replay.Complete(ctx, ModelRequest{Model: "gpt-4o-mini", Messages: reworded})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Output != want.Output {
+		t.Fatalf("replayed %q, recorded %q", got.Output, want.Output)
+	}
+
+	var chunks []string
+	if _, err := replay.Stream(ctx, ModelRequest{Model: "gpt-4o-mini", Messages: userTurn("stream me")}, func(d string) error {
+		chunks = append(chunks, d)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(chunks, "|") != strings.Join(recordedChunks, "|") || len(chunks) < 2 {
+		t.Fatalf("replayed chunks %q, recorded %q", chunks, recordedChunks)
+	}
+
+	_, err = replay.Complete(ctx, ModelRequest{Model: "gpt-4.1-mini", Messages: toolTurns})
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.Code != CodeCassetteMiss || appErr.HTTPStatus != http.StatusInternalServerError {
+		t.Fatalf("expected %s for another model, got %v", CodeCassetteMiss, err)
+	}
+}
+
+func TestCassetteReplaysErrorsInOrderWithLatency(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	req := ModelRequest{Model: "gpt-4o-mini", Messages: userTurn("hi")}
+	failing, err := NewRecordingClient(failingClient{err: upstreamStatusError("openai", http.StatusTooManyRequests, "slow down"), delay: 30 * time.Millisecond}, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = failing.Complete(context.Background(), req)
+	// A second recorder appends to the same cassette.
+	ok, err := NewRecordingClient(SimulatedModelClient{}, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ok.Complete(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	// Calls cancelled by the caller are not recorded.
+	canceled, err := NewRecordingClient(failingClient{err: upstreamTransportError("openai", context.Canceled)}, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = canceled.Complete(context.Background(), req)
+
+	replay, err := NewReplayClient(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := time.Now()
+	_, err = replay.Complete(context.Background(), req)
+	if code := asUpstreamError(err).Code; code != CodeUpstreamRateLimited || time.Since(started) < 30*time.Millisecond {
+		t.Fatalf("expected the recorded rate limit after its latency, got %v after %v", err, time.Since(started))
+	}
+	for range 2 {
+		if _, err := replay.Complete(context.Background(), req); err != nil {
+			t.Fatalf("expected the recorded success to be served and then repeated, got %v", err)
+		}
+	}
+}
+
+func TestRecordingAppendsOneLinePerCall(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.jsonl")
+	rec, err := NewRecordingClient(SimulatedModelClient{}, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, prompt := range []string{"one", "two", "three"} {
+		if _, err := rec.Complete(context.Background(), ModelRequest{Model: "gpt-4o-mini", Messages: userTurn(prompt)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The header line plus one line per call.
+	if lines := strings.Count(string(raw), "\n"); lines != 4 {
+		t.Fatalf("expected 4 lines, got %d:\n%s", lines, raw)
+	}
+}
+
+func TestReplayProviderRequiresCassette(t *testing.T) {
+	if _, err := NewModelClient(config.ProviderConfig{Type: "replay"}, nil); err == nil {
+		t.Fatal("expected a replay provider without a cassette to be rejected")
+	}
+	if _, err := NewModelClient(config.ProviderConfig{Type: "replay", Cassette: filepath.Join(t.TempDir(), "missing.json")}, nil); err == nil {
+		t.Fatal("expected a missing cassette to be rejected")
+	}
+}
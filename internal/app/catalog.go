@@ -0,0 +1,115 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/auth"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/billing"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+// ModeEmbedding marks embedding-only models in the catalog; only they are
+// served embeddings.
+const ModeEmbedding = "embedding"
+
+// Error codes for requests a model's catalog entry rules out.
+const (
+	CodeContextLengthExceeded = "context_length_exceeded"
+	CodeUnsupportedFeature    = "unsupported_feature"
+)
+
+// applyCatalogPrices gives catalog models their input and output prices. A
+// side without a catalog price keeps the model's unit price.
+func applyCatalogPrices(svc *billing.Service, catalog map[string]config.ModelInfo) {
+	for model, info := range catalog {
+		if info.InputPricePer1KUSD == nil && info.OutputPricePer1KUSD == nil {
+			continue
+		}
+		input, output := svc.UnitPrice(model), svc.UnitPrice(model)
+		if info.InputPricePer1KUSD != nil {
+			input = *info.InputPricePer1KUSD
+		}
+		if info.OutputPricePer1KUSD != nil {
+			output = *info.OutputPricePer1KUSD
+		}
+		svc.SetModelPrice(model, input, output)
+	}
+}
+
+// checkModelFit rejects requests that model's catalog entry says it cannot
+// serve: completions on an embedding model, streaming or tools it does not
+// support, a max_tokens above its output limit, or input plus max_tokens
+// beyond its context window. Models without an entry are not checked.
+func (s *Service) checkModelFit(model string, req ModelRequest, inputTokens int, stream bool) *AppError {
+	info, ok := s.catalog[model]
+	if !ok {
+		return nil
+	}
+	unsupported := func(feature string) *AppError {
+		return &AppError{Code: CodeUnsupportedFeature, Message: fmt.Sprintf("model %q does not support %s", model, feature), HTTPStatus: http.StatusBadRequest}
+	}
+	if info.Mode == ModeEmbedding {
+		return unsupported("completions")
+	}
+	if stream && info.SupportsStreaming != nil && !*info.SupportsStreaming {
+		return unsupported("streaming")
+	}
+	if len(req.Tools) > 0 && info.SupportsTools != nil && !*info.SupportsTools {
+		return unsupported("tools")
+	}
+	if info.MaxOutputTokens > 0 && req.MaxTokens > info.MaxOutputTokens {
+		return &AppError{
+			Code:       "invalid_parameters",
+			Message:    fmt.Sprintf("max_tokens %d exceeds the %d output tokens of model %q", req.MaxTokens, info.MaxOutputTokens, model),
+			HTTPStatus: http.StatusBadRequest,
+		}
+	}
+	if info.ContextWindow > 0 && inputTokens+req.MaxTokens > info.ContextWindow {
+		return contextLengthError(model, inputTokens+req.MaxTokens, info.ContextWindow)
+	}
+	return nil
+}
+
+func contextLengthError(model string, tokens, window int) *AppError {
+	return &AppError{
+		Code:       CodeContextLengthExceeded,
+		Message:    fmt.Sprintf("request needs about %d tokens but model %q has a %d-token context window", tokens, model, window),
+		HTTPStatus: http.StatusBadRequest,
+	}
+}
+
+// Models lists the models principal may use, with their catalog entries and
+// effective prices, sorted by name. Aliases are described by the model they
+// stand for.
+func (s *Service) Models(principal auth.Principal) contracts.ModelList {
+	names := make([]string, 0, len(principal.AllowedModels))
+	for name := range principal.AllowedModels {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	list := contracts.ModelList{Object: "list", Data: make([]contracts.ModelCard, 0, len(names))}
+	for _, name := range names {
+		card := contracts.ModelCard{ID: name, Object: "model", OwnedBy: "gateway"}
+		model := name
+		if resolved, ok := s.aliases.resolve(principal.Team, name); ok {
+			model = resolved
+		}
+		if info, ok := s.catalog[model]; ok {
+			if info.Provider != "" {
+				card.OwnedBy = info.Provider
+			}
+			card.ContextWindow = info.ContextWindow
+			card.MaxOutputTokens = info.MaxOutputTokens
+			card.Modalities = info.Modalities
+			card.SupportsStreaming = info.SupportsStreaming
+			card.SupportsTools = info.SupportsTools
+		}
+		card.InputPricePer1KUSD, card.OutputPricePer1KUSD = s.billing.Prices(model)
+		list.Data = append(list.Data, card)
+	}
+	return list
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+func TestCatalogRejectsUnsupportedFeatures(t *testing.T) {
+	no := false
+	cfg := config.Default()
+	cfg.ModelCatalog["gpt-4.1-mini"] = config.ModelInfo{SupportsStreaming: &no, SupportsTools: &no, MaxOutputTokens: 100}
+	svc, principal := newTestService(t, cfg, SimulatedModelClient{})
+	ctx := context.Background()
+
+	tooLong := 500
+	for name, req := range map[string]contracts.CompletionRequest{
+		CodeUnsupportedFeature: {Model: "gpt-4.1-mini", Input: "hi", Tools: []contracts.Tool{{Name: "search_logs"}}},
+		"invalid_parameters":   {Model: "gpt-4.1-mini", Input: "hi", MaxTokens: &tooLong},
+	} {
+		if _, appErr := svc.HandleCompletion(ctx, "req-1", principal, req); appErr == nil || appErr.Code != name {
+			t.Fatalf("expected %s, got %v", name, appErr)
+		}
+	}
+	_, appErr := svc.HandleCompletionStream(ctx, "req-2", principal, contracts.CompletionRequest{Model: "gpt-4.1-mini", Input: "hi"}, func(_, _ string) error { return nil })
+	if appErr == nil || appErr.Code != CodeUnsupportedFeature {
+		t.Fatalf("expected streaming to be rejected, got %v", appErr)
+	}
+	// Models without limits in the catalog are served as before.
+	if _, appErr := svc.HandleCompletion(ctx, "req-3", principal, contracts.CompletionRequest{Model: "gpt-4o-mini", Input: "hi", MaxTokens: &tooLong}); appErr != nil {
+		t.Fatal(appErr)
+	}
+}
+
+func TestFallbackSkipsModelsTooSmallForRequest(t *testing.T) {
+	cfg := config.Default()
+	cfg.ModelCatalog["gpt-4o-mini"] = config.ModelInfo{ContextWindow: 10}
+	svc, principal := newTestService(t, cfg, failingModels{"gpt-4.1-mini": upstreamStatusError("openai", 503, "down")})
+
+	_, appErr := svc.HandleCompletion(context.Background(), "req-1", principal, contracts.CompletionRequest{Model: "gpt-4.1-mini", Input: "summarize the suspicious login burst from the VPN gateway"})
+	if appErr == nil {
+		t.Fatal("expected the failure to surface once the only allowed fallback is skipped")
+	}
+	ev := svc.AuditEvents(principal, 1)[0]
+	if len(ev.Attempts) < 2 || ev.Attempts[1].Model != "gpt-4o-mini" || ev.Attempts[1].Reason != CodeContextLengthExceeded {
+		t.Fatalf("expected gpt-4o-mini to be skipped for its context window, got %+v", ev.Attempts)
+	}
+}
+
+func TestCatalogPricesSplitInputAndOutput(t *testing.T) {
+	in, out := 0.001, 0.004
+	cfg := config.Default()
+	cfg.ModelCatalog["gpt-4o-mini"] = config.ModelInfo{InputPricePer1KUSD: &in, OutputPricePer1KUSD: &out}
+	svc, principal := newTestService(t, cfg, SimulatedModelClient{})
+
+	if got := svc.billing.EstimateCost("gpt-4o-mini", 1000, 1000); got != 0.005 {
+		t.Fatalf("expected split pricing, got %f", got)
+	}
+	for _, card := range svc.Models(principal).Data {
+		if card.ID == "gpt-4o-mini" && (card.InputPricePer1KUSD != in || card.OutputPricePer1KUSD != out) {
+			t.Fatalf("unexpected listed prices: %+v", card)
+		}
+	}
+}
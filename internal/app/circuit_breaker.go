@@ -0,0 +1,219 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+)
+
+// CodeProviderUnavailable is returned without calling upstream while a
+// provider's circuit is open.
+const CodeProviderUnavailable = "provider_unavailable"
+
+// BreakerState is the state of a circuit breaker. Values are exported as the
+// gateway_provider_circuit_state gauge.
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerHalfOpen
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerHalfOpen:
+		return "half_open"
+	case BreakerOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreaker trips open when the failure ratio within a counting window
+// reaches the threshold, rejects calls for the cool-down, then lets a single
+// probe through (half-open) whose outcome closes or re-opens the circuit.
+type CircuitBreaker struct {
+	mu           sync.Mutex
+	state        BreakerState
+	failureRatio float64
+	minRequests  int
+	window       time.Duration
+	cooldown     time.Duration
+	windowStart  time.Time
+	openedAt     time.Time
+	requests     int
+	failures     int
+	probing      bool
+	now          func() time.Time
+	onChange     func(BreakerState)
+}
+
+func NewCircuitBreaker(cfg config.CircuitBreakerConfig, onChange func(BreakerState)) *CircuitBreaker {
+	minRequests := cfg.MinRequests
+	if minRequests <= 0 {
+		minRequests = 1
+	}
+	if onChange == nil {
+		onChange = func(BreakerState) {}
+	}
+	return &CircuitBreaker{
+		failureRatio: cfg.FailureRatio,
+		minRequests:  minRequests,
+		window:       time.Duration(cfg.WindowMS) * time.Millisecond,
+		cooldown:     time.Duration(cfg.CooldownMS) * time.Millisecond,
+		now:          time.Now,
+		onChange:     onChange,
+	}
+}
+
+// State returns the current state, moving open circuits whose cool-down has
+// elapsed to half-open.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance(b.now())
+	return b.state
+}
+
+// Allow reports whether a call may proceed. In half-open state only one probe
+// is admitted at a time; every admitted call must be followed by Record.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.advance(now)
+	switch b.state {
+	case BreakerOpen:
+		return false
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	if b.window > 0 && now.Sub(b.windowStart) >= b.window {
+		b.windowStart = now
+		b.requests, b.failures = 0, 0
+	}
+	return true
+}
+
+// Record reports the outcome of an admitted call. Calls that neither prove nor
+// disprove provider health (e.g. caller cancellations) should pass counted=false.
+func (b *CircuitBreaker) Record(success, counted bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerHalfOpen {
+		b.probing = false
+		if !counted {
+			return
+		}
+		if success {
+			b.transition(BreakerClosed)
+		} else {
+			b.transition(BreakerOpen)
+		}
+		return
+	}
+	if b.state != BreakerClosed || !counted {
+		return
+	}
+	b.requests++
+	if !success {
+		b.failures++
+	}
+	if b.failureRatio > 0 && b.requests >= b.minRequests && float64(b.failures)/float64(b.requests) >= b.failureRatio {
+		b.transition(BreakerOpen)
+	}
+}
+
+func (b *CircuitBreaker) advance(now time.Time) {
+	if b.state == BreakerOpen && now.Sub(b.openedAt) >= b.cooldown {
+		b.transition(BreakerHalfOpen)
+	}
+}
+
+func (b *CircuitBreaker) transition(to BreakerState) {
+	if b.state == to {
+		return
+	}
+	b.state = to
+	now := b.now()
+	switch to {
+	case BreakerOpen:
+		b.openedAt = now
+	case BreakerClosed:
+		b.windowStart = now
+		b.requests, b.failures = 0, 0
+	}
+	b.onChange(to)
+}
+
+// breakerClient guards a single provider backend with a CircuitBreaker.
+type breakerClient struct {
+	provider string
+	next     ModelClient
+	breaker  *CircuitBreaker
+}
+
+func newBreakerClient(provider string, next ModelClient, cfg config.CircuitBreakerConfig, metrics *Metrics) *breakerClient {
+	onChange := func(state BreakerState) {
+		if metrics != nil {
+			metrics.ProviderCircuitState.WithLabelValues(provider).Set(float64(state))
+		}
+	}
+	onChange(BreakerClosed)
+	return &breakerClient{provider: provider, next: next, breaker: NewCircuitBreaker(cfg, onChange)}
+}
+
+func (c *breakerClient) Complete(ctx context.Context, req ModelRequest) (ModelResponse, error) {
+	if !c.breaker.Allow() {
+		return ModelResponse{}, providerUnavailableError(c.provider)
+	}
+	resp, err := c.next.Complete(ctx, req)
+	c.breaker.Record(err == nil || !isTransient(err), ctx.Err() == nil)
+	return resp, err
+}
+
+func (c *breakerClient) Stream(ctx context.Context, req ModelRequest, onDelta func(string) error) (ModelResponse, error) {
+	if !c.breaker.Allow() {
+		return ModelResponse{}, providerUnavailableError(c.provider)
+	}
+	// A caller that stops reading says nothing about the provider's health.
+	undelivered := false
+	resp, err := streamCompletion(ctx, c.next, req, func(delta string) error {
+		if err := onDelta(delta); err != nil {
+			undelivered = true
+			return err
+		}
+		return nil
+	})
+	c.breaker.Record(err == nil || !isTransient(err), ctx.Err() == nil && !undelivered)
+	return resp, err
+}
+
+func (c *breakerClient) Embed(ctx context.Context, req EmbeddingRequest) (EmbeddingResponse, error) {
+	if !c.breaker.Allow() {
+		return EmbeddingResponse{}, providerUnavailableError(c.provider)
+	}
+	resp, err := embed(ctx, c.next, req)
+	c.breaker.Record(err == nil || !isTransient(err), ctx.Err() == nil)
+	return resp, err
+}
+
+func providerUnavailableError(provider string) *AppError {
+	return &AppError{
+		Code:       CodeProviderUnavailable,
+		Message:    fmt.Sprintf("provider %q is unavailable (circuit open)", provider),
+		HTTPStatus: http.StatusServiceUnavailable,
+	}
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCircuitBreakerOpensAndRecoversThroughHalfOpen(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewCircuitBreaker(config.CircuitBreakerConfig{FailureRatio: 0.5, MinRequests: 4, WindowMS: 60000, CooldownMS: 1000}, nil)
+	b.now = func() time.Time { return now }
+
+	for _, ok := range []bool{true, false, true, false} {
+		if !b.Allow() {
+			t.Fatal("expected closed circuit to allow")
+		}
+		b.Record(ok, true)
+	}
+	if b.State() != BreakerOpen || b.Allow() {
+		t.Fatalf("expected open circuit, got %s", b.State())
+	}
+
+	now = now.Add(time.Second)
+	if !b.Allow() {
+		t.Fatal("expected half-open probe to be allowed")
+	}
+	if b.Allow() {
+		t.Fatal("expected only one concurrent half-open probe")
+	}
+	b.Record(false, true)
+	if b.State() != BreakerOpen {
+		t.Fatalf("expected failed probe to re-open, got %s", b.State())
+	}
+
+	now = now.Add(time.Second)
+	if !b.Allow() {
+		t.Fatal("expected second probe to be allowed")
+	}
+	b.Record(true, true)
+	if b.State() != BreakerClosed {
+		t.Fatalf("expected successful probe to close, got %s", b.State())
+	}
+}
+
+func TestBreakerClientFailsFastAndExportsState(t *testing.T) {
+	metrics := NewMetrics(prometheus.NewRegistry())
+	next := failingModels{"m": upstreamStatusError("openai", http.StatusServiceUnavailable, "")}
+	c := newBreakerClient("openai", next, config.CircuitBreakerConfig{FailureRatio: 1, MinRequests: 2, CooldownMS: 60000}, metrics)
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Complete(context.Background(), ModelRequest{Model: "m"}); err == nil {
+			t.Fatal("expected upstream error")
+		}
+	}
+	_, err := c.Complete(context.Background(), ModelRequest{Model: "m"})
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.Code != CodeProviderUnavailable {
+		t.Fatalf("expected %s, got %v", CodeProviderUnavailable, err)
+	}
+	if got := testutil.ToFloat64(metrics.ProviderCircuitState.WithLabelValues("openai")); got != float64(BreakerOpen) {
+		t.Fatalf("expected open gauge, got %v", got)
+	}
+}
+
+func TestBreakerClientIgnoresStreamsTheCallerAbandoned(t *testing.T) {
+	c := newBreakerClient("openai", SimulatedModelClient{}, config.CircuitBreakerConfig{FailureRatio: 1, MinRequests: 1, CooldownMS: 60000}, nil)
+	c.breaker.transition(BreakerHalfOpen)
+
+	_, err := c.Stream(context.Background(), ModelRequest{Model: "m", Messages: userTurn("hi")}, func(string) error {
+		return errors.New("broken pipe")
+	})
+	if err == nil {
+		t.Fatal("expected the delivery error")
+	}
+	if state := c.breaker.State(); state != BreakerHalfOpen {
+		t.Fatalf("expected the abandoned probe to leave the circuit half-open, got %s", state)
+	}
+	if !c.breaker.Allow() {
+		t.Fatal("expected a new probe to be allowed")
+	}
+}
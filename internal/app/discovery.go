@@ -0,0 +1,90 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+)
+
+// modelLister is implemented by backends that can list the models they serve.
+type modelLister interface {
+	ListModels(ctx context.Context) ([]string, error)
+}
+
+// DiscoverModels asks every provider with DiscoverModels set for its models
+// and returns cfg with an exact route added for each model that no route or
+// deployment set matches yet; providers listed earlier win ties. A provider
+// that cannot be listed is skipped and its error joined into the returned
+// one, so the gateway can still start without it.
+func DiscoverModels(ctx context.Context, cfg config.Config) (config.Config, error) {
+	routes := make(map[string]string, len(cfg.ModelRoutes))
+	for pattern, provider := range cfg.ModelRoutes {
+		routes[pattern] = provider
+	}
+	var errs []error
+	for _, p := range cfg.Providers {
+		if !p.DiscoverModels {
+			continue
+		}
+		client, err := NewModelClient(p, nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("provider %q: %w", p.Name, err))
+			continue
+		}
+		lister, ok := client.(modelLister)
+		if !ok {
+			errs = append(errs, fmt.Errorf("provider %q: type %q cannot list models", p.Name, p.Type))
+			continue
+		}
+		models, err := lister.ListModels(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("provider %q: %w", p.Name, err))
+			continue
+		}
+		for _, model := range models {
+			if !routeMatches(routes, model) && !routeMatches(cfg.ModelDeployments, model) {
+				routes[model] = p.Name
+			}
+		}
+	}
+	cfg.ModelRoutes = routes
+	return cfg, errors.Join(errs...)
+}
+
+// routeMatches reports whether any pattern in routes serves model.
+func routeMatches[V any](routes map[string]V, model string) bool {
+	for pattern := range routes {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(model, prefix) || pattern == model {
+			return true
+		}
+	}
+	return false
+}
+
+// localModelPatterns returns the route patterns served only by local
+// providers: direct routes to one, and deployment sets made of them alone.
+func localModelPatterns(cfg config.Config) []string {
+	local := make(map[string]bool)
+	for _, p := range cfg.Providers {
+		local[p.Name] = localProviderTypes[p.Type]
+	}
+	var patterns []string
+	for pattern, provider := range cfg.ModelRoutes {
+		if local[provider] {
+			patterns = append(patterns, pattern)
+		}
+	}
+	for pattern, pool := range cfg.ModelDeployments {
+		allLocal := len(pool.Deployments) > 0
+		for _, d := range pool.Deployments {
+			allLocal = allLocal && local[d.Provider]
+		}
+		if allLocal {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
@@ -0,0 +1,282 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/audit"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/auth"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/billing"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/policy"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/redaction"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+// Operation kinds recorded in audit events and metrics.
+const (
+	OperationCompletion = "completion"
+	OperationEmbedding  = "embedding"
+)
+
+// CodeEmbeddingsUnsupported is returned when the provider serving a model has
+// no embeddings API.
+const CodeEmbeddingsUnsupported = "embeddings_unsupported"
+
+// maxEmbeddingInputs bounds the number of texts embedded in one request.
+const maxEmbeddingInputs = 256
+
+// EmbeddingRequest is a provider-agnostic embeddings call. Dimensions is
+// optional; zero leaves the model default.
+type EmbeddingRequest struct {
+	Model      string
+	Input      []string
+	Dimensions int
+}
+
+// EmbeddingResponse holds one vector per input, in input order.
+type EmbeddingResponse struct {
+	Vectors     [][]float32
+	InputTokens int
+}
+
+// EmbeddingClient abstracts embedding model backends.
+type EmbeddingClient interface {
+	Embed(ctx context.Context, req EmbeddingRequest) (EmbeddingResponse, error)
+}
+
+// embed calls client if it can serve embeddings.
+func embed(ctx context.Context, client ModelClient, req EmbeddingRequest) (EmbeddingResponse, error) {
+	embedder, ok := client.(EmbeddingClient)
+	if !ok {
+		return EmbeddingResponse{}, embeddingsUnsupportedError(req.Model)
+	}
+	return embedder.Embed(ctx, req)
+}
+
+func embeddingsUnsupportedError(model string) *AppError {
+	return &AppError{
+		Code:       CodeEmbeddingsUnsupported,
+		Message:    fmt.Sprintf("model %q does not support embeddings", model),
+		HTTPStatus: http.StatusBadRequest,
+	}
+}
+
+// simulatedEmbeddingDims is the vector size used when none is requested.
+const simulatedEmbeddingDims = 256
+
+// SimulatedEmbeddingClient is a deterministic local embedder: each word is
+// hashed into a signed bucket and the vector is L2-normalized, so texts that
+// share words land close together.
+type SimulatedEmbeddingClient struct{}
+
+func (SimulatedEmbeddingClient) Embed(_ context.Context, req EmbeddingRequest) (EmbeddingResponse, error) {
+	dims := req.Dimensions
+	if dims <= 0 {
+		dims = simulatedEmbeddingDims
+	}
+	out := EmbeddingResponse{Vectors: make([][]float32, 0, len(req.Input))}
+	for _, text := range req.Input {
+		vec := make([]float64, dims)
+		for _, word := range strings.Fields(strings.ToLower(text)) {
+			h := fnv.New64a()
+			_, _ = h.Write([]byte(word))
+			sum := h.Sum64()
+			sign := 1.0
+			if sum>>63 == 1 {
+				sign = -1
+			}
+			vec[sum%uint64(dims)] += sign
+		}
+		var norm float64
+		for _, v := range vec {
+			norm += v * v
+		}
+		norm = math.Sqrt(norm)
+		vector := make([]float32, dims)
+		for i, v := range vec {
+			if norm > 0 {
+				vector[i] = float32(v / norm)
+			}
+		}
+		out.Vectors = append(out.Vectors, vector)
+		out.InputTokens += billing.ApproxTokens(text)
+	}
+	return out, nil
+}
+
+// Embed lets the simulated backend serve embedding routes as well.
+func (SimulatedModelClient) Embed(ctx context.Context, req EmbeddingRequest) (EmbeddingResponse, error) {
+	return SimulatedEmbeddingClient{}.Embed(ctx, req)
+}
+
+func validateEmbeddingRequest(req contracts.EmbeddingRequest) *AppError {
+	if strings.TrimSpace(req.Model) == "" {
+		return &AppError{Code: "invalid_input", Message: "model is required", HTTPStatus: http.StatusBadRequest}
+	}
+	if len(req.Input) == 0 {
+		return &AppError{Code: "invalid_input", Message: "input is required", HTTPStatus: http.StatusBadRequest}
+	}
+	if len(req.Input) > maxEmbeddingInputs {
+		return &AppError{Code: "input_too_large", Message: fmt.Sprintf("at most %d inputs per request", maxEmbeddingInputs), HTTPStatus: http.StatusBadRequest}
+	}
+	if req.EncodingFormat != "" && req.EncodingFormat != "float" {
+		return &AppError{Code: "invalid_input", Message: "only float encoding_format is supported", HTTPStatus: http.StatusBadRequest}
+	}
+	if req.Dimensions != nil && *req.Dimensions <= 0 {
+		return &AppError{Code: "invalid_input", Message: "dimensions must be positive", HTTPStatus: http.StatusBadRequest}
+	}
+	for _, text := range req.Input {
+		if strings.TrimSpace(text) == "" {
+			return &AppError{Code: "invalid_input", Message: "input must not contain empty strings", HTTPStatus: http.StatusBadRequest}
+		}
+		if len([]rune(text)) > maxInputChars {
+			return &AppError{Code: "input_too_large", Message: "input exceeds 32000 characters", HTTPStatus: http.StatusBadRequest}
+		}
+	}
+	return nil
+}
+
+// HandleEmbedding runs an embeddings request through the same allowlist,
+// policy, rate and budget checks as completions. Fallback chains are not
+// applied: vectors from different models are not interchangeable.
+func (s *Service) HandleEmbedding(
+	ctx context.Context,
+	requestID string,
+	principal auth.Principal,
+	req contracts.EmbeddingRequest,
+) (contracts.EmbeddingResponse, *AppError) {
+	start := time.Now()
+	model := req.Model
+	status := "ok"
+
+	track := func(inputTokens int, cost float64) {
+		s.metrics.RequestsTotal.WithLabelValues(principal.Team, model, OperationEmbedding, status).Inc()
+		s.metrics.LatencySec.WithLabelValues(principal.Team, model, OperationEmbedding, status).Observe(time.Since(start).Seconds())
+		if inputTokens > 0 {
+			s.metrics.TokensTotal.WithLabelValues(principal.Team, model, "input").Add(float64(inputTokens))
+		}
+		if cost > 0 {
+			s.metrics.CostTotalUSD.WithLabelValues(principal.Team, model).Add(cost)
+		}
+	}
+
+	if appErr := validateEmbeddingRequest(req); appErr != nil {
+		status = "bad_request"
+		track(0, 0)
+		return contracts.EmbeddingResponse{}, appErr
+	}
+	if info, ok := s.catalog[model]; ok && info.Mode != ModeEmbedding {
+		status = "bad_request"
+		track(0, 0)
+		return contracts.EmbeddingResponse{}, &AppError{Code: CodeUnsupportedFeature, Message: fmt.Sprintf("model %q does not support embeddings", model), HTTPStatus: http.StatusBadRequest}
+	}
+	if router, ok := s.modelClient.(modelSupporter); ok && !router.Supports(model) {
+		status = "bad_request"
+		track(0, 0)
+		return contracts.EmbeddingResponse{}, unknownModelError(model)
+	}
+	embedder, ok := s.modelClient.(EmbeddingClient)
+	if !ok {
+		status = "bad_request"
+		track(0, 0)
+		return contracts.EmbeddingResponse{}, embeddingsUnsupportedError(model)
+	}
+
+	inputs := []string(req.Input)
+	redacted := make([]string, 0, len(inputs))
+	turns := make([]policy.Message, 0, len(inputs))
+	for _, text := range inputs {
+		redacted = append(redacted, redaction.Scrub(text).Text)
+		turns = append(turns, policy.Message{Role: RoleUser, Content: text})
+	}
+	record := func(reason string, cost float64) {
+		s.audit.Add(audit.Event{
+			Timestamp:      time.Now().UTC(),
+			RequestID:      requestID,
+			Team:           principal.Team,
+			Operation:      OperationEmbedding,
+			Model:          model,
+			RequestedModel: model,
+			Status:         status,
+			DenyReason:     reason,
+			RedactedInput:  strings.Join(redacted, "\n"),
+			CostUSD:        cost,
+			LatencyMS:      time.Since(start).Milliseconds(),
+		})
+	}
+
+	decision := s.policy.Evaluate(policy.Input{Model: model, Messages: turns, AllowedModels: principal.AllowedModels})
+	if !decision.Allowed {
+		status = "denied_policy"
+		record(decision.Reason, 0)
+		track(0, 0)
+		return contracts.EmbeddingResponse{}, &AppError{Code: "policy_denied", Message: decision.Reason, HTTPStatus: http.StatusForbidden}
+	}
+
+	if allowed := s.limiter.Allow(principal.Team, principal.RequestsPerMinute, time.Now()); !allowed {
+		status = "rate_limited"
+		record("requests_per_minute_exceeded", 0)
+		track(0, 0)
+		return contracts.EmbeddingResponse{}, &AppError{Code: "rate_limited", Message: "requests_per_minute_exceeded", HTTPStatus: http.StatusTooManyRequests}
+	}
+
+	// Embeddings have no output tokens, so the estimate is the whole cost.
+	inputTokens := 0
+	for _, text := range inputs {
+		tokens := billing.ApproxTokens(text)
+		if info, ok := s.catalog[model]; ok && info.ContextWindow > 0 && tokens > info.ContextWindow {
+			status = "bad_request"
+			record(CodeContextLengthExceeded, 0)
+			track(0, 0)
+			return contracts.EmbeddingResponse{}, contextLengthError(model, tokens, info.ContextWindow)
+		}
+		inputTokens += tokens
+	}
+	if !s.billing.CanAfford(principal.Team, principal.MonthlyBudgetUSD, s.billing.EstimateCost(model, inputTokens, 0)) {
+		status = "budget_exceeded"
+		record("estimated_cost_exceeds_budget", 0)
+		track(0, 0)
+		return contracts.EmbeddingResponse{}, &AppError{Code: "budget_exceeded", Message: "estimated_cost_exceeds_budget", HTTPStatus: http.StatusPaymentRequired}
+	}
+
+	embedReq := EmbeddingRequest{Model: model, Input: inputs}
+	if req.Dimensions != nil {
+		embedReq.Dimensions = *req.Dimensions
+	}
+	result, err := embedder.Embed(ctx, embedReq)
+	if err == nil && len(result.Vectors) != len(inputs) {
+		err = upstreamDecodeError(model, fmt.Errorf("got %d vectors for %d inputs", len(result.Vectors), len(inputs)))
+	}
+	if err != nil {
+		upstreamErr := asUpstreamError(err)
+		status = "upstream_error"
+		s.logger.Error("embedding failed", "request_id", requestID, "team", principal.Team, "code", upstreamErr.Code, "err", err)
+		record(upstreamErr.Code, 0)
+		track(0, 0)
+		return contracts.EmbeddingResponse{}, upstreamErr
+	}
+
+	if result.InputTokens > 0 {
+		inputTokens = result.InputTokens
+	}
+	cost := s.billing.EstimateCost(model, inputTokens, 0)
+	s.billing.Record(principal.Team, model, inputTokens, 0, cost)
+	record("", cost)
+	track(inputTokens, cost)
+
+	data := make([]contracts.EmbeddingData, 0, len(result.Vectors))
+	for i, vec := range result.Vectors {
+		data = append(data, contracts.EmbeddingData{Object: "embedding", Index: i, Embedding: vec})
+	}
+	return contracts.EmbeddingResponse{
+		Object: "list",
+		Data:   data,
+		Model:  model,
+		Usage:  contracts.EmbeddingUsage{PromptTokens: inputTokens, TotalTokens: inputTokens},
+	}, nil
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+func TestSimulatedEmbeddingIsDeterministicAndNormalized(t *testing.T) {
+	req := EmbeddingRequest{Model: "text-embedding-3-small", Input: []string{"failed login burst", "failed login burst", "quarterly revenue"}, Dimensions: 32}
+	a, err := SimulatedEmbeddingClient{}.Embed(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Vectors) != 3 || len(a.Vectors[0]) != 32 || a.InputTokens == 0 {
+		t.Fatalf("unexpected response shape: %d vectors, %d tokens", len(a.Vectors), a.InputTokens)
+	}
+	var norm, same float64
+	for i := range a.Vectors[0] {
+		norm += float64(a.Vectors[0][i] * a.Vectors[0][i])
+		if a.Vectors[0][i] == a.Vectors[1][i] {
+			same++
+		}
+	}
+	if norm < 0.99 || norm > 1.01 {
+		t.Fatalf("expected unit vector, got squared norm %f", norm)
+	}
+	if same != 32 {
+		t.Fatal("expected identical inputs to embed identically")
+	}
+}
+
+func TestHandleEmbeddingBillsInputAndAudits(t *testing.T) {
+	svc, principal := newTestService(t, config.Default(), SimulatedModelClient{})
+	resp, appErr := svc.HandleEmbedding(context.Background(), "req-1", principal, contracts.EmbeddingRequest{
+		Model: "text-embedding-3-small",
+		Input: contracts.EmbeddingInput{"john@example.com logged in", "vpn disconnect"},
+	})
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	if len(resp.Data) != 2 || resp.Data[1].Index != 1 || resp.Usage.PromptTokens == 0 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	usage := svc.Usage(principal)
+	if usage.TotalInputTokens != int64(resp.Usage.PromptTokens) || usage.TotalOutputTokens != 0 || usage.PerModel["text-embedding-3-small"] <= 0 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+	events := svc.AuditEvents(principal, 1)
+	if len(events) != 1 || events[0].Operation != OperationEmbedding || events[0].RedactedInput == "" {
+		t.Fatalf("unexpected audit event: %+v", events)
+	}
+}
+
+func TestHandleEmbeddingEnforcesAllowlist(t *testing.T) {
+	svc, principal := newTestService(t, config.Default(), SimulatedModelClient{})
+	_, appErr := svc.HandleEmbedding(context.Background(), "req-1", principal, contracts.EmbeddingRequest{
+		Model: "text-embedding-3-large",
+		Input: contracts.EmbeddingInput{"hello"},
+	})
+	if appErr == nil || appErr.Code != "policy_denied" {
+		t.Fatalf("expected policy_denied, got %v", appErr)
+	}
+}
+
+// countingEmbedder counts the embedding calls that reach it.
+type countingEmbedder struct {
+	SimulatedModelClient
+	calls *int
+}
+
+func (c countingEmbedder) Embed(ctx context.Context, req EmbeddingRequest) (EmbeddingResponse, error) {
+	*c.calls++
+	return c.SimulatedModelClient.Embed(ctx, req)
+}
+
+func TestHandleEmbeddingRejectsCompletionModels(t *testing.T) {
+	var calls int
+	svc, principal := newTestService(t, config.Default(), countingEmbedder{calls: &calls})
+	_, appErr := svc.HandleEmbedding(context.Background(), "req-1", principal, contracts.EmbeddingRequest{
+		Model: "gpt-4o-mini",
+		Input: contracts.EmbeddingInput{"hello"},
+	})
+	if appErr == nil || appErr.Code != CodeUnsupportedFeature || appErr.HTTPStatus != http.StatusBadRequest {
+		t.Fatalf("expected %s, got %v", CodeUnsupportedFeature, appErr)
+	}
+	if calls != 0 {
+		t.Fatalf("expected no upstream call, got %d", calls)
+	}
+}
+
+func TestRouterEmbedRejectsProvidersWithoutEmbeddings(t *testing.T) {
+	r, err := newRouter(map[string]ModelClient{"anthropic": namedClient("anthropic")}, map[string]string{"claude-*": "anthropic"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.Embed(context.Background(), EmbeddingRequest{Model: "claude-3-5-sonnet", Input: []string{"x"}})
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.Code != CodeEmbeddingsUnsupported {
+		t.Fatalf("expected %s, got %v", CodeEmbeddingsUnsupported, err)
+	}
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"hash/fnv"
+	"log/slog"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+)
+
+// experiment is a configured A/B experiment with its variants' total weight.
+type experiment struct {
+	config.ExperimentConfig
+	totalWeight int
+}
+
+// experimentArm is the experiment variant a request was assigned to.
+type experimentArm struct {
+	Experiment string
+	Variant    string
+	Model      string
+}
+
+// newExperiments groups the configured experiments by team. Experiments
+// without a name, team, model or a positively weighted variant, or with a
+// variant missing its name or model, are dropped with a warning.
+func newExperiments(cfg config.Config, logger *slog.Logger) map[string][]experiment {
+	byTeam := make(map[string][]experiment)
+	for _, e := range cfg.Experiments {
+		total := 0
+		for _, v := range e.Variants {
+			if v.Name == "" || v.Model == "" {
+				total = 0
+				break
+			}
+			if v.Weight > 0 {
+				total += v.Weight
+			}
+		}
+		if e.Name == "" || e.Team == "" || e.Model == "" || total == 0 {
+			logger.Warn("experiment ignored: needs a name, team, model, named variants with models and a variant with positive weight", "experiment", e.Name, "team", e.Team)
+			continue
+		}
+		byTeam[e.Team] = append(byTeam[e.Team], experiment{ExperimentConfig: e, totalWeight: total})
+	}
+	return byTeam
+}
+
+// assignExperiment returns the variant of the first of team's experiments
+// running at now that covers a request for name (or the model it resolves
+// to). The variant is picked by hashing key with the experiment name, so a
+// key always lands on the same variant of an experiment. Requests without a
+// key are not enrolled.
+func (s *Service) assignExperiment(team, name, resolved, key string, now time.Time) (experimentArm, bool) {
+	if key == "" {
+		return experimentArm{}, false
+	}
+	for _, e := range s.experiments[team] {
+		if e.Model != name && e.Model != resolved {
+			continue
+		}
+		if (!e.Start.IsZero() && now.Before(e.Start)) || (!e.End.IsZero() && !now.Before(e.End)) {
+			continue
+		}
+		h := fnv.New64a()
+		h.Write([]byte(e.Name))
+		h.Write([]byte{0})
+		h.Write([]byte(key))
+		bucket := int(h.Sum64() % uint64(e.totalWeight))
+		for _, v := range e.Variants {
+			if v.Weight <= 0 {
+				continue
+			}
+			if bucket < v.Weight {
+				return experimentArm{Experiment: e.Name, Variant: v.Name, Model: v.Model}, true
+			}
+			bucket -= v.Weight
+		}
+	}
+	return experimentArm{}, false
+}
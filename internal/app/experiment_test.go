@@ -0,0 +1,97 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+func TestExperimentAssignsStickyVariants(t *testing.T) {
+	cfg := config.Default()
+	cfg.Experiments = []config.ExperimentConfig{{
+		Name:  "mini-vs-41",
+		Team:  cfg.Teams[0].Name,
+		Model: "fast",
+		Variants: []config.ExperimentVariant{
+			{Name: "control", Model: "gpt-4o-mini", Weight: 1},
+			{Name: "candidate", Model: "gpt-4.1-mini", Weight: 1},
+		},
+	}}
+	svc, principal := newTestService(t, cfg, SimulatedModelClient{})
+	ctx := context.Background()
+
+	seen := make(map[string]string)
+	for i := range 20 {
+		user := fmt.Sprintf("user-%d", i)
+		for range 2 {
+			resp, appErr := svc.HandleCompletion(ctx, "req-"+user, principal, contracts.CompletionRequest{Model: "fast", Input: "hi", User: user})
+			if appErr != nil {
+				t.Fatal(appErr)
+			}
+			if prev, ok := seen[user]; ok && prev != resp.Variant {
+				t.Fatalf("expected %s to keep variant %s, got %s", user, prev, resp.Variant)
+			}
+			seen[user] = resp.Variant
+			if resp.Experiment != "mini-vs-41" || (resp.Variant == "candidate") != (resp.Model == "gpt-4.1-mini") {
+				t.Fatalf("unexpected assignment: %+v", resp)
+			}
+		}
+	}
+	counts := make(map[string]int)
+	for _, v := range seen {
+		counts[v]++
+	}
+	if counts["control"] == 0 || counts["candidate"] == 0 {
+		t.Fatalf("expected both variants to be assigned, got %v", counts)
+	}
+	if ev := svc.AuditEvents(principal, 1)[0]; ev.Experiment != "mini-vs-41" || ev.Variant == "" {
+		t.Fatalf("expected the variant in the audit event, got %+v", ev)
+	}
+
+	resp, appErr := svc.HandleCompletion(ctx, "req-anon", principal, contracts.CompletionRequest{Model: "fast", Input: "hi"})
+	if appErr != nil || resp.Experiment != "" || resp.Model != "gpt-4o-mini" {
+		t.Fatalf("expected requests without a user key to stay out of the experiment, got %+v %v", resp, appErr)
+	}
+}
+
+func TestExperimentOnlyRunsInItsWindow(t *testing.T) {
+	now := time.Now()
+	cfg := config.Default()
+	cfg.Experiments = []config.ExperimentConfig{{
+		Name:     "later",
+		Team:     cfg.Teams[0].Name,
+		Model:    "gpt-4o-mini",
+		Variants: []config.ExperimentVariant{{Name: "only", Model: "gpt-4.1-mini", Weight: 1}},
+		Start:    now.Add(time.Hour),
+	}}
+	svc, principal := newTestService(t, cfg, SimulatedModelClient{})
+
+	if _, ok := svc.assignExperiment(principal.Team, "", "gpt-4o-mini", "user-1", now); ok {
+		t.Fatal("expected no assignment before the experiment starts")
+	}
+	if arm, ok := svc.assignExperiment(principal.Team, "", "gpt-4o-mini", "user-1", now.Add(2*time.Hour)); !ok || arm.Model != "gpt-4.1-mini" {
+		t.Fatalf("expected an assignment once started, got %+v %v", arm, ok)
+	}
+}
+
+func TestExperimentsWithIncompleteVariantsAreDropped(t *testing.T) {
+	cfg := config.Default()
+	team := cfg.Teams[0].Name
+	cfg.Experiments = []config.ExperimentConfig{
+		{Name: "no-model", Team: team, Model: "fast", Variants: []config.ExperimentVariant{
+			{Name: "control", Model: "gpt-4o-mini", Weight: 1},
+			{Name: "candidate", Weight: 1},
+		}},
+		{Name: "no-name", Team: team, Model: "fast", Variants: []config.ExperimentVariant{
+			{Model: "gpt-4.1-mini", Weight: 1},
+		}},
+	}
+	svc, _ := newTestService(t, cfg, SimulatedModelClient{})
+	if got := svc.experiments[team]; len(got) != 0 {
+		t.Fatalf("expected incomplete experiments to be dropped, got %+v", got)
+	}
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/audit"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/auth"
+)
+
+// Attempt outcomes recorded in audit events.
+const (
+	attemptOK      = "ok"
+	attemptFailed  = "failed"
+	attemptSkipped = "skipped"
+)
+
+// isTransient reports whether an upstream failure is worth trying again,
+// either against the same model or a fallback.
+func isTransient(err error) bool {
+	var appErr *AppError
+	if !errors.As(err, &appErr) {
+		return false
+	}
+	switch appErr.Code {
+	case CodeUpstreamTimeout, CodeUpstreamRateLimited, CodeUpstreamUnavailable, CodeUpstreamUnreachable:
+		return true
+	}
+	return false
+}
+
+// canFallback reports whether a failed call may be retried on another model.
+// Open circuits qualify even though retrying the same provider would not.
+func canFallback(err error) bool {
+	var appErr *AppError
+	if errors.As(err, &appErr) && appErr.Code == CodeProviderUnavailable {
+		return true
+	}
+	return isTransient(err)
+}
+
+// fallbackCandidates returns model followed by its configured fallback chain,
+// without duplicates.
+func (s *Service) fallbackCandidates(model string) []string {
+	chain := s.fallbacks[model]
+	out := make([]string, 0, 1+len(chain))
+	seen := map[string]struct{}{model: {}}
+	out = append(out, model)
+	for _, m := range chain {
+		if _, dup := seen[m]; dup {
+			continue
+		}
+		seen[m] = struct{}{}
+		out = append(out, m)
+	}
+	return out
+}
+
+// completeWithFallback calls req.Model and, on transient failures,
+// walks its fallback chain. Fallback candidates must be allowed for the team,
+// routable, able to serve the request per the model catalog and affordable
+// under the pre-call estimate; anything else is
+// recorded as skipped. The first model is assumed to be already admitted.
+// When sink is set the call is streamed, and fallback stops as soon as any
+// output has been delivered; otherwise calls may be hedged (see hedge).
+func (s *Service) completeWithFallback(
+	ctx context.Context,
+	principal auth.Principal,
+	req ModelRequest,
+	inputTokens int,
+	sink *deltaSink,
+) (ModelResponse, string, []audit.Attempt, error) {
+	model := req.Model
+	candidates := s.fallbackCandidates(model)
+	attempts := make([]audit.Attempt, 0, len(candidates))
+	var lastErr error
+
+	for i, candidate := range candidates {
+		if i > 0 {
+			if reason := s.fallbackSkipReason(principal, candidate, req, inputTokens, sink != nil); reason != "" {
+				attempts = append(attempts, audit.Attempt{Model: candidate, Status: attemptSkipped, Reason: reason})
+				continue
+			}
+		}
+
+		started := time.Now()
+		var resp ModelResponse
+		var err error
+		modelReq := req
+		modelReq.Model = candidate
+		if sink != nil {
+			sink.model = candidate
+			resp, err = streamCompletion(ctx, s.modelClient, modelReq, sink.write)
+		} else {
+			var hedged []audit.Attempt
+			resp, hedged, err = s.complete(ctx, principal, modelReq, inputTokens)
+			attempts = append(attempts, hedged...)
+		}
+		if err == nil {
+			attempts = append(attempts, audit.Attempt{Model: candidate, Status: attemptOK, LatencyMS: time.Since(started).Milliseconds()})
+			return resp, candidate, attempts, nil
+		}
+		attempts = append(attempts, audit.Attempt{
+			Model:     candidate,
+			Status:    attemptFailed,
+			Reason:    asUpstreamError(err).Code,
+			LatencyMS: time.Since(started).Milliseconds(),
+		})
+		lastErr = err
+		model = candidate
+		if !canFallback(err) || ctx.Err() != nil || (sink != nil && sink.sent) {
+			break
+		}
+	}
+	return ModelResponse{}, model, attempts, lastErr
+}
+
+func (s *Service) fallbackSkipReason(principal auth.Principal, model string, req ModelRequest, inputTokens int, stream bool) string {
+	if router, ok := s.modelClient.(modelSupporter); ok && !router.Supports(model) {
+		return CodeUnknownModel
+	}
+	decision := s.policy.Evaluate(policyInput(principal, model, req))
+	if !decision.Allowed {
+		return decision.Reason
+	}
+	if appErr := s.checkModelFit(model, req, inputTokens, stream); appErr != nil {
+		return appErr.Code
+	}
+	if !s.billing.CanAfford(principal.Team, principal.MonthlyBudgetUSD, s.billing.EstimateCost(model, inputTokens, outputEstimate(req))) {
+		return "estimated_cost_exceeds_budget"
+	}
+	return ""
+}
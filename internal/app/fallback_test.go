@@ -0,0 +1,51 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+func TestFallbackChainSkipsDisallowedAndUsesNextModel(t *testing.T) {
+	cfg := config.Default()
+	cfg.FallbackChains = map[string][]string{"gpt-4.1-mini": {"claude-3-5-sonnet", "gpt-4o-mini"}}
+	client := failingModels{"gpt-4.1-mini": upstreamStatusError("openai", http.StatusServiceUnavailable, "")}
+	svc, principal := newTestService(t, cfg, client)
+
+	resp, appErr := svc.HandleCompletion(context.Background(), "req-1", principal, contracts.CompletionRequest{Model: "gpt-4.1-mini", Input: "hello"})
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	if resp.Model != "gpt-4o-mini" || resp.RequestedModel != "gpt-4.1-mini" {
+		t.Fatalf("unexpected models: served=%s requested=%s", resp.Model, resp.RequestedModel)
+	}
+
+	events := svc.AuditEvents(principal, 1)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 audit event, got %d", len(events))
+	}
+	got := events[0].Attempts
+	if len(got) != 3 || got[0].Status != attemptFailed || got[1].Status != attemptSkipped || got[2].Status != attemptOK {
+		t.Fatalf("unexpected attempts: %+v", got)
+	}
+	if got[1].Reason != "model_not_allowed_for_team" {
+		t.Fatalf("expected claude to be skipped as not allowed, got %q", got[1].Reason)
+	}
+}
+
+func TestFallbackNotUsedForPermanentErrors(t *testing.T) {
+	cfg := config.Default()
+	client := failingModels{"gpt-4.1-mini": upstreamStatusError("openai", http.StatusBadRequest, "bad")}
+	svc, principal := newTestService(t, cfg, client)
+
+	_, appErr := svc.HandleCompletion(context.Background(), "req-1", principal, contracts.CompletionRequest{Model: "gpt-4.1-mini", Input: "hello"})
+	if appErr == nil || appErr.Code != CodeUpstreamBadRequest {
+		t.Fatalf("expected %s, got %v", CodeUpstreamBadRequest, appErr)
+	}
+	if attempts := svc.AuditEvents(principal, 1)[0].Attempts; len(attempts) != 1 {
+		t.Fatalf("expected a single attempt, got %+v", attempts)
+	}
+}
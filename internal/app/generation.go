@@ -0,0 +1,76 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/auth"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/policy"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+// maxStopSequences is the most stop sequences providers commonly accept.
+const maxStopSequences = 4
+
+// validateGenerationParams checks generation parameters against the ranges
+// providers accept; team limits are enforced by policy.
+func validateGenerationParams(req contracts.CompletionRequest) *AppError {
+	invalid := func(msg string) *AppError {
+		return &AppError{Code: "invalid_parameters", Message: msg, HTTPStatus: http.StatusBadRequest}
+	}
+	if req.MaxTokens != nil && *req.MaxTokens < 1 {
+		return invalid("max_tokens must be at least 1")
+	}
+	if req.Temperature != nil && (*req.Temperature < 0 || *req.Temperature > 2) {
+		return invalid("temperature must be between 0 and 2")
+	}
+	if req.TopP != nil && (*req.TopP <= 0 || *req.TopP > 1) {
+		return invalid("top_p must be greater than 0 and at most 1")
+	}
+	if len(req.Stop) > maxStopSequences {
+		return invalid("at most 4 stop sequences are allowed")
+	}
+	for _, s := range req.Stop {
+		if s == "" {
+			return invalid("stop sequences must not be empty")
+		}
+	}
+	return nil
+}
+
+// withGenerationParams copies req's generation parameters onto modelReq. A
+// request without max_tokens gets the team's ceiling, if it has one.
+func withGenerationParams(modelReq ModelRequest, req contracts.CompletionRequest, principal auth.Principal) ModelRequest {
+	modelReq.MaxTokens = principal.MaxTokens
+	if req.MaxTokens != nil {
+		modelReq.MaxTokens = *req.MaxTokens
+	}
+	modelReq.Temperature = req.Temperature
+	modelReq.TopP = req.TopP
+	modelReq.Stop = req.Stop
+	return modelReq
+}
+
+// policyInput is the policy check of req sent to model on principal's behalf.
+func policyInput(principal auth.Principal, model string, req ModelRequest) policy.Input {
+	return policy.Input{
+		Model:          model,
+		Messages:       policyMessages(req.Messages),
+		Tools:          toolNames(req.Tools),
+		AllowedModels:  principal.AllowedModels,
+		AllowedTools:   principal.AllowedTools,
+		MaxTokens:      req.MaxTokens,
+		Temperature:    req.Temperature,
+		MaxTokensLimit: principal.MaxTokens,
+		MinTemperature: principal.MinTemperature,
+		MaxTemperature: principal.MaxTemperature,
+	}
+}
+
+// outputEstimate is the output size assumed for req in pre-call budget
+// checks: its max_tokens, or estimatedOutputTokens when it sets none.
+func outputEstimate(req ModelRequest) int {
+	if req.MaxTokens > 0 {
+		return req.MaxTokens
+	}
+	return estimatedOutputTokens
+}
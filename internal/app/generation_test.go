@@ -0,0 +1,80 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+// requestRecorder keeps the last request it served.
+type requestRecorder struct{ last *ModelRequest }
+
+func (r requestRecorder) Complete(_ context.Context, req ModelRequest) (ModelResponse, error) {
+	*r.last = req
+	return ModelResponse{Output: "ok"}, nil
+}
+
+func TestGenerationParamsRespectTeamLimits(t *testing.T) {
+	lo, hi := 0.0, 0.7
+	cfg := config.Default()
+	cfg.Teams[0].Generation = &config.GenerationLimits{MaxTokens: 256, MinTemperature: &lo, MaxTemperature: &hi}
+	var last ModelRequest
+	svc, principal := newTestService(t, cfg, requestRecorder{&last})
+	ctx := context.Background()
+
+	temp := 0.2
+	if _, appErr := svc.HandleCompletion(ctx, "req-1", principal, contracts.CompletionRequest{Model: "gpt-4o-mini", Input: "hi", Temperature: &temp, Stop: []string{"END"}}); appErr != nil {
+		t.Fatal(appErr)
+	}
+	if last.MaxTokens != 256 || last.Temperature == nil || *last.Temperature != temp || len(last.Stop) != 1 {
+		t.Fatalf("expected the team ceiling and request params to reach the provider, got %+v", last)
+	}
+
+	tooMany, tooHot := 512, 0.9
+	for name, req := range map[string]contracts.CompletionRequest{
+		"max_tokens_exceeds_team_limit":    {Model: "gpt-4o-mini", Input: "hi", MaxTokens: &tooMany},
+		"temperature_not_allowed_for_team": {Model: "gpt-4o-mini", Input: "hi", Temperature: &tooHot},
+	} {
+		if _, appErr := svc.HandleCompletion(ctx, "req-2", principal, req); appErr == nil || appErr.Code != "policy_denied" || appErr.Message != name {
+			t.Fatalf("expected policy_denied %s, got %v", name, appErr)
+		}
+	}
+
+	badTopP := 1.5
+	if _, appErr := svc.HandleCompletion(ctx, "req-3", principal, contracts.CompletionRequest{Model: "gpt-4o-mini", Input: "hi", TopP: &badTopP}); appErr == nil || appErr.Code != "invalid_parameters" {
+		t.Fatalf("expected invalid_parameters, got %v", appErr)
+	}
+}
+
+func TestBudgetEstimateUsesMaxTokens(t *testing.T) {
+	cfg := config.Default()
+	cfg.Teams[0].MonthlyBudgetUSD = 0.001
+	svc, principal := newTestService(t, cfg, SimulatedModelClient{})
+	ctx := context.Background()
+
+	short := 20
+	if _, appErr := svc.HandleCompletion(ctx, "req-1", principal, contracts.CompletionRequest{Model: "gpt-4o-mini", Input: "hi", MaxTokens: &short}); appErr != nil {
+		t.Fatal(appErr)
+	}
+	long := 1000
+	_, appErr := svc.HandleCompletion(ctx, "req-2", principal, contracts.CompletionRequest{Model: "gpt-4o-mini", Input: "hi", MaxTokens: &long})
+	if appErr == nil || appErr.Message != "estimated_cost_exceeds_budget" {
+		t.Fatalf("expected the max_tokens estimate to exceed the budget, got %v", appErr)
+	}
+}
+
+func TestSimulatedOutputHonorsStopAndMaxTokens(t *testing.T) {
+	resp, err := SimulatedModelClient{}.Complete(context.Background(), ModelRequest{Model: "m", Messages: userTurn("hi"), Stop: []string{"summary"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Output != "[m] triage " {
+		t.Fatalf("expected output cut at the stop sequence, got %q", resp.Output)
+	}
+	resp, _ = SimulatedModelClient{}.Complete(context.Background(), ModelRequest{Model: "m", Messages: userTurn("hi"), MaxTokens: 2})
+	if resp.Output != "[m] tria" {
+		t.Fatalf("expected output cut at max_tokens, got %q", resp.Output)
+	}
+}
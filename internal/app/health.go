@@ -0,0 +1,248 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+)
+
+// Provider health states reported by HealthChecker.
+const (
+	HealthUnknown   = "unknown"
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+)
+
+// prober is implemented by backends that offer a cheap health check, such as
+// listing models. Backends without one are never probed and stay unknown.
+type prober interface {
+	Probe(ctx context.Context) error
+}
+
+// healthReporter is implemented by clients that track provider health.
+type healthReporter interface {
+	ProviderHealth() []ProviderHealth
+}
+
+// availabilityReporter is implemented by clients that know whether a model's
+// backend currently passes its health checks.
+type availabilityReporter interface {
+	Available(model string) bool
+}
+
+// ProviderHealth is one row of the provider health table.
+type ProviderHealth struct {
+	Provider            string
+	Status              string
+	LastCheckedAt       time.Time
+	LatencyMS           int64
+	ConsecutiveFailures int
+	LastError           string
+}
+
+// HealthChecker probes provider backends on an interval and keeps their
+// health state. Unknown providers, including those not yet probed, count as
+// healthy so that startup does not wait for the first round.
+type HealthChecker struct {
+	interval           time.Duration
+	timeout            time.Duration
+	unhealthyThreshold int
+	healthyThreshold   int
+	metrics            *Metrics
+	now                func() time.Time
+
+	mu     sync.Mutex
+	probes map[string]prober
+	state  map[string]*providerState
+}
+
+type providerState struct {
+	ProviderHealth
+	successes int
+}
+
+// NewHealthChecker returns a checker for cfg. metrics may be nil.
+func NewHealthChecker(cfg config.HealthCheckConfig, metrics *Metrics) *HealthChecker {
+	timeout := time.Duration(cfg.TimeoutMS) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &HealthChecker{
+		interval:           time.Duration(cfg.IntervalMS) * time.Millisecond,
+		timeout:            timeout,
+		unhealthyThreshold: max(cfg.UnhealthyThreshold, 1),
+		healthyThreshold:   max(cfg.HealthyThreshold, 1),
+		metrics:            metrics,
+		now:                time.Now,
+		probes:             make(map[string]prober),
+		state:              make(map[string]*providerState),
+	}
+}
+
+// Register adds a provider backend; it is probed only if it implements Probe.
+func (h *HealthChecker) Register(provider string, client ModelClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.state[provider] = &providerState{ProviderHealth: ProviderHealth{Provider: provider, Status: HealthUnknown}}
+	if p, ok := client.(prober); ok {
+		h.probes[provider] = p
+	}
+}
+
+// Run probes every provider immediately and then on each interval until ctx
+// is done. It returns at once when probing is disabled.
+func (h *HealthChecker) Run(ctx context.Context) {
+	if h.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		h.ProbeAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProbeAll probes every provider concurrently and waits for the results.
+func (h *HealthChecker) ProbeAll(ctx context.Context) {
+	h.mu.Lock()
+	probes := make(map[string]prober, len(h.probes))
+	for name, p := range h.probes {
+		probes[name] = p
+	}
+	h.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for name, p := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, h.timeout)
+			defer cancel()
+			started := h.now()
+			err := p.Probe(probeCtx)
+			if ctx.Err() != nil {
+				return
+			}
+			h.record(name, h.now().Sub(started), err)
+		}()
+	}
+	wg.Wait()
+}
+
+func (h *HealthChecker) record(provider string, latency time.Duration, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st := h.state[provider]
+	st.LastCheckedAt = h.now()
+	st.LatencyMS = latency.Milliseconds()
+	if err == nil {
+		st.ConsecutiveFailures = 0
+		st.LastError = ""
+		st.successes++
+		if st.Status != HealthHealthy && (st.Status == HealthUnknown || st.successes >= h.healthyThreshold) {
+			h.transition(st, HealthHealthy)
+		}
+		return
+	}
+	st.successes = 0
+	st.ConsecutiveFailures++
+	st.LastError = probeErrorCode(err)
+	if st.Status != HealthUnhealthy && st.ConsecutiveFailures >= h.unhealthyThreshold {
+		h.transition(st, HealthUnhealthy)
+	}
+}
+
+func (h *HealthChecker) transition(st *providerState, to string) {
+	st.Status = to
+	if h.metrics != nil {
+		healthy := 0.0
+		if to == HealthHealthy {
+			healthy = 1
+		}
+		h.metrics.ProviderHealthy.WithLabelValues(st.Provider).Set(healthy)
+	}
+}
+
+// Healthy reports whether calls should be sent to provider.
+func (h *HealthChecker) Healthy(provider string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st, ok := h.state[provider]
+	return !ok || st.Status != HealthUnhealthy
+}
+
+// Snapshot returns the health table sorted by provider name.
+func (h *HealthChecker) Snapshot() []ProviderHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]ProviderHealth, 0, len(h.state))
+	for _, st := range h.state {
+		out = append(out, st.ProviderHealth)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Provider < out[j].Provider })
+	return out
+}
+
+// probeErrorCode reduces a probe failure to its gateway error code, so the
+// health table does not echo upstream error bodies.
+func probeErrorCode(err error) string {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return CodeUpstreamTimeout
+	}
+	return CodeUpstreamUnreachable
+}
+
+// healthGate fails calls fast while its provider is unhealthy, so that the
+// call falls back like it would on an open circuit.
+type healthGate struct {
+	provider string
+	next     ModelClient
+	health   *HealthChecker
+}
+
+// available lets a Balancer skip deployments on unhealthy providers.
+func (g *healthGate) available() bool { return g.health.Healthy(g.provider) }
+
+func (g *healthGate) Complete(ctx context.Context, req ModelRequest) (ModelResponse, error) {
+	if !g.available() {
+		return ModelResponse{}, providerUnhealthyError(g.provider)
+	}
+	return g.next.Complete(ctx, req)
+}
+
+func (g *healthGate) Stream(ctx context.Context, req ModelRequest, onDelta func(string) error) (ModelResponse, error) {
+	if !g.available() {
+		return ModelResponse{}, providerUnhealthyError(g.provider)
+	}
+	return streamCompletion(ctx, g.next, req, onDelta)
+}
+
+func (g *healthGate) Embed(ctx context.Context, req EmbeddingRequest) (EmbeddingResponse, error) {
+	if !g.available() {
+		return EmbeddingResponse{}, providerUnhealthyError(g.provider)
+	}
+	return embed(ctx, g.next, req)
+}
+
+func providerUnhealthyError(provider string) *AppError {
+	return &AppError{
+		Code:       CodeProviderUnavailable,
+		Message:    fmt.Sprintf("provider %q is unavailable (failing health checks)", provider),
+		HTTPStatus: http.StatusServiceUnavailable,
+	}
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// flakyProbe fails its health probe while down is set.
+type flakyProbe struct {
+	namedClient
+	down bool
+}
+
+func (p *flakyProbe) Probe(context.Context) error {
+	if p.down {
+		return upstreamStatusError("openai", http.StatusServiceUnavailable, "")
+	}
+	return nil
+}
+
+func TestHealthCheckerThresholds(t *testing.T) {
+	metrics := NewMetrics(prometheus.NewRegistry())
+	h := NewHealthChecker(config.HealthCheckConfig{UnhealthyThreshold: 2, HealthyThreshold: 2}, metrics)
+	backend := &flakyProbe{namedClient: "east"}
+	h.Register("east", backend)
+	h.Register("static", namedClient("static"))
+
+	h.ProbeAll(context.Background())
+	if got := h.Snapshot(); got[0].Status != HealthHealthy || got[1].Status != HealthUnknown {
+		t.Fatalf("unexpected table after first round: %+v", got)
+	}
+
+	backend.down = true
+	h.ProbeAll(context.Background())
+	if !h.Healthy("east") {
+		t.Fatal("expected a single failed probe to be tolerated")
+	}
+	h.ProbeAll(context.Background())
+	if h.Healthy("east") {
+		t.Fatal("expected east to be unhealthy after two failed probes")
+	}
+	if st := h.Snapshot()[0]; st.ConsecutiveFailures != 2 || st.LastError != CodeUpstreamUnavailable {
+		t.Fatalf("unexpected state: %+v", st)
+	}
+	if got := testutil.ToFloat64(metrics.ProviderHealthy.WithLabelValues("east")); got != 0 {
+		t.Fatalf("expected health gauge 0, got %v", got)
+	}
+
+	backend.down = false
+	h.ProbeAll(context.Background())
+	if h.Healthy("east") {
+		t.Fatal("expected recovery to need two successful probes")
+	}
+	h.ProbeAll(context.Background())
+	if !h.Healthy("east") {
+		t.Fatal("expected east to recover")
+	}
+}
+
+func TestRouterSkipsUnhealthyProviders(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	cfg := config.Config{
+		Providers: []config.ProviderConfig{
+			{Name: "broken", Type: "openai", BaseURL: down.URL},
+			{Name: "sim", Type: "simulated"},
+		},
+		ModelRoutes: map[string]string{"gpt-4.1-mini": "broken"},
+		ModelDeployments: map[string]config.BalancerConfig{
+			"gpt-4o-mini": {Deployments: []config.DeploymentConfig{{Provider: "broken"}, {Provider: "sim"}}},
+		},
+		HealthCheck: config.HealthCheckConfig{UnhealthyThreshold: 1},
+	}
+	r, err := NewRouter(cfg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.health.ProbeAll(context.Background())
+
+	_, err = r.Complete(context.Background(), ModelRequest{Model: "gpt-4.1-mini", Messages: userTurn("hi")})
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.Code != CodeProviderUnavailable {
+		t.Fatalf("expected %s without calling upstream, got %v", CodeProviderUnavailable, err)
+	}
+	for range 4 {
+		if _, err := r.Complete(context.Background(), ModelRequest{Model: "gpt-4o-mini", Messages: userTurn("hi")}); err != nil {
+			t.Fatalf("expected the balancer to avoid the unhealthy deployment, got %v", err)
+		}
+	}
+
+	health := r.ProviderHealth()
+	if len(health) != 2 || health[0].Provider != "broken" || health[0].Status != HealthUnhealthy || health[1].Status != HealthHealthy {
+		t.Fatalf("unexpected health table: %+v", health)
+	}
+}
@@ -0,0 +1,210 @@
+package app
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/audit"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/auth"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/billing"
+)
+
+// Hedged request outcomes exported as metric labels.
+const (
+	hedgeOutcomePrimaryWon = "primary_won"
+	hedgeOutcomeHedgeWon   = "hedge_won"
+	hedgeOutcomeFailed     = "failed"
+)
+
+// Attempt status and reasons recorded for the losing call of a hedged pair.
+const (
+	attemptCancelled  = "cancelled"
+	reasonPrimaryLost = "primary_lost"
+	reasonHedgeLost   = "hedge_lost"
+)
+
+// latencyWindowSize bounds the latencies kept per model; percentiles are only
+// trusted once minLatencySamples have been seen.
+const (
+	latencyWindowSize = 128
+	minLatencySamples = 20
+)
+
+// latencyWindow keeps the most recent successful call latencies per model.
+type latencyWindow struct {
+	mu      sync.Mutex
+	samples map[string][]time.Duration
+	next    map[string]int
+}
+
+func newLatencyWindow() *latencyWindow {
+	return &latencyWindow{samples: make(map[string][]time.Duration), next: make(map[string]int)}
+}
+
+func (w *latencyWindow) observe(model string, d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if s := w.samples[model]; len(s) < latencyWindowSize {
+		w.samples[model] = append(s, d)
+		return
+	}
+	w.samples[model][w.next[model]] = d
+	w.next[model] = (w.next[model] + 1) % latencyWindowSize
+}
+
+// percentile returns the p-th percentile (0 < p <= 1) of model's recent
+// latencies, or false until enough have been observed.
+func (w *latencyWindow) percentile(model string, p float64) (time.Duration, bool) {
+	w.mu.Lock()
+	sorted := slices.Clone(w.samples[model])
+	w.mu.Unlock()
+	if len(sorted) < minLatencySamples {
+		return 0, false
+	}
+	slices.Sort(sorted)
+	idx := int(p*float64(len(sorted))+0.5) - 1
+	return sorted[min(max(idx, 0), len(sorted)-1)], true
+}
+
+// hedgeDelay returns how long the team's first call to model may run before
+// it is hedged, or false when the team has not opted in.
+func (s *Service) hedgeDelay(team, model string) (time.Duration, bool) {
+	cfg, ok := s.hedging[team]
+	if !ok {
+		return 0, false
+	}
+	delay := time.Duration(cfg.MinDelayMS) * time.Millisecond
+	if p, ok := s.latencies.percentile(model, cfg.Percentile); ok && p > delay {
+		delay = p
+	}
+	return delay, true
+}
+
+// complete makes one non-streamed upstream call, hedged for teams that opted
+// in. The returned attempts describe calls other than the one whose result is
+// returned; losing calls are already billed and carry their cost.
+func (s *Service) complete(ctx context.Context, principal auth.Principal, req ModelRequest, inputTokens int) (ModelResponse, []audit.Attempt, error) {
+	delay, ok := s.hedgeDelay(principal.Team, req.Model)
+	if !ok {
+		started := time.Now()
+		resp, err := s.modelClient.Complete(ctx, req)
+		if err == nil {
+			s.latencies.observe(req.Model, time.Since(started))
+		}
+		return resp, nil, err
+	}
+	return s.hedge(ctx, principal, req, inputTokens, delay)
+}
+
+type hedgeResult struct {
+	resp    ModelResponse
+	err     error
+	hedge   bool
+	latency time.Duration
+}
+
+// hedge starts req and, if it has not returned after delay and the team can
+// afford a second call, starts it again; behind a Balancer the second call
+// lands on another deployment. The first success wins and the other call is
+// cancelled. A failure only ends the hedge once no call is left in flight.
+func (s *Service) hedge(ctx context.Context, principal auth.Principal, req ModelRequest, inputTokens int, delay time.Duration) (ModelResponse, []audit.Attempt, error) {
+	callCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan hedgeResult, 2)
+	launch := func(hedge bool) {
+		go func() {
+			started := time.Now()
+			resp, err := s.modelClient.Complete(callCtx, req)
+			results <- hedgeResult{resp: resp, err: err, hedge: hedge, latency: time.Since(started)}
+		}()
+	}
+
+	launch(false)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	hedgeAt := timer.C
+	inFlight, hedged := 1, false
+	var failures []audit.Attempt
+	for {
+		select {
+		case <-hedgeAt:
+			hedgeAt = nil
+			secondCall := s.billing.EstimateCost(req.Model, inputTokens, outputEstimate(req))
+			if !s.billing.CanAfford(principal.Team, principal.MonthlyBudgetUSD, 2*secondCall) {
+				continue
+			}
+			launch(true)
+			inFlight++
+			hedged = true
+		case r := <-results:
+			inFlight--
+			if r.err != nil {
+				if inFlight > 0 {
+					failures = append(failures, audit.Attempt{Model: req.Model, Status: attemptFailed, Reason: asUpstreamError(r.err).Code, LatencyMS: r.latency.Milliseconds()})
+					continue
+				}
+				if hedged {
+					s.metrics.HedgedRequests.WithLabelValues(req.Model, hedgeOutcomeFailed).Inc()
+				}
+				return ModelResponse{}, failures, r.err
+			}
+			s.latencies.observe(req.Model, r.latency)
+			if !hedged {
+				return r.resp, nil, nil
+			}
+			outcome := hedgeOutcomePrimaryWon
+			if r.hedge {
+				outcome = hedgeOutcomeHedgeWon
+			}
+			s.metrics.HedgedRequests.WithLabelValues(req.Model, outcome).Inc()
+			if inFlight > 0 {
+				cancel()
+				failures = append(failures, s.settleHedgeLoser(principal, req.Model, inputTokens, <-results))
+			}
+			return r.resp, failures, nil
+		}
+	}
+}
+
+// settleHedgeLoser bills the losing call of a hedged pair. A cancelled call is
+// billed for its prompt, which the provider has already received.
+func (s *Service) settleHedgeLoser(principal auth.Principal, model string, inputTokens int, loser hedgeResult) audit.Attempt {
+	reason := reasonPrimaryLost
+	if loser.hedge {
+		reason = reasonHedgeLost
+	}
+	attempt := audit.Attempt{Model: model, Status: attemptCancelled, Reason: reason, LatencyMS: loser.latency.Milliseconds()}
+	outputTokens := 0
+	if loser.err == nil {
+		attempt.Status = attemptOK
+		if loser.resp.InputTokens > 0 {
+			inputTokens = loser.resp.InputTokens
+		}
+		outputTokens = loser.resp.OutputTokens
+		if outputTokens == 0 {
+			outputTokens = billing.ApproxTokens(loser.resp.Output)
+		}
+	}
+	attempt.CostUSD = s.billing.EstimateCost(model, inputTokens, outputTokens)
+	s.billing.RecordOverhead(principal.Team, model, inputTokens, outputTokens, attempt.CostUSD)
+	s.metrics.TokensTotal.WithLabelValues(principal.Team, model, "input").Add(float64(inputTokens))
+	if outputTokens > 0 {
+		s.metrics.TokensTotal.WithLabelValues(principal.Team, model, "output").Add(float64(outputTokens))
+	}
+	if attempt.CostUSD > 0 {
+		s.metrics.CostTotalUSD.WithLabelValues(principal.Team, model).Add(attempt.CostUSD)
+	}
+	return attempt
+}
+
+// extraCost sums the cost of calls billed besides the one that served the
+// request.
+func extraCost(attempts []audit.Attempt) float64 {
+	total := 0.0
+	for _, a := range attempts {
+		total += a.CostUSD
+	}
+	return total
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// stallFirst blocks its first call until it is cancelled and answers every
+// later call at once.
+type stallFirst struct {
+	calls atomic.Int32
+}
+
+func (c *stallFirst) Complete(ctx context.Context, req ModelRequest) (ModelResponse, error) {
+	if c.calls.Add(1) == 1 {
+		<-ctx.Done()
+		return ModelResponse{}, upstreamTransportError("openai", ctx.Err())
+	}
+	return SimulatedModelClient{}.Complete(ctx, req)
+}
+
+func TestLatencyWindowPercentile(t *testing.T) {
+	w := newLatencyWindow()
+	for i := 1; i < minLatencySamples; i++ {
+		w.observe("m", time.Duration(i)*time.Millisecond)
+	}
+	if _, ok := w.percentile("m", 0.9); ok {
+		t.Fatal("expected no percentile before enough samples")
+	}
+	for i := minLatencySamples; i <= 2*latencyWindowSize; i++ {
+		w.observe("m", time.Duration(i)*time.Millisecond)
+	}
+	// Only the latest latencyWindowSize samples (129ms..256ms) are kept.
+	if p, ok := w.percentile("m", 0.5); !ok || p != 192*time.Millisecond {
+		t.Fatalf("unexpected median %v", p)
+	}
+}
+
+func TestHedgedCompletionCancelsLoserAndBillsBoth(t *testing.T) {
+	cfg := config.Default()
+	cfg.Teams[0].Hedging = &config.HedgingConfig{Percentile: 0.95, MinDelayMS: 5}
+	client := &stallFirst{}
+	svc, principal := newTestService(t, cfg, client)
+
+	resp, appErr := svc.HandleCompletion(context.Background(), "req-1", principal, contracts.CompletionRequest{Model: "gpt-4o-mini", Input: "check the login burst"})
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	if client.calls.Load() != 2 {
+		t.Fatalf("expected a hedged second call, got %d calls", client.calls.Load())
+	}
+
+	ev := svc.AuditEvents(principal, 1)[0]
+	if len(ev.Attempts) != 2 || ev.Attempts[0].Status != attemptCancelled || ev.Attempts[0].Reason != reasonPrimaryLost || ev.Attempts[0].CostUSD <= 0 || ev.Attempts[1].Status != attemptOK {
+		t.Fatalf("unexpected attempts: %+v", ev.Attempts)
+	}
+	usage := svc.Usage(principal)
+	if usage.TotalRequests != 1 || usage.TotalInputTokens != int64(2*resp.InputTokens) {
+		t.Fatalf("expected one request billed for both prompts, got %+v", usage)
+	}
+	if diff := usage.TotalCostUSD - resp.CostUSD; diff > 1e-12 || diff < -1e-12 || ev.CostUSD != resp.CostUSD {
+		t.Fatalf("expected response and audit cost %v to match billed %v", resp.CostUSD, usage.TotalCostUSD)
+	}
+	if got := testutil.ToFloat64(svc.metrics.HedgedRequests.WithLabelValues("gpt-4o-mini", hedgeOutcomeHedgeWon)); got != 1 {
+		t.Fatalf("expected hedge_won to be counted, got %v", got)
+	}
+}
+
+func TestCompletionNotHedgedWithoutOptIn(t *testing.T) {
+	client := &stallFirst{}
+	client.calls.Store(1)
+	svc, principal := newTestService(t, config.Default(), client)
+	if _, appErr := svc.HandleCompletion(context.Background(), "req-1", principal, contracts.CompletionRequest{Model: "gpt-4o-mini", Input: "hi"}); appErr != nil {
+		t.Fatal(appErr)
+	}
+	if ev := svc.AuditEvents(principal, 1)[0]; len(ev.Attempts) != 1 || client.calls.Load() != 2 {
+		t.Fatalf("expected a single unhedged call, got %+v", ev.Attempts)
+	}
+}
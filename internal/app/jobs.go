@@ -0,0 +1,443 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/auth"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/jobs"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+// Error codes of the job API.
+const (
+	CodeJobNotFound  = "job_not_found"
+	CodeJobQueueFull = "job_queue_full"
+	CodeJobFinished  = "job_finished"
+)
+
+const (
+	// jobRetention is how long finished jobs can still be fetched.
+	jobRetention = 24 * time.Hour
+	// webhookAttempts bounds the deliveries of one job's callback.
+	webhookAttempts    = 3
+	webhookTimeout     = 10 * time.Second
+	webhookDialTimeout = 5 * time.Second
+)
+
+// Webhook delivery outcomes reported in JobView.WebhookStatus.
+const (
+	webhookDelivered = "delivered"
+	webhookFailed    = "failed"
+)
+
+// Headers of webhook callbacks. The signature is the hex HMAC-SHA256, keyed
+// with the webhook secret, of the timestamp, a ".", and the body.
+const (
+	WebhookTimestampHeader = "X-Gateway-Timestamp"
+	WebhookSignatureHeader = "X-Gateway-Signature"
+)
+
+// jobRunner queues admitted jobs for a bounded pool of workers and tracks
+// the running ones so they can be cancelled.
+type jobRunner struct {
+	store         *jobs.Store
+	queue         chan string
+	workers       int
+	webhookSecret string
+	webhookHosts  map[string]struct{}
+	webhookClient *http.Client
+
+	mu      sync.Mutex
+	running map[string]context.CancelFunc
+}
+
+// newJobRunner restores the jobs saved at cfg.StatePath and queues the
+// unfinished ones. When the state cannot be read, jobs are kept in memory
+// only.
+func newJobRunner(cfg config.JobsConfig, logger *slog.Logger) *jobRunner {
+	store, err := jobs.NewStore(cfg.StatePath, jobRetention)
+	if err != nil {
+		logger.Error("job state unavailable, keeping jobs in memory only", "path", cfg.StatePath, "err", err)
+		store, _ = jobs.NewStore("", jobRetention)
+	}
+	queued := store.Queued()
+	hosts := make(map[string]struct{}, len(cfg.WebhookHosts))
+	for _, h := range cfg.WebhookHosts {
+		hosts[strings.ToLower(h)] = struct{}{}
+	}
+	r := &jobRunner{
+		store:         store,
+		queue:         make(chan string, max(cfg.QueueSize, len(queued), 1)),
+		workers:       max(cfg.Workers, 1),
+		webhookSecret: cfg.WebhookSecret,
+		webhookHosts:  hosts,
+		webhookClient: newWebhookClient(hosts),
+		running:       make(map[string]context.CancelFunc),
+	}
+	for _, id := range queued {
+		r.queue <- id
+	}
+	if len(queued) > 0 {
+		logger.Info("resuming queued jobs", "jobs", len(queued))
+	}
+	return r
+}
+
+// SubmitJob admits req as it would a completion, with the same validation,
+// policy, rate limit and budget checks, and queues it to run in the
+// background. The job's result is posted to webhookURL, when set, once it
+// finishes.
+func (s *Service) SubmitJob(ctx context.Context, requestID string, principal auth.Principal, req contracts.CompletionRequest, webhookURL string) (contracts.JobView, *AppError) {
+	if req.Stream {
+		return contracts.JobView{}, &AppError{Code: "invalid_request", Message: "jobs cannot be streamed", HTTPStatus: http.StatusBadRequest}
+	}
+	if webhookURL != "" {
+		if s.jobs.webhookSecret == "" {
+			return contracts.JobView{}, &AppError{Code: "invalid_request", Message: "webhooks are not enabled on this gateway", HTTPStatus: http.StatusBadRequest}
+		}
+		u, err := url.Parse(webhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+			return contracts.JobView{}, &AppError{Code: "invalid_request", Message: "webhook URL must be an absolute http(s) URL", HTTPStatus: http.StatusBadRequest}
+		}
+		if _, ok := s.jobs.webhookHosts[strings.ToLower(u.Hostname())]; len(s.jobs.webhookHosts) > 0 && !ok {
+			return contracts.JobView{}, &AppError{Code: "invalid_request", Message: "webhook host " + u.Hostname() + " is not allowed", HTTPStatus: http.StatusBadRequest}
+		}
+	}
+	if _, appErr := s.handleCompletion(ctx, requestID, principal, req, nil, admitOnly); appErr != nil {
+		return contracts.JobView{}, appErr
+	}
+
+	model := req.Model
+	if model == "" {
+		model = s.defaultModel
+	}
+	job := jobs.Job{
+		ID:         newJobID(),
+		RequestID:  requestID,
+		Team:       principal.Team,
+		Model:      model,
+		Status:     jobs.StatusQueued,
+		Request:    &req,
+		WebhookURL: webhookURL,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err := s.jobs.store.Create(job); err != nil {
+		s.logger.Warn("job state not saved", "job_id", job.ID, "err", err)
+	}
+	select {
+	case s.jobs.queue <- job.ID:
+	default:
+		_ = s.jobs.store.Delete(job.ID)
+		// The job never runs, so it should not count against the team's rate.
+		s.limiter.Release(principal.Team, time.Now())
+		return contracts.JobView{}, &AppError{Code: CodeJobQueueFull, Message: "too many queued jobs, retry later", HTTPStatus: http.StatusServiceUnavailable}
+	}
+	return jobView(job), nil
+}
+
+// Job returns principal's job with id.
+func (s *Service) Job(principal auth.Principal, id string) (contracts.JobView, *AppError) {
+	job, ok := s.jobs.store.Get(id)
+	if !ok || job.Team != principal.Team {
+		return contracts.JobView{}, jobNotFound(id)
+	}
+	return jobView(job), nil
+}
+
+// CancelJob cancels principal's job with id. A running job's model call is
+// cancelled; whatever it already cost stays billed. Finished jobs cannot be
+// cancelled.
+func (s *Service) CancelJob(principal auth.Principal, id string) (contracts.JobView, *AppError) {
+	errFinished := errors.New(CodeJobFinished)
+	wasRunning := false
+	job, err := s.jobs.store.Update(id, func(j *jobs.Job) error {
+		if j.Team != principal.Team {
+			return jobs.ErrNotFound
+		}
+		if j.Finished() {
+			return errFinished
+		}
+		wasRunning = j.Status == jobs.StatusRunning
+		now := time.Now().UTC()
+		j.Status, j.FinishedAt, j.Request = jobs.StatusCancelled, &now, nil
+		return nil
+	})
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		return contracts.JobView{}, jobNotFound(id)
+	case errors.Is(err, errFinished):
+		return contracts.JobView{}, &AppError{Code: CodeJobFinished, Message: "job " + id + " already finished", HTTPStatus: http.StatusConflict}
+	case err != nil:
+		s.logger.Warn("job state not saved", "job_id", id, "err", err)
+	}
+
+	if wasRunning {
+		// The worker delivers the webhook once the call has stopped.
+		s.jobs.mu.Lock()
+		if cancel := s.jobs.running[id]; cancel != nil {
+			cancel()
+		}
+		s.jobs.mu.Unlock()
+	} else if job.WebhookURL != "" {
+		s.background.Add(1)
+		go func() {
+			defer s.background.Done()
+			s.deliverWebhook(context.Background(), job)
+		}()
+	}
+	return jobView(job), nil
+}
+
+// RunJobs runs queued jobs on the configured number of workers until ctx is
+// done. Jobs interrupted by ctx are queued again, so they run after a
+// restart when job state is persisted.
+func (s *Service) RunJobs(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range s.jobs.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-s.jobs.queue:
+					s.runJob(ctx, id)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// runJob serves the queued job with id. Its cancel func is registered
+// before the job is marked running, so CancelJob can always stop a running
+// job.
+func (s *Service) runJob(ctx context.Context, id string) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.jobs.mu.Lock()
+	s.jobs.running[id] = cancel
+	s.jobs.mu.Unlock()
+	defer func() {
+		s.jobs.mu.Lock()
+		delete(s.jobs.running, id)
+		s.jobs.mu.Unlock()
+	}()
+
+	errNotQueued := errors.New("job not queued")
+	job, err := s.jobs.store.Update(id, func(j *jobs.Job) error {
+		if j.Status != jobs.StatusQueued {
+			return errNotQueued
+		}
+		now := time.Now().UTC()
+		j.Status, j.StartedAt = jobs.StatusRunning, &now
+		return nil
+	})
+	if errors.Is(err, errNotQueued) || errors.Is(err, jobs.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		s.logger.Warn("job state not saved", "job_id", id, "err", err)
+	}
+
+	var (
+		resp   contracts.CompletionResponse
+		appErr *AppError
+	)
+	if principal, ok := s.auth.Principal(job.Team); !ok {
+		appErr = &AppError{Code: "invalid_api_key", Message: "team " + job.Team + " is no longer configured", HTTPStatus: http.StatusUnauthorized}
+	} else {
+		resp, appErr = s.handleCompletion(jobCtx, job.RequestID, principal, *job.Request, nil, serveAdmitted)
+	}
+
+	job, err = s.jobs.store.Update(id, func(j *jobs.Job) error {
+		if j.Status == jobs.StatusCancelled {
+			return nil
+		}
+		if appErr != nil && ctx.Err() != nil {
+			j.Status, j.StartedAt = jobs.StatusQueued, nil
+			return nil
+		}
+		now := time.Now().UTC()
+		j.Request, j.FinishedAt = nil, &now
+		if appErr != nil {
+			errResp := appErr.WithRequestID(j.RequestID)
+			j.Status, j.Error = jobs.StatusFailed, &errResp
+		} else {
+			j.Status, j.Result = jobs.StatusSucceeded, &resp
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Warn("job state not saved", "job_id", id, "err", err)
+	}
+	if job.Status == jobs.StatusQueued {
+		// Picked up again by RunJobs if it restarts in this process.
+		select {
+		case s.jobs.queue <- id:
+		default:
+		}
+		return
+	}
+	s.deliverWebhook(ctx, job)
+}
+
+// deliverWebhook posts the finished job to its webhook URL, retrying failed
+// deliveries, and records the outcome on the job.
+func (s *Service) deliverWebhook(ctx context.Context, job jobs.Job) {
+	if job.WebhookURL == "" {
+		return
+	}
+	body, err := json.Marshal(jobView(job))
+	if err != nil {
+		return
+	}
+	status := webhookFailed
+	for attempt := range webhookAttempts {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if err = s.postWebhook(ctx, job.WebhookURL, body); err == nil {
+			status = webhookDelivered
+			break
+		}
+		if errors.Is(err, errWebhookAddrRefused) {
+			break
+		}
+	}
+	if err != nil {
+		s.logger.Warn("job webhook not delivered", "job_id", job.ID, "err", err)
+	}
+	if _, err := s.jobs.store.Update(job.ID, func(j *jobs.Job) error {
+		j.WebhookStatus = status
+		return nil
+	}); err != nil && !errors.Is(err, jobs.ErrNotFound) {
+		s.logger.Warn("job state not saved", "job_id", job.ID, "err", err)
+	}
+}
+
+func (s *Service) postWebhook(ctx context.Context, target string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(s.jobs.webhookSecret, timestamp, body))
+	resp, err := s.jobs.webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("webhook returned " + resp.Status)
+	}
+	return nil
+}
+
+// errWebhookAddrRefused is returned when a webhook host resolves to an
+// address callbacks may not reach.
+var errWebhookAddrRefused = errors.New("webhook address not allowed")
+
+// newWebhookClient returns the client posting webhook callbacks. It does not
+// follow redirects, and it refuses to connect to loopback, private and
+// link-local addresses unless the host is one of trusted. The check runs on
+// the address actually dialled, so a host cannot get around it by resolving
+// differently at delivery time.
+func newWebhookClient(trusted map[string]struct{}) *http.Client {
+	guarded := &net.Dialer{Timeout: webhookDialTimeout, Control: refuseInternalAddr}
+	plain := &net.Dialer{Timeout: webhookDialTimeout}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would dial on the gateway's behalf and skip the address check.
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, _ := net.SplitHostPort(addr)
+		if _, ok := trusted[strings.ToLower(host)]; ok {
+			return plain.DialContext(ctx, network, addr)
+		}
+		return guarded.DialContext(ctx, network, addr)
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   webhookTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func refuseInternalAddr(_, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return errWebhookAddrRefused
+	}
+	ip := ap.Addr().Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return errWebhookAddrRefused
+	}
+	return nil
+}
+
+// SignWebhook returns the signature header value of a webhook callback with
+// the given timestamp header and body.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func jobView(j jobs.Job) contracts.JobView {
+	return contracts.JobView{
+		ID:            j.ID,
+		Status:        j.Status,
+		Model:         j.Model,
+		RequestID:     j.RequestID,
+		Result:        j.Result,
+		Error:         j.Error,
+		WebhookStatus: j.WebhookStatus,
+		CreatedAt:     j.CreatedAt,
+		StartedAt:     j.StartedAt,
+		FinishedAt:    j.FinishedAt,
+	}
+}
+
+func jobNotFound(id string) *AppError {
+	return &AppError{Code: CodeJobNotFound, Message: "job " + id + " not found", HTTPStatus: http.StatusNotFound}
+}
+
+func newJobID() string {
+	buf := make([]byte, 12)
+	_, _ = rand.Read(buf)
+	return "job-" + hex.EncodeToString(buf)
+}
@@ -0,0 +1,237 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/jobs"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+// waitForJob polls until the job leaves the queued and running states.
+func waitForJob(t *testing.T, svc *Service, id string) contracts.JobView {
+	t.Helper()
+	for range 200 {
+		if job, ok := svc.jobs.store.Get(id); ok && job.Finished() && (job.WebhookURL == "" || job.WebhookStatus != "") {
+			return jobView(job)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return contracts.JobView{}
+}
+
+func TestJobRunsAndPostsSignedWebhook(t *testing.T) {
+	type callback struct {
+		body      []byte
+		timestamp string
+		signature string
+	}
+	received := make(chan callback, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- callback{body, r.Header.Get(WebhookTimestampHeader), r.Header.Get(WebhookSignatureHeader)}
+	}))
+	defer hook.Close()
+
+	cfg := config.Default()
+	cfg.Jobs.WebhookSecret = "s3cret"
+	cfg.Jobs.WebhookHosts = []string{"127.0.0.1"}
+	svc, principal := newTestService(t, cfg, SimulatedModelClient{})
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go svc.RunJobs(ctx)
+
+	submitted, appErr := svc.SubmitJob(ctx, "req-1", principal, contracts.CompletionRequest{Model: "gpt-4o-mini", Input: "summarize the login burst"}, hook.URL)
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	job := waitForJob(t, svc, submitted.ID)
+	if job.Status != jobs.StatusSucceeded || job.Result == nil || job.Result.RequestID != "req-1" || job.WebhookStatus != webhookDelivered {
+		t.Fatalf("unexpected job: %+v", job)
+	}
+	if usage := svc.Usage(principal); usage.TotalRequests != 1 {
+		t.Fatalf("expected the job to be billed once, got %+v", usage)
+	}
+
+	cb := <-received
+	if cb.signature != SignWebhook("s3cret", cb.timestamp, cb.body) {
+		t.Fatalf("webhook signature %q does not match its body", cb.signature)
+	}
+}
+
+func TestWebhookRefusesInternalAddresses(t *testing.T) {
+	called := make(chan struct{}, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		called <- struct{}{}
+	}))
+	defer hook.Close()
+
+	cfg := config.Default()
+	cfg.Jobs.WebhookSecret = "s3cret"
+	svc, principal := newTestService(t, cfg, SimulatedModelClient{})
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go svc.RunJobs(ctx)
+
+	submitted, appErr := svc.SubmitJob(ctx, "req-1", principal, contracts.CompletionRequest{Model: "gpt-4o-mini", Input: "hi"}, hook.URL)
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	if job := waitForJob(t, svc, submitted.ID); job.Status != jobs.StatusSucceeded || job.WebhookStatus != webhookFailed {
+		t.Fatalf("expected the loopback webhook to be refused, got %+v", job)
+	}
+	select {
+	case <-called:
+		t.Fatal("expected the loopback webhook not to be called")
+	default:
+	}
+
+	cfg.Jobs.WebhookHosts = []string{"hooks.example.com"}
+	svc, principal = newTestService(t, cfg, SimulatedModelClient{})
+	if _, appErr := svc.SubmitJob(ctx, "req-2", principal, contracts.CompletionRequest{Model: "gpt-4o-mini", Input: "hi"}, hook.URL); appErr == nil || appErr.Code != "invalid_request" {
+		t.Fatalf("expected a host outside WebhookHosts to be rejected, got %v", appErr)
+	}
+}
+
+func TestWebhookClientDoesNotFollowRedirects(t *testing.T) {
+	followed := make(chan struct{}, 1)
+	target := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		followed <- struct{}{}
+	}))
+	defer target.Close()
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	}))
+	defer hook.Close()
+
+	client := newWebhookClient(map[string]struct{}{"127.0.0.1": {}})
+	resp, err := client.Post(hook.URL, "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTemporaryRedirect {
+		t.Fatalf("expected the redirect to be returned, got %d", resp.StatusCode)
+	}
+	select {
+	case <-followed:
+		t.Fatal("expected the redirect not to be followed")
+	default:
+	}
+}
+
+func TestJobAdmissionChecksRunBeforeQueueing(t *testing.T) {
+	cfg := config.Default()
+	cfg.Teams[0].MonthlyBudgetUSD = 0.000001
+	svc, principal := newTestService(t, cfg, SimulatedModelClient{})
+	ctx := context.Background()
+
+	for name, tc := range map[string]struct {
+		req     contracts.CompletionRequest
+		webhook string
+		code    string
+	}{
+		"policy":           {contracts.CompletionRequest{Model: "claude-3-5-sonnet", Input: "hi"}, "", "policy_denied"},
+		"budget":           {contracts.CompletionRequest{Model: "gpt-4o-mini", Input: "hi"}, "", "budget_exceeded"},
+		"stream":           {contracts.CompletionRequest{Model: "gpt-4o-mini", Input: "hi", Stream: true}, "", "invalid_request"},
+		"webhook_disabled": {contracts.CompletionRequest{Model: "gpt-4o-mini", Input: "hi"}, "https://example.com/hook", "invalid_request"},
+	} {
+		if _, appErr := svc.SubmitJob(ctx, "req-"+name, principal, tc.req, tc.webhook); appErr == nil || appErr.Code != tc.code {
+			t.Fatalf("%s: expected %s, got %v", name, tc.code, appErr)
+		}
+	}
+	if queued := svc.jobs.store.Queued(); len(queued) != 0 {
+		t.Fatalf("expected rejected jobs not to be queued, got %v", queued)
+	}
+}
+
+func TestFullJobQueueDoesNotChargeRateLimit(t *testing.T) {
+	cfg := config.Default()
+	cfg.Jobs.QueueSize = 1
+	cfg.Teams[0].RequestsPerMinute = 2
+	svc, principal := newTestService(t, cfg, SimulatedModelClient{})
+	ctx := context.Background()
+	req := contracts.CompletionRequest{Model: "gpt-4o-mini", Input: "hi"}
+
+	if _, appErr := svc.SubmitJob(ctx, "req-1", principal, req, ""); appErr != nil {
+		t.Fatal(appErr)
+	}
+	for _, id := range []string{"req-2", "req-3", "req-4"} {
+		if _, appErr := svc.SubmitJob(ctx, id, principal, req, ""); appErr == nil || appErr.Code != CodeJobQueueFull {
+			t.Fatalf("%s: expected %s, got %v", id, CodeJobQueueFull, appErr)
+		}
+	}
+	if _, appErr := svc.HandleCompletion(ctx, "req-5", principal, req); appErr != nil {
+		t.Fatalf("expected refused jobs not to use up the rate limit, got %v", appErr)
+	}
+}
+
+func TestQueuedJobsSurviveRestartAndCanBeCancelled(t *testing.T) {
+	cfg := config.Default()
+	cfg.Jobs.StatePath = filepath.Join(t.TempDir(), "jobs.json")
+	svc, principal := newTestService(t, cfg, SimulatedModelClient{})
+	ctx := context.Background()
+
+	var ids []string
+	for _, id := range []string{"req-1", "req-2"} {
+		job, appErr := svc.SubmitJob(ctx, id, principal, contracts.CompletionRequest{Model: "gpt-4o-mini", Input: "hi"}, "")
+		if appErr != nil {
+			t.Fatal(appErr)
+		}
+		ids = append(ids, job.ID)
+	}
+	if job, appErr := svc.CancelJob(principal, ids[1]); appErr != nil || job.Status != jobs.StatusCancelled {
+		t.Fatalf("expected the queued job to be cancelled, got %+v %v", job, appErr)
+	}
+	if _, appErr := svc.CancelJob(principal, ids[1]); appErr == nil || appErr.Code != CodeJobFinished {
+		t.Fatalf("expected %s, got %v", CodeJobFinished, appErr)
+	}
+
+	restarted, _ := newTestService(t, cfg, SimulatedModelClient{})
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	go restarted.RunJobs(runCtx)
+
+	if job := waitForJob(t, restarted, ids[0]); job.Status != jobs.StatusSucceeded {
+		t.Fatalf("expected the queued job to run after the restart, got %+v", job)
+	}
+	if job, appErr := restarted.Job(principal, ids[1]); appErr != nil || job.Status != jobs.StatusCancelled {
+		t.Fatalf("expected the cancelled job to stay cancelled, got %+v %v", job, appErr)
+	}
+}
+
+// blockingClient holds every call until its context is done.
+type blockingClient struct{ started chan struct{} }
+
+func (c blockingClient) Complete(ctx context.Context, _ ModelRequest) (ModelResponse, error) {
+	c.started <- struct{}{}
+	<-ctx.Done()
+	return ModelResponse{}, ctx.Err()
+}
+
+func TestCancellingRunningJobStopsItsCall(t *testing.T) {
+	client := blockingClient{started: make(chan struct{}, 1)}
+	svc, principal := newTestService(t, config.Default(), client)
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go svc.RunJobs(ctx)
+
+	submitted, appErr := svc.SubmitJob(ctx, "req-1", principal, contracts.CompletionRequest{Model: "gpt-4o-mini", Input: "hi"}, "")
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	<-client.started
+	if _, appErr := svc.CancelJob(principal, submitted.ID); appErr != nil {
+		t.Fatal(appErr)
+	}
+	if job := waitForJob(t, svc, submitted.ID); job.Status != jobs.StatusCancelled || job.Result != nil {
+		t.Fatalf("expected the running job to end cancelled, got %+v", job)
+	}
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/audit"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/billing"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/policy"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/redaction"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+// maxInputChars bounds the total characters across all turns of a request.
+const maxInputChars = 32000
+
+// Conversation roles accepted in CompletionRequest.Messages.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// validateCompletionInput checks that exactly one of Input/Messages is set and
+// that every turn is well-formed. Assistant turns that only carry tool calls
+// may have empty content; tool turns must name the call they answer.
+func validateCompletionInput(req contracts.CompletionRequest) *AppError {
+	if req.Input != "" && len(req.Messages) > 0 {
+		return &AppError{Code: "invalid_input", Message: "input and messages are mutually exclusive", HTTPStatus: http.StatusBadRequest}
+	}
+	if req.Input == "" && len(req.Messages) == 0 {
+		return &AppError{Code: "invalid_input", Message: "input is required", HTTPStatus: http.StatusBadRequest}
+	}
+
+	total := len([]rune(req.Input))
+	hasUser := req.Input != ""
+	for _, m := range req.Messages {
+		switch m.Role {
+		case RoleSystem, RoleAssistant:
+		case RoleUser:
+			hasUser = true
+		case RoleTool:
+			if m.ToolCallID == "" {
+				return &AppError{Code: "invalid_messages", Message: "tool messages require tool_call_id", HTTPStatus: http.StatusBadRequest}
+			}
+		default:
+			return &AppError{Code: "invalid_messages", Message: "unsupported message role: " + m.Role, HTTPStatus: http.StatusBadRequest}
+		}
+		if len(m.ToolCalls) > 0 && m.Role != RoleAssistant {
+			return &AppError{Code: "invalid_messages", Message: "only assistant messages may carry tool_calls", HTTPStatus: http.StatusBadRequest}
+		}
+		for _, call := range m.ToolCalls {
+			if call.ID == "" || call.Name == "" {
+				return &AppError{Code: "invalid_messages", Message: "tool_calls require id and name", HTTPStatus: http.StatusBadRequest}
+			}
+			total += len([]rune(call.Arguments))
+		}
+		if strings.TrimSpace(m.Content) == "" && len(m.ToolCalls) == 0 {
+			return &AppError{Code: "invalid_messages", Message: "message content is required", HTTPStatus: http.StatusBadRequest}
+		}
+		total += len([]rune(m.Content))
+	}
+	if !hasUser {
+		return &AppError{Code: "invalid_messages", Message: "at least one user message is required", HTTPStatus: http.StatusBadRequest}
+	}
+	if total > maxInputChars {
+		return &AppError{Code: "input_too_large", Message: "input exceeds 32000 characters", HTTPStatus: http.StatusBadRequest}
+	}
+	return validateTools(req.Tools, req.ToolChoice)
+}
+
+// conversation returns the request as a list of turns; a legacy Input becomes
+// a single user turn.
+func conversation(req contracts.CompletionRequest) []contracts.Message {
+	if len(req.Messages) > 0 {
+		return req.Messages
+	}
+	return []contracts.Message{{Role: RoleUser, Content: req.Input}}
+}
+
+// approxInputTokens estimates prompt tokens. Structured turns carry framing
+// overhead per message; a legacy Input is counted as plain text. Tool
+// definitions and past tool calls are sent to the model and count as input.
+func approxInputTokens(req contracts.CompletionRequest) int {
+	n := toolTokens(req.Tools)
+	if len(req.Messages) == 0 {
+		return n + billing.ApproxTokens(req.Input)
+	}
+	for _, m := range req.Messages {
+		n += billing.ApproxMessageTokens(m.Content)
+		for _, call := range m.ToolCalls {
+			n += billing.ApproxTokens(call.Name + " " + call.Arguments)
+		}
+	}
+	return n
+}
+
+func policyMessages(msgs []contracts.Message) []policy.Message {
+	out := make([]policy.Message, 0, len(msgs))
+	for _, m := range msgs {
+		out = append(out, policy.Message{Role: m.Role, Content: m.Content})
+	}
+	return out
+}
+
+// redactRequest scrubs the request for audit. RedactedInput keeps a single
+// readable blob (role-labelled for multi-turn requests) and, for structured
+// requests, each turn is also redacted on its own.
+func redactRequest(req contracts.CompletionRequest) (string, []audit.Message) {
+	if len(req.Messages) == 0 {
+		return redaction.Scrub(req.Input).Text, nil
+	}
+	turns := make([]audit.Message, 0, len(req.Messages))
+	lines := make([]string, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		text := redaction.Scrub(m.Content).Text
+		turns = append(turns, audit.Message{Role: m.Role, Content: text})
+		lines = append(lines, m.Role+": "+text)
+	}
+	return strings.Join(lines, "\n"), turns
+}
+
+// lastUserContent returns the most recent user turn, or "" if there is none.
+func lastUserContent(msgs []contracts.Message) string {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role == RoleUser {
+			return msgs[i].Content
+		}
+	}
+	return ""
+}
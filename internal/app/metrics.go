@@ -8,6 +8,29 @@ type Metrics struct {
 	LatencySec    *prometheus.HistogramVec
 	TokensTotal   *prometheus.CounterVec
 	CostTotalUSD  *prometheus.CounterVec
+	// UpstreamAttempts counts individual provider calls, including retries.
+	UpstreamAttempts *prometheus.CounterVec
+	// ProviderCircuitState is 0 (closed), 1 (half-open) or 2 (open) per provider.
+	ProviderCircuitState *prometheus.GaugeVec
+	// ProviderHealthy is 1 while a provider passes its health probes, else 0.
+	ProviderHealthy *prometheus.GaugeVec
+	// DeploymentRequests counts calls per balanced deployment.
+	DeploymentRequests *prometheus.CounterVec
+	// DeploymentEjections counts balanced deployments taken out of rotation.
+	DeploymentEjections *prometheus.CounterVec
+	// HedgedRequests counts hedged calls by which call won.
+	HedgedRequests *prometheus.CounterVec
+	// StructuredOutputChecks counts response_format validations, re-asks included.
+	StructuredOutputChecks *prometheus.CounterVec
+	// AliasRequests counts completions requested through a model alias.
+	AliasRequests *prometheus.CounterVec
+	// ShadowRequests counts completions mirrored to shadow models.
+	ShadowRequests *prometheus.CounterVec
+	// ExperimentRequests, ExperimentLatencySec and ExperimentCostUSD compare
+	// the variants of A/B experiments.
+	ExperimentRequests   *prometheus.CounterVec
+	ExperimentLatencySec *prometheus.HistogramVec
+	ExperimentCostUSD    *prometheus.CounterVec
 }
 
 func NewMetrics(reg prometheus.Registerer) *Metrics {
@@ -17,7 +40,7 @@ func NewMetrics(reg prometheus.Registerer) *Metrics {
 				Name: "gateway_requests_total",
 				Help: "Total requests processed by the gateway.",
 			},
-			[]string{"team", "model", "status"},
+			[]string{"team", "model", "operation", "status"},
 		),
 		LatencySec: prometheus.NewHistogramVec(
 			prometheus.HistogramOpts{
@@ -25,7 +48,7 @@ func NewMetrics(reg prometheus.Registerer) *Metrics {
 				Help:    "Latency distribution for gateway requests.",
 				Buckets: prometheus.DefBuckets,
 			},
-			[]string{"team", "model", "status"},
+			[]string{"team", "model", "operation", "status"},
 		),
 		TokensTotal: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
@@ -41,6 +64,91 @@ func NewMetrics(reg prometheus.Registerer) *Metrics {
 			},
 			[]string{"team", "model"},
 		),
+		UpstreamAttempts: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_upstream_attempts_total",
+				Help: "Upstream provider calls grouped by model/outcome, including retries.",
+			},
+			[]string{"model", "outcome"},
+		),
+		ProviderCircuitState: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "gateway_provider_circuit_state",
+				Help: "Provider circuit breaker state (0=closed, 1=half-open, 2=open).",
+			},
+			[]string{"provider"},
+		),
+		ProviderHealthy: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "gateway_provider_healthy",
+				Help: "Provider health from active probes (1=healthy, 0=unhealthy).",
+			},
+			[]string{"provider"},
+		),
+		DeploymentRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_deployment_requests_total",
+				Help: "Calls to balanced deployments grouped by model/deployment/outcome.",
+			},
+			[]string{"model", "deployment", "outcome"},
+		),
+		DeploymentEjections: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_deployment_ejections_total",
+				Help: "Balanced deployments ejected after consecutive retryable failures.",
+			},
+			[]string{"model", "deployment"},
+		),
+		HedgedRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_hedged_requests_total",
+				Help: "Hedged completion calls grouped by model/outcome (primary_won, hedge_won, failed).",
+			},
+			[]string{"model", "outcome"},
+		),
+		StructuredOutputChecks: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_structured_output_validations_total",
+				Help: "Structured output validations grouped by model/outcome (valid, invalid).",
+			},
+			[]string{"model", "outcome"},
+		),
+		AliasRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_alias_requests_total",
+				Help: "Completions requested through a model alias grouped by team/alias/serving model/status.",
+			},
+			[]string{"team", "alias", "model", "status"},
+		),
+		ShadowRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_shadow_requests_total",
+				Help: "Completions mirrored to shadow models grouped by team/model/outcome (ok, failed, dropped).",
+			},
+			[]string{"team", "model", "outcome"},
+		),
+		ExperimentRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_experiment_requests_total",
+				Help: "Completions enrolled in A/B experiments grouped by experiment/variant/status.",
+			},
+			[]string{"experiment", "variant", "status"},
+		),
+		ExperimentLatencySec: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "gateway_experiment_latency_seconds",
+				Help:    "Latency distribution of A/B experiment completions.",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"experiment", "variant", "status"},
+		),
+		ExperimentCostUSD: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_experiment_cost_usd_total",
+				Help: "Estimated cost (USD) of A/B experiment completions.",
+			},
+			[]string{"experiment", "variant"},
+		),
 	}
 
 	reg.MustRegister(
@@ -48,6 +156,18 @@ func NewMetrics(reg prometheus.Registerer) *Metrics {
 		m.LatencySec,
 		m.TokensTotal,
 		m.CostTotalUSD,
+		m.UpstreamAttempts,
+		m.ProviderCircuitState,
+		m.ProviderHealthy,
+		m.DeploymentRequests,
+		m.DeploymentEjections,
+		m.HedgedRequests,
+		m.StructuredOutputChecks,
+		m.AliasRequests,
+		m.ShadowRequests,
+		m.ExperimentRequests,
+		m.ExperimentLatencySec,
+		m.ExperimentCostUSD,
 	)
 	return m
 }
@@ -2,22 +2,191 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
 )
 
+// ModelRequest is the provider-agnostic payload sent to a ModelClient.
+// Messages always holds at least one user turn. Tools, ToolChoice and
+// ResponseFormat are passed through from the caller and already validated;
+// providers use ResponseFormat as a hint, the gateway validates the output.
+// MaxTokens (zero when unset), Temperature, TopP and Stop are generation
+// parameters within the team's limits.
+type ModelRequest struct {
+	Model          string
+	Messages       []contracts.Message
+	Tools          []contracts.Tool
+	ToolChoice     string
+	ResponseFormat *contracts.ResponseFormat
+	MaxTokens      int
+	Temperature    *float64
+	TopP           *float64
+	Stop           []string
+}
+
+// ModelResponse is a finished generation. Token counts are provider-reported;
+// zero means the provider did not report usage and the caller should estimate.
+// ToolCalls is set when the model asked to invoke tools instead of (or in
+// addition to) producing text.
+type ModelResponse struct {
+	Output       string
+	ToolCalls    []contracts.ToolCall
+	InputTokens  int
+	OutputTokens int
+}
+
 // ModelClient abstracts LLM provider integrations.
 type ModelClient interface {
-	Complete(ctx context.Context, model, input string) (string, error)
+	Complete(ctx context.Context, req ModelRequest) (ModelResponse, error)
+}
+
+// StreamingModelClient is implemented by clients that can emit output
+// incrementally. onDelta is called for every chunk in order; an error from it
+// aborts the stream and is returned as-is. The returned ModelResponse carries
+// the full output and usage, as with Complete.
+type StreamingModelClient interface {
+	ModelClient
+	Stream(ctx context.Context, req ModelRequest, onDelta func(delta string) error) (ModelResponse, error)
+}
+
+// streamCompletion streams from client when it supports it, and otherwise
+// completes normally and delivers the whole output as a single delta.
+func streamCompletion(ctx context.Context, client ModelClient, req ModelRequest, onDelta func(string) error) (ModelResponse, error) {
+	if streamer, ok := client.(StreamingModelClient); ok {
+		return streamer.Stream(ctx, req, onDelta)
+	}
+	resp, err := client.Complete(ctx, req)
+	if err != nil {
+		return ModelResponse{}, err
+	}
+	if resp.Output != "" {
+		if err := onDelta(resp.Output); err != nil {
+			return ModelResponse{}, err
+		}
+	}
+	return resp, nil
+}
+
+const defaultLlamaCppBaseURL = "http://localhost:8080/v1"
+
+// localProviderTypes lists provider types that run models on the operator's
+// own hardware; their models are free unless priced explicitly.
+var localProviderTypes = map[string]bool{"ollama": true, "llamacpp": true}
+
+// NewModelClient builds the client for a configured provider backend,
+// recording its calls when it has a cassette. logger may be nil.
+func NewModelClient(p config.ProviderConfig, logger *slog.Logger) (ModelClient, error) {
+	if p.Type == "replay" {
+		if p.Cassette == "" {
+			return nil, errors.New("replay provider has no cassette")
+		}
+		client, err := NewReplayClient(p.Cassette, p.ReplayLatency)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	}
+	client, err := newProviderClient(p)
+	if err != nil || p.Cassette == "" {
+		return client, err
+	}
+	recorder, err := NewRecordingClient(client, p.Cassette, logger)
+	if err != nil {
+		return nil, err
+	}
+	return recorder, nil
+}
+
+func newProviderClient(p config.ProviderConfig) (ModelClient, error) {
+	switch p.Type {
+	case "", "simulated":
+		if p.Scenario == nil {
+			return SimulatedModelClient{}, nil
+		}
+		client, err := NewScenarioClient(p)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	case "openai":
+		return NewOpenAIClient(p), nil
+	case "anthropic":
+		return NewAnthropicClient(p), nil
+	case "ollama":
+		return NewOllamaClient(p), nil
+	case "llamacpp":
+		// llama.cpp's server speaks the OpenAI wire format.
+		if p.BaseURL == "" {
+			p.BaseURL = defaultLlamaCppBaseURL
+		}
+		return NewOpenAIClient(p), nil
+	default:
+		return nil, fmt.Errorf("unknown provider type %q", p.Type)
+	}
 }
 
 // SimulatedModelClient provides deterministic local responses for demos and tests.
 type SimulatedModelClient struct{}
 
-func (SimulatedModelClient) Complete(_ context.Context, model, input string) (string, error) {
-	normalized := strings.TrimSpace(input)
+// Complete echoes the last user turn. When tools are offered and the caller
+// is not already returning tool results, it calls the chosen tool (or the
+// first one) with that turn as {"input": ...} instead; with a response format
+// it returns a minimal conforming JSON document.
+func (SimulatedModelClient) Complete(_ context.Context, req ModelRequest) (ModelResponse, error) {
+	if call, ok := simulatedToolCall(req); ok {
+		return ModelResponse{ToolCalls: []contracts.ToolCall{call}}, nil
+	}
+	if output, ok := simulatedStructuredOutput(req); ok {
+		return ModelResponse{Output: output}, nil
+	}
+	normalized := strings.TrimSpace(lastUserContent(req.Messages))
 	if len(normalized) > 180 {
 		normalized = normalized[:180] + "..."
 	}
-	return fmt.Sprintf("[%s] triage summary: request accepted; key risks extracted from input: %s", model, normalized), nil
+	return ModelResponse{
+		Output: limitOutput(fmt.Sprintf("[%s] triage summary: request accepted; key risks extracted from input: %s", req.Model, normalized), req),
+	}, nil
+}
+
+// limitOutput applies req's stop sequences and max_tokens to simulated
+// output, cutting at the first stop sequence and then at about four
+// characters per token.
+func limitOutput(output string, req ModelRequest) string {
+	for _, stop := range req.Stop {
+		if i := strings.Index(output, stop); i >= 0 {
+			output = output[:i]
+		}
+	}
+	if req.MaxTokens > 0 {
+		if runes := []rune(output); len(runes) > 4*req.MaxTokens {
+			output = string(runes[:4*req.MaxTokens])
+		}
+	}
+	return output
+}
+
+// Probe always succeeds; the simulated backend has nothing to check.
+func (SimulatedModelClient) Probe(context.Context) error { return nil }
+
+// Stream emits the simulated output word by word.
+func (c SimulatedModelClient) Stream(ctx context.Context, req ModelRequest, onDelta func(string) error) (ModelResponse, error) {
+	resp, err := c.Complete(ctx, req)
+	if err != nil {
+		return ModelResponse{}, err
+	}
+	words := strings.SplitAfter(resp.Output, " ")
+	for _, w := range words {
+		if err := ctx.Err(); err != nil {
+			return ModelResponse{}, upstreamTransportError("simulated", err)
+		}
+		if err := onDelta(w); err != nil {
+			return ModelResponse{}, err
+		}
+	}
+	return resp, nil
 }
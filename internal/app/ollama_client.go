@@ -0,0 +1,440 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+// Ollama endpoints selectable with ProviderConfig.API.
+const (
+	OllamaAPIChat     = "chat"
+	OllamaAPIGenerate = "generate"
+)
+
+const defaultOllamaBaseURL = "http://localhost:11434"
+
+// OllamaClient talks to an Ollama server through its native /api/chat or
+// /api/generate endpoint. Ollama reports no tool call IDs, so the client
+// numbers the calls of each response itself.
+type OllamaClient struct {
+	baseURL      string
+	api          string
+	systemPrompt string
+	timeout      time.Duration
+	httpClient   *http.Client
+}
+
+func NewOllamaClient(p config.ProviderConfig) *OllamaClient {
+	baseURL := strings.TrimRight(p.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = defaultOllamaBaseURL
+	}
+	api := p.API
+	if api == "" {
+		api = OllamaAPIChat
+	}
+	timeout := providerTimeout(p)
+	return &OllamaClient{
+		baseURL:      baseURL,
+		api:          api,
+		systemPrompt: p.SystemPrompt,
+		timeout:      timeout,
+		httpClient:   newProviderHTTPClient(timeout),
+	}
+}
+
+type ollamaFunctionCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type ollamaToolCall struct {
+	Function ollamaFunctionCall `json:"function"`
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []openAITool    `json:"tools,omitempty"`
+	Format   any             `json:"format,omitempty"`
+	Options  *ollamaOptions  `json:"options,omitempty"`
+	Stream   bool            `json:"stream"`
+}
+
+type ollamaGenerateRequest struct {
+	Model   string         `json:"model"`
+	Prompt  string         `json:"prompt"`
+	System  string         `json:"system,omitempty"`
+	Format  any            `json:"format,omitempty"`
+	Options *ollamaOptions `json:"options,omitempty"`
+	Stream  bool           `json:"stream"`
+}
+
+// ollamaOptions carries generation parameters, which Ollama takes as model
+// options rather than top-level fields.
+type ollamaOptions struct {
+	NumPredict  int      `json:"num_predict,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+}
+
+// ollamaResponse is a whole /api/chat or /api/generate response, or one line
+// of a streamed one; usage arrives with the line marked done.
+type ollamaResponse struct {
+	Message         ollamaMessage `json:"message"`
+	Response        string        `json:"response"`
+	Done            bool          `json:"done"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+type ollamaEmbedRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+type ollamaEmbedResponse struct {
+	Embeddings      [][]float32 `json:"embeddings"`
+	PromptEvalCount int         `json:"prompt_eval_count"`
+}
+
+type ollamaTagsResponse struct {
+	Models []struct {
+		Name string `json:"name"`
+	} `json:"models"`
+}
+
+type ollamaErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func (c *OllamaClient) Complete(ctx context.Context, req ModelRequest) (ModelResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	path, payload, err := c.request(req, false)
+	if err != nil {
+		return ModelResponse{}, err
+	}
+	resp, err := c.post(ctx, path, payload)
+	if err != nil {
+		return ModelResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxUpstreamBodyBytes))
+	if err != nil {
+		return ModelResponse{}, upstreamTransportError("ollama", err)
+	}
+	var out ollamaResponse
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return ModelResponse{}, upstreamDecodeError("ollama", err)
+	}
+	return ModelResponse{
+		Output:       out.Message.Content + out.Response,
+		ToolCalls:    fromOllamaToolCalls(out.Message.ToolCalls, 0),
+		InputTokens:  out.PromptEvalCount,
+		OutputTokens: out.EvalCount,
+	}, nil
+}
+
+// Stream reads Ollama's newline-delimited JSON stream.
+func (c *OllamaClient) Stream(ctx context.Context, req ModelRequest, onDelta func(string) error) (ModelResponse, error) {
+	path, payload, err := c.request(req, true)
+	if err != nil {
+		return ModelResponse{}, err
+	}
+	resp, err := c.post(ctx, path, payload)
+	if err != nil {
+		return ModelResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	var out ModelResponse
+	var text strings.Builder
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 64*1024), maxUpstreamBodyBytes)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var chunk ollamaResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return ModelResponse{}, upstreamDecodeError("ollama", err)
+		}
+		if chunk.Error != "" {
+			return ModelResponse{}, upstreamStatusError("ollama", http.StatusBadGateway, chunk.Error)
+		}
+		out.ToolCalls = append(out.ToolCalls, fromOllamaToolCalls(chunk.Message.ToolCalls, len(out.ToolCalls))...)
+		if delta := chunk.Message.Content + chunk.Response; delta != "" {
+			text.WriteString(delta)
+			if err := onDelta(delta); err != nil {
+				return ModelResponse{}, err
+			}
+		}
+		if chunk.Done {
+			out.InputTokens = chunk.PromptEvalCount
+			out.OutputTokens = chunk.EvalCount
+			break
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return ModelResponse{}, streamError("ollama", err)
+	}
+	out.Output = text.String()
+	return out, nil
+}
+
+func (c *OllamaClient) Embed(ctx context.Context, req EmbeddingRequest) (EmbeddingResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.post(ctx, "/api/embed", ollamaEmbedRequest{
+		Model:      req.Model,
+		Input:      req.Input,
+		Dimensions: req.Dimensions,
+	})
+	if err != nil {
+		return EmbeddingResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxUpstreamBodyBytes))
+	if err != nil {
+		return EmbeddingResponse{}, upstreamTransportError("ollama", err)
+	}
+	var out ollamaEmbedResponse
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return EmbeddingResponse{}, upstreamDecodeError("ollama", err)
+	}
+	if len(out.Embeddings) != len(req.Input) {
+		return EmbeddingResponse{}, upstreamDecodeError("ollama", fmt.Errorf("got %d embeddings for %d inputs", len(out.Embeddings), len(req.Input)))
+	}
+	return EmbeddingResponse{Vectors: out.Embeddings, InputTokens: out.PromptEvalCount}, nil
+}
+
+// Probe lists the local models as a cheap health check.
+func (c *OllamaClient) Probe(ctx context.Context) error {
+	_, err := c.ListModels(ctx)
+	return err
+}
+
+// ListModels returns the models pulled on the server. Models tagged
+// ":latest" are listed under their bare name too, which is how clients
+// usually refer to them.
+func (c *OllamaClient) ListModels(ctx context.Context) ([]string, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxUpstreamBodyBytes))
+	if err != nil {
+		return nil, upstreamTransportError("ollama", err)
+	}
+	var out ollamaTagsResponse
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, upstreamDecodeError("ollama", err)
+	}
+	var models []string
+	for _, m := range out.Models {
+		models = append(models, m.Name)
+		if bare, ok := strings.CutSuffix(m.Name, ":latest"); ok {
+			models = append(models, bare)
+		}
+	}
+	return models, nil
+}
+
+// request builds the payload for the configured endpoint. The generate
+// endpoint takes a single prompt, so earlier turns are flattened into it and
+// tools are rejected.
+func (c *OllamaClient) request(req ModelRequest, stream bool) (string, any, error) {
+	if c.api == OllamaAPIGenerate {
+		if len(req.Tools) > 0 && req.ToolChoice != ToolChoiceNone {
+			return "", nil, upstreamStatusError("ollama", http.StatusBadRequest, "tools require the chat API")
+		}
+		system, prompt := ollamaPrompt(c.systemPrompt, req.Messages)
+		return "/api/generate", ollamaGenerateRequest{
+			Model:   req.Model,
+			Prompt:  prompt,
+			System:  system,
+			Format:  ollamaFormat(req.ResponseFormat),
+			Options: ollamaGenerationOptions(req),
+			Stream:  stream,
+		}, nil
+	}
+
+	var messages []ollamaMessage
+	if c.systemPrompt != "" {
+		messages = append(messages, ollamaMessage{Role: RoleSystem, Content: c.systemPrompt})
+	}
+	toolNames := make(map[string]string)
+	for _, m := range req.Messages {
+		msg := ollamaMessage{Role: m.Role, Content: m.Content}
+		for _, call := range m.ToolCalls {
+			toolNames[call.ID] = call.Name
+			args := json.RawMessage(call.Arguments)
+			if !json.Valid(args) {
+				args = json.RawMessage("{}")
+			}
+			msg.ToolCalls = append(msg.ToolCalls, ollamaToolCall{Function: ollamaFunctionCall{Name: call.Name, Arguments: args}})
+		}
+		if m.Role == RoleTool {
+			msg.ToolName = toolNames[m.ToolCallID]
+		}
+		messages = append(messages, msg)
+	}
+	return "/api/chat", ollamaChatRequest{
+		Model:    req.Model,
+		Messages: messages,
+		Tools:    ollamaTools(req.Tools, req.ToolChoice),
+		Format:   ollamaFormat(req.ResponseFormat),
+		Options:  ollamaGenerationOptions(req),
+		Stream:   stream,
+	}, nil
+}
+
+// ollamaGenerationOptions returns nil when req sets no generation parameters,
+// leaving the model's own defaults in place.
+func ollamaGenerationOptions(req ModelRequest) *ollamaOptions {
+	if req.MaxTokens == 0 && req.Temperature == nil && req.TopP == nil && len(req.Stop) == 0 {
+		return nil
+	}
+	return &ollamaOptions{NumPredict: req.MaxTokens, Temperature: req.Temperature, TopP: req.TopP, Stop: req.Stop}
+}
+
+// ollamaTools narrows the offered tools to honor a tool choice, which Ollama
+// has no parameter for; "required" cannot be enforced and offers them all.
+func ollamaTools(tools []contracts.Tool, choice string) []openAITool {
+	switch choice {
+	case "", ToolChoiceAuto, ToolChoiceRequired:
+		return openAITools(tools)
+	case ToolChoiceNone:
+		return nil
+	}
+	for _, t := range tools {
+		if t.Name == choice {
+			return openAITools([]contracts.Tool{t})
+		}
+	}
+	return nil
+}
+
+// ollamaFormat maps a response format onto Ollama's format parameter, which
+// takes "json" or a JSON Schema.
+func ollamaFormat(rf *contracts.ResponseFormat) any {
+	if rf == nil {
+		return nil
+	}
+	switch rf.Type {
+	case FormatJSONObject:
+		return "json"
+	case FormatJSONSchema:
+		if len(rf.Schema) == 0 {
+			return "json"
+		}
+		return rf.Schema
+	}
+	return nil
+}
+
+// ollamaPrompt joins system turns into the system prompt and renders the
+// rest as a transcript ending with the assistant's cue. A lone user turn is
+// sent as is.
+func ollamaPrompt(systemPrompt string, msgs []contracts.Message) (string, string) {
+	var system []string
+	if systemPrompt != "" {
+		system = append(system, systemPrompt)
+	}
+	var turns []contracts.Message
+	for _, m := range msgs {
+		if m.Role == RoleSystem {
+			system = append(system, m.Content)
+			continue
+		}
+		turns = append(turns, m)
+	}
+	if len(turns) == 1 && turns[0].Role == RoleUser {
+		return strings.Join(system, "\n\n"), turns[0].Content
+	}
+	var prompt strings.Builder
+	for _, m := range turns {
+		prompt.WriteString(strings.ToUpper(m.Role[:1]) + m.Role[1:] + ": " + m.Content + "\n\n")
+	}
+	prompt.WriteString("Assistant:")
+	return strings.Join(system, "\n\n"), prompt.String()
+}
+
+// fromOllamaToolCalls converts calls and gives them IDs numbered from offset.
+func fromOllamaToolCalls(calls []ollamaToolCall, offset int) []contracts.ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]contracts.ToolCall, 0, len(calls))
+	for i, call := range calls {
+		args := string(call.Function.Arguments)
+		if args == "" || args == "null" {
+			args = "{}"
+		}
+		out = append(out, contracts.ToolCall{ID: fmt.Sprintf("call_%d", offset+i), Name: call.Function.Name, Arguments: args})
+	}
+	return out
+}
+
+// post sends a JSON request to path; do maps non-2xx responses to AppErrors.
+// The caller owns the returned body.
+func (c *OllamaClient) post(ctx context.Context, path string, payload any) (*http.Response, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(ctx, http.MethodPost, path, bytes.NewReader(body))
+}
+
+func (c *OllamaClient) do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, upstreamTransportError("ollama", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxUpstreamBodyBytes))
+		var envelope ollamaErrorResponse
+		_ = json.Unmarshal(raw, &envelope)
+		upstreamErr := upstreamStatusError("ollama", resp.StatusCode, envelope.Error)
+		upstreamErr.RetryAfter = parseRetryAfter(resp.Header)
+		return nil, upstreamErr
+	}
+	return resp, nil
+}
@@ -0,0 +1,209 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+// fakeOllama serves /api/tags with models and records the last request body.
+func fakeOllama(t *testing.T, models []string, handle func(w http.ResponseWriter, body map[string]any)) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/tags" {
+			var tags ollamaTagsResponse
+			for _, m := range models {
+				tags.Models = append(tags.Models, struct {
+					Name string `json:"name"`
+				}{m})
+			}
+			_ = json.NewEncoder(w).Encode(tags)
+			return
+		}
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		body["path"] = r.URL.Path
+		handle(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOllamaClientChatWithToolsAndFormat(t *testing.T) {
+	var got map[string]any
+	srv := fakeOllama(t, nil, func(w http.ResponseWriter, body map[string]any) {
+		got = body
+		_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"lookup_ip","arguments":{"ip":"10.0.0.1"}}}]},"done":true,"prompt_eval_count":21,"eval_count":7}`))
+	})
+
+	client := NewOllamaClient(config.ProviderConfig{BaseURL: srv.URL, SystemPrompt: "be brief"})
+	resp, err := client.Complete(context.Background(), ModelRequest{
+		Model: "llama3",
+		Messages: []contracts.Message{
+			{Role: RoleUser, Content: "check 10.0.0.1"},
+			{Role: RoleAssistant, ToolCalls: []contracts.ToolCall{{ID: "call_0", Name: "lookup_ip", Arguments: `{"ip":"10.0.0.1"}`}}},
+			{Role: RoleTool, ToolCallID: "call_0", Content: "clean"},
+		},
+		Tools:          []contracts.Tool{{Name: "lookup_ip"}, {Name: "block_ip"}},
+		ToolChoice:     "lookup_ip",
+		ResponseFormat: &contracts.ResponseFormat{Type: FormatJSONObject},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["path"] != "/api/chat" || got["format"] != "json" || got["stream"] != false {
+		t.Fatalf("unexpected request: %+v", got)
+	}
+	msgs := got["messages"].([]any)
+	if len(msgs) != 4 || msgs[0].(map[string]any)["content"] != "be brief" || msgs[3].(map[string]any)["tool_name"] != "lookup_ip" {
+		t.Fatalf("unexpected messages: %+v", msgs)
+	}
+	if tools := got["tools"].([]any); len(tools) != 1 {
+		t.Fatalf("expected the tool choice to narrow the tools, got %+v", tools)
+	}
+	want := contracts.ToolCall{ID: "call_0", Name: "lookup_ip", Arguments: `{"ip":"10.0.0.1"}`}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0] != want || resp.InputTokens != 21 || resp.OutputTokens != 7 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestOllamaClientStreamsNDJSON(t *testing.T) {
+	srv := fakeOllama(t, nil, func(w http.ResponseWriter, _ map[string]any) {
+		_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"hel"},"done":false}
+{"message":{"role":"assistant","content":"lo"},"done":false}
+{"message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":5,"eval_count":2}
+`))
+	})
+
+	var deltas []string
+	resp, err := NewOllamaClient(config.ProviderConfig{BaseURL: srv.URL}).Stream(context.Background(), ModelRequest{Model: "llama3", Messages: userTurn("hi")}, func(d string) error {
+		deltas = append(deltas, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(deltas, "|") != "hel|lo" || resp.Output != "hello" || resp.InputTokens != 5 || resp.OutputTokens != 2 {
+		t.Fatalf("unexpected stream result %q: %+v", deltas, resp)
+	}
+}
+
+func TestOllamaClientGenerateFlattensConversation(t *testing.T) {
+	var got map[string]any
+	srv := fakeOllama(t, nil, func(w http.ResponseWriter, body map[string]any) {
+		got = body
+		_, _ = w.Write([]byte(`{"response":"3 accounts","done":true,"prompt_eval_count":30,"eval_count":3}`))
+	})
+
+	client := NewOllamaClient(config.ProviderConfig{BaseURL: srv.URL, API: OllamaAPIGenerate})
+	resp, err := client.Complete(context.Background(), ModelRequest{Model: "llama3", Messages: []contracts.Message{
+		{Role: RoleSystem, Content: "You are a SOC analyst."},
+		{Role: RoleUser, Content: "Summarize."},
+		{Role: RoleAssistant, Content: "42 failed logins."},
+		{Role: RoleUser, Content: "How many accounts?"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPrompt := "User: Summarize.\n\nAssistant: 42 failed logins.\n\nUser: How many accounts?\n\nAssistant:"
+	if got["path"] != "/api/generate" || got["system"] != "You are a SOC analyst." || got["prompt"] != wantPrompt {
+		t.Fatalf("unexpected request: %+v", got)
+	}
+	if resp.Output != "3 accounts" || resp.InputTokens != 30 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	_, err = client.Complete(context.Background(), ModelRequest{Model: "llama3", Messages: userTurn("hi"), Tools: []contracts.Tool{{Name: "lookup_ip"}}})
+	if code := asUpstreamError(err).Code; code != CodeUpstreamBadRequest {
+		t.Fatalf("expected tools to be rejected with %s, got %v", CodeUpstreamBadRequest, err)
+	}
+}
+
+func TestOllamaClientEmbedAndErrors(t *testing.T) {
+	srv := fakeOllama(t, nil, func(w http.ResponseWriter, body map[string]any) {
+		if body["model"] == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"model \"missing\" not found, try pulling it first"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"embeddings":[[0.1,0.2],[0.3,0.4]],"prompt_eval_count":6}`))
+	})
+	client := NewOllamaClient(config.ProviderConfig{BaseURL: srv.URL})
+
+	resp, err := client.Embed(context.Background(), EmbeddingRequest{Model: "nomic-embed-text", Input: []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Vectors) != 2 || resp.Vectors[1][0] != 0.3 || resp.InputTokens != 6 {
+		t.Fatalf("unexpected embeddings: %+v", resp)
+	}
+
+	_, err = client.Complete(context.Background(), ModelRequest{Model: "missing", Messages: userTurn("hi")})
+	if appErr := asUpstreamError(err); appErr.Code != CodeUpstreamBadRequest || !strings.Contains(appErr.Message, "try pulling it first") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestDiscoverModelsRoutesListedModelsForFree(t *testing.T) {
+	srv := fakeOllama(t, []string{"llama3:latest", "gpt-4o-mini", "qwen2.5:7b"}, func(w http.ResponseWriter, body map[string]any) {
+		_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"ok"},"done":true,"prompt_eval_count":12,"eval_count":1}`))
+	})
+
+	cfg := config.Default()
+	cfg.Providers = append(cfg.Providers, config.ProviderConfig{Name: "ollama", Type: "ollama", BaseURL: srv.URL, DiscoverModels: true})
+	cfg, err := DiscoverModels(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, model := range []string{"llama3", "llama3:latest", "qwen2.5:7b"} {
+		if cfg.ModelRoutes[model] != "ollama" {
+			t.Fatalf("expected %s to be routed to ollama, got %v", model, cfg.ModelRoutes)
+		}
+	}
+	if _, ok := cfg.ModelRoutes["gpt-4o-mini"]; ok {
+		t.Fatal("expected a model matched by an existing route to keep it")
+	}
+
+	router, err := NewRouter(cfg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Teams[0].AllowedModels = append(cfg.Teams[0].AllowedModels, "llama3")
+	svc, principal := newTestService(t, cfg, router)
+	resp, appErr := svc.HandleCompletion(context.Background(), "req-1", principal, contracts.CompletionRequest{Model: "llama3", Input: "hi"})
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	if resp.Output != "ok" || resp.CostUSD != 0 || svc.billing.UnitPrice("gpt-4o-mini") == 0 {
+		t.Fatalf("expected a free local completion, got %+v", resp)
+	}
+}
+
+func TestLocalModelPatterns(t *testing.T) {
+	cfg := config.Config{
+		Providers: []config.ProviderConfig{
+			{Name: "ollama", Type: "ollama"},
+			{Name: "llamacpp", Type: "llamacpp"},
+			{Name: "openai", Type: "openai"},
+		},
+		ModelRoutes: map[string]string{"llama*": "ollama", "gpt-*": "openai"},
+		ModelDeployments: map[string]config.BalancerConfig{
+			"mistral": {Deployments: []config.DeploymentConfig{{Provider: "ollama"}, {Provider: "llamacpp"}}},
+			"mixed":   {Deployments: []config.DeploymentConfig{{Provider: "ollama"}, {Provider: "openai"}}},
+		},
+	}
+	got := localModelPatterns(cfg)
+	slices.Sort(got)
+	if !slices.Equal(got, []string{"llama*", "mistral"}) {
+		t.Fatalf("unexpected local patterns: %v", got)
+	}
+}
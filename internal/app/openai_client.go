@@ -0,0 +1,432 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+// OpenAIClient talks to any upstream speaking the OpenAI chat-completions wire format.
+type OpenAIClient struct {
+	baseURL    string
+	apiKey     string
+	timeout    time.Duration
+	httpClient *http.Client
+}
+
+func NewOpenAIClient(p config.ProviderConfig) *OpenAIClient {
+	baseURL := strings.TrimRight(p.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	timeout := providerTimeout(p)
+	return &OpenAIClient{
+		baseURL:    baseURL,
+		apiKey:     p.APIKey,
+		timeout:    timeout,
+		httpClient: newProviderHTTPClient(timeout),
+	}
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+type openAIToolCall struct {
+	Index    int                `json:"index,omitempty"`
+	ID       string             `json:"id,omitempty"`
+	Type     string             `json:"type,omitempty"`
+	Function openAIFunctionCall `json:"function"`
+}
+
+type openAIFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIChatRequest struct {
+	Model          string               `json:"model"`
+	Messages       []openAIMessage      `json:"messages"`
+	Tools          []openAITool         `json:"tools,omitempty"`
+	ToolChoice     any                  `json:"tool_choice,omitempty"`
+	ResponseFormat any                  `json:"response_format,omitempty"`
+	MaxTokens      int                  `json:"max_tokens,omitempty"`
+	Temperature    *float64             `json:"temperature,omitempty"`
+	TopP           *float64             `json:"top_p,omitempty"`
+	Stop           []string             `json:"stop,omitempty"`
+	Stream         bool                 `json:"stream,omitempty"`
+	StreamOptions  *openAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+	Usage openAIUsage `json:"usage"`
+}
+
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string           `json:"content"`
+			ToolCalls []openAIToolCall `json:"tool_calls"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type openAIEmbeddingRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage openAIUsage `json:"usage"`
+}
+
+type openAIModelList struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+type openAIErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+func (c *OpenAIClient) Complete(ctx context.Context, req ModelRequest) (ModelResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.post(ctx, "/chat/completions", c.request(req, false))
+	if err != nil {
+		return ModelResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxUpstreamBodyBytes))
+	if err != nil {
+		return ModelResponse{}, upstreamTransportError("openai", err)
+	}
+	var out openAIChatResponse
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return ModelResponse{}, upstreamDecodeError("openai", err)
+	}
+	if len(out.Choices) == 0 {
+		return ModelResponse{}, upstreamDecodeError("openai", errNoChoices)
+	}
+	return ModelResponse{
+		Output:       out.Choices[0].Message.Content,
+		ToolCalls:    fromOpenAIToolCalls(out.Choices[0].Message.ToolCalls),
+		InputTokens:  out.Usage.PromptTokens,
+		OutputTokens: out.Usage.CompletionTokens,
+	}, nil
+}
+
+func (c *OpenAIClient) Stream(ctx context.Context, req ModelRequest, onDelta func(string) error) (ModelResponse, error) {
+	resp, err := c.post(ctx, "/chat/completions", c.request(req, true))
+	if err != nil {
+		return ModelResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	var out ModelResponse
+	var text strings.Builder
+	// Tool call fragments arrive keyed by index: the first carries id and
+	// name, later ones append to the arguments.
+	var calls []openAIToolCall
+	err = readSSE(resp.Body, func(_, data string) error {
+		if data == "[DONE]" {
+			return errStreamDone
+		}
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return upstreamDecodeError("openai", err)
+		}
+		if chunk.Error != nil {
+			return upstreamStatusError("openai", http.StatusBadGateway, chunk.Error.Message)
+		}
+		if chunk.Usage != nil {
+			out.InputTokens = chunk.Usage.PromptTokens
+			out.OutputTokens = chunk.Usage.CompletionTokens
+		}
+		for _, choice := range chunk.Choices {
+			for _, frag := range choice.Delta.ToolCalls {
+				if frag.Index < 0 || frag.Index >= maxStreamToolCalls {
+					return upstreamDecodeError("openai", fmt.Errorf("tool call index %d out of range", frag.Index))
+				}
+				for len(calls) <= frag.Index {
+					calls = append(calls, openAIToolCall{})
+				}
+				if frag.ID != "" {
+					calls[frag.Index].ID = frag.ID
+				}
+				calls[frag.Index].Function.Name += frag.Function.Name
+				calls[frag.Index].Function.Arguments += frag.Function.Arguments
+			}
+			if choice.Delta.Content == "" {
+				continue
+			}
+			text.WriteString(choice.Delta.Content)
+			if err := onDelta(choice.Delta.Content); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStreamDone) {
+		return ModelResponse{}, streamError("openai", err)
+	}
+	out.Output = text.String()
+	out.ToolCalls = fromOpenAIToolCalls(calls)
+	return out, nil
+}
+
+func (c *OpenAIClient) Embed(ctx context.Context, req EmbeddingRequest) (EmbeddingResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.post(ctx, "/embeddings", openAIEmbeddingRequest{
+		Model:      req.Model,
+		Input:      req.Input,
+		Dimensions: req.Dimensions,
+	})
+	if err != nil {
+		return EmbeddingResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxUpstreamBodyBytes))
+	if err != nil {
+		return EmbeddingResponse{}, upstreamTransportError("openai", err)
+	}
+	var out openAIEmbeddingResponse
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return EmbeddingResponse{}, upstreamDecodeError("openai", err)
+	}
+	vectors := make([][]float32, len(req.Input))
+	filled := make([]bool, len(req.Input))
+	for _, d := range out.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return EmbeddingResponse{}, upstreamDecodeError("openai", fmt.Errorf("embedding index %d out of range", d.Index))
+		}
+		if filled[d.Index] {
+			return EmbeddingResponse{}, upstreamDecodeError("openai", fmt.Errorf("embedding index %d returned twice", d.Index))
+		}
+		vectors[d.Index], filled[d.Index] = d.Embedding, true
+	}
+	if i := slices.Index(filled, false); i >= 0 {
+		return EmbeddingResponse{}, upstreamDecodeError("openai", fmt.Errorf("embedding index %d missing", i))
+	}
+	return EmbeddingResponse{Vectors: vectors, InputTokens: out.Usage.PromptTokens}, nil
+}
+
+func (c *OpenAIClient) request(req ModelRequest, stream bool) openAIChatRequest {
+	payload := openAIChatRequest{
+		Model:          req.Model,
+		Messages:       openAIMessages(req.Messages),
+		Tools:          openAITools(req.Tools),
+		ToolChoice:     openAIToolChoice(req.ToolChoice),
+		ResponseFormat: openAIResponseFormat(req.ResponseFormat),
+		MaxTokens:      req.MaxTokens,
+		Temperature:    req.Temperature,
+		TopP:           req.TopP,
+		Stop:           req.Stop,
+	}
+	if stream {
+		payload.Stream = true
+		payload.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
+	return payload
+}
+
+// openAIResponseFormat maps a response format onto OpenAI's native
+// structured output. Strict mode is left off: it rejects schemas outside
+// OpenAI's supported subset, and the gateway validates the output anyway.
+func openAIResponseFormat(rf *contracts.ResponseFormat) any {
+	if rf == nil {
+		return nil
+	}
+	switch rf.Type {
+	case FormatJSONObject:
+		return map[string]string{"type": FormatJSONObject}
+	case FormatJSONSchema:
+		name := rf.Name
+		if name == "" {
+			name = "response"
+		}
+		return map[string]any{
+			"type":        FormatJSONSchema,
+			"json_schema": map[string]any{"name": name, "schema": rf.Schema},
+		}
+	}
+	return nil
+}
+
+func openAIMessages(msgs []contracts.Message) []openAIMessage {
+	out := make([]openAIMessage, 0, len(msgs))
+	for _, m := range msgs {
+		msg := openAIMessage{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+		for _, call := range m.ToolCalls {
+			msg.ToolCalls = append(msg.ToolCalls, openAIToolCall{
+				ID:       call.ID,
+				Type:     "function",
+				Function: openAIFunctionCall{Name: call.Name, Arguments: call.Arguments},
+			})
+		}
+		out = append(out, msg)
+	}
+	return out
+}
+
+func openAITools(tools []contracts.Tool) []openAITool {
+	if len(tools) == 0 {
+		return nil
+	}
+	out := make([]openAITool, 0, len(tools))
+	for _, t := range tools {
+		out = append(out, openAITool{
+			Type:     "function",
+			Function: openAIFunction{Name: t.Name, Description: t.Description, Parameters: t.Parameters},
+		})
+	}
+	return out
+}
+
+// openAIToolChoice passes the modes through and wraps a tool name in the
+// function selector object.
+func openAIToolChoice(choice string) any {
+	switch choice {
+	case "":
+		return nil
+	case ToolChoiceAuto, ToolChoiceNone, ToolChoiceRequired:
+		return choice
+	}
+	return map[string]any{"type": "function", "function": map[string]string{"name": choice}}
+}
+
+func fromOpenAIToolCalls(calls []openAIToolCall) []contracts.ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]contracts.ToolCall, 0, len(calls))
+	for _, call := range calls {
+		out = append(out, contracts.ToolCall{ID: call.ID, Name: call.Function.Name, Arguments: call.Function.Arguments})
+	}
+	return out
+}
+
+// post sends a JSON request to path; do maps non-2xx responses to AppErrors.
+// The caller owns the returned body.
+func (c *OpenAIClient) post(ctx context.Context, path string, payload any) (*http.Response, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(ctx, http.MethodPost, path, bytes.NewReader(body))
+}
+
+// Probe lists the upstream's models as a cheap health check.
+func (c *OpenAIClient) Probe(ctx context.Context) error {
+	resp, err := c.do(ctx, http.MethodGet, "/models", nil)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxUpstreamBodyBytes))
+	return resp.Body.Close()
+}
+
+// ListModels returns the IDs the upstream lists under /models.
+func (c *OpenAIClient) ListModels(ctx context.Context) ([]string, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxUpstreamBodyBytes))
+	if err != nil {
+		return nil, upstreamTransportError("openai", err)
+	}
+	var out openAIModelList
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, upstreamDecodeError("openai", err)
+	}
+	models := make([]string, 0, len(out.Data))
+	for _, m := range out.Data {
+		models = append(models, m.ID)
+	}
+	return models, nil
+}
+
+func (c *OpenAIClient) do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, upstreamTransportError("openai", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxUpstreamBodyBytes))
+		var envelope openAIErrorResponse
+		_ = json.Unmarshal(raw, &envelope)
+		upstreamErr := upstreamStatusError("openai", resp.StatusCode, envelope.Error.Message)
+		upstreamErr.RetryAfter = parseRetryAfter(resp.Header)
+		return nil, upstreamErr
+	}
+	return resp, nil
+}
@@ -0,0 +1,315 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+func userTurn(content string) []contracts.Message {
+	return []contracts.Message{{Role: RoleUser, Content: content}}
+}
+
+func TestOpenAIClientCompleteReturnsUsage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			t.Errorf("unexpected auth header: %q", got)
+		}
+		var body openAIChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Model != "gpt-4o-mini" || len(body.Messages) != 1 || body.Messages[0].Content != "hello" {
+			t.Errorf("unexpected request body: %+v", body)
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi there"}}],"usage":{"prompt_tokens":11,"completion_tokens":3}}`))
+	}))
+	defer srv.Close()
+
+	client := NewOpenAIClient(config.ProviderConfig{BaseURL: srv.URL + "/v1", APIKey: "sk-test"})
+	resp, err := client.Complete(context.Background(), ModelRequest{Model: "gpt-4o-mini", Messages: userTurn("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Output != "hi there" || resp.InputTokens != 11 || resp.OutputTokens != 3 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestOpenAIClientMapsUpstreamErrors(t *testing.T) {
+	cases := []struct {
+		status int
+		code   string
+	}{
+		{http.StatusTooManyRequests, CodeUpstreamRateLimited},
+		{http.StatusUnauthorized, CodeUpstreamAuthFailed},
+		{http.StatusBadRequest, CodeUpstreamBadRequest},
+		{http.StatusServiceUnavailable, CodeUpstreamUnavailable},
+	}
+	for _, tc := range cases {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(tc.status)
+			_, _ = w.Write([]byte(`{"error":{"message":"nope","type":"x"}}`))
+		}))
+		client := NewOpenAIClient(config.ProviderConfig{BaseURL: srv.URL})
+		_, err := client.Complete(context.Background(), ModelRequest{Model: "m", Messages: userTurn("x")})
+		srv.Close()
+
+		var appErr *AppError
+		if !errors.As(err, &appErr) {
+			t.Fatalf("status %d: expected AppError, got %v", tc.status, err)
+		}
+		if appErr.Code != tc.code {
+			t.Fatalf("status %d: expected code %s, got %s", tc.status, tc.code, appErr.Code)
+		}
+	}
+}
+
+func TestOpenAIClientTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	client := NewOpenAIClient(config.ProviderConfig{BaseURL: srv.URL, TimeoutMS: 20})
+	_, err := client.Complete(context.Background(), ModelRequest{Model: "m", Messages: userTurn("x")})
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.Code != CodeUpstreamTimeout {
+		t.Fatalf("expected %s, got %v", CodeUpstreamTimeout, err)
+	}
+}
+
+func TestOpenAIClientStreamParsesChunksAndUsage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body openAIChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if !body.Stream || body.StreamOptions == nil || !body.StreamOptions.IncludeUsage {
+			t.Errorf("expected streaming request with usage, got %+v", body)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"hel\"}}]}\n\n" +
+			"data: {\"choices\":[{\"delta\":{\"content\":\"lo\"}}]}\n\n" +
+			"data: {\"choices\":[],\"usage\":{\"prompt_tokens\":5,\"completion_tokens\":2}}\n\n" +
+			"data: [DONE]\n\n"))
+	}))
+	defer srv.Close()
+
+	client := NewOpenAIClient(config.ProviderConfig{BaseURL: srv.URL})
+	var deltas []string
+	resp, err := client.Stream(context.Background(), ModelRequest{Model: "m", Messages: userTurn("x")}, func(d string) error {
+		deltas = append(deltas, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deltas) != 2 || resp.Output != "hello" || resp.InputTokens != 5 || resp.OutputTokens != 2 {
+		t.Fatalf("unexpected stream result: deltas=%v resp=%+v", deltas, resp)
+	}
+}
+
+func TestOpenAIClientEmbedOrdersVectorsByIndex(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		var body struct {
+			Input []string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Input) != 2 {
+			t.Errorf("unexpected request body: %+v %v", body, err)
+		}
+		_, _ = w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}],"usage":{"prompt_tokens":6,"total_tokens":6}}`))
+	}))
+	defer srv.Close()
+
+	client := NewOpenAIClient(config.ProviderConfig{BaseURL: srv.URL + "/v1"})
+	resp, err := client.Embed(context.Background(), EmbeddingRequest{Model: "text-embedding-3-small", Input: []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.InputTokens != 6 || resp.Vectors[0][0] != 1 || resp.Vectors[1][1] != 1 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestOpenAIClientEmbedRejectsMissingOrDuplicateIndexes(t *testing.T) {
+	for name, data := range map[string]string{
+		"missing":   `[{"index":0,"embedding":[1,0]}]`,
+		"duplicate": `[{"index":0,"embedding":[1,0]},{"index":0,"embedding":[0,1]}]`,
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(`{"data":` + data + `,"usage":{"prompt_tokens":6,"total_tokens":6}}`))
+		}))
+		client := NewOpenAIClient(config.ProviderConfig{BaseURL: srv.URL + "/v1"})
+		_, err := client.Embed(context.Background(), EmbeddingRequest{Model: "text-embedding-3-small", Input: []string{"a", "b"}})
+		srv.Close()
+		var appErr *AppError
+		if !errors.As(err, &appErr) || appErr.Code != CodeUpstreamInvalidResponse {
+			t.Fatalf("%s: expected %s, got %v", name, CodeUpstreamInvalidResponse, err)
+		}
+	}
+}
+
+func TestOpenAIClientTranslatesTools(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Tools []struct {
+				Type     string `json:"type"`
+				Function struct {
+					Name string `json:"name"`
+				} `json:"function"`
+			} `json:"tools"`
+			ToolChoice struct {
+				Function struct {
+					Name string `json:"name"`
+				} `json:"function"`
+			} `json:"tool_choice"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if len(body.Tools) != 1 || body.Tools[0].Type != "function" || body.ToolChoice.Function.Name != "search_logs" {
+			t.Errorf("unexpected tools payload: %+v", body)
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"search_logs","arguments":"{\"q\":\"x\"}"}}]}}]}`))
+	}))
+	defer srv.Close()
+
+	client := NewOpenAIClient(config.ProviderConfig{BaseURL: srv.URL})
+	resp, err := client.Complete(context.Background(), ModelRequest{
+		Model:      "m",
+		Messages:   userTurn("x"),
+		Tools:      []contracts.Tool{{Name: "search_logs"}},
+		ToolChoice: "search_logs",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "call_1" || resp.ToolCalls[0].Arguments != `{"q":"x"}` {
+		t.Fatalf("unexpected tool calls: %+v", resp.ToolCalls)
+	}
+}
+
+func TestOpenAIClientStreamAssemblesToolCalls(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"id\":\"call_1\",\"function\":{\"name\":\"lookup_ip\",\"arguments\":\"{\\\"ip\\\":\"}}]}}]}\n\n" +
+			"data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":\"\\\"10.0.0.8\\\"}\"}}]}}]}\n\n" +
+			"data: [DONE]\n\n"))
+	}))
+	defer srv.Close()
+
+	client := NewOpenAIClient(config.ProviderConfig{BaseURL: srv.URL})
+	resp, err := client.Stream(context.Background(), ModelRequest{Model: "m", Messages: userTurn("x")}, func(string) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "lookup_ip" || resp.ToolCalls[0].Arguments != `{"ip":"10.0.0.8"}` {
+		t.Fatalf("unexpected tool calls: %+v", resp.ToolCalls)
+	}
+}
+
+func TestOpenAIClientStreamRejectsBadToolCallIndex(t *testing.T) {
+	for _, index := range []int{-1, 1 << 30} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":%d,\"id\":\"call_1\",\"function\":{\"name\":\"lookup_ip\"}}]}}]}\n\ndata: [DONE]\n\n", index)
+		}))
+
+		client := NewOpenAIClient(config.ProviderConfig{BaseURL: srv.URL})
+		_, err := client.Stream(context.Background(), ModelRequest{Model: "m", Messages: userTurn("x")}, func(string) error { return nil })
+		srv.Close()
+		var appErr *AppError
+		if !errors.As(err, &appErr) || appErr.Code != CodeUpstreamInvalidResponse {
+			t.Fatalf("index %d: expected %s, got %v", index, CodeUpstreamInvalidResponse, err)
+		}
+	}
+}
+
+func TestOpenAIClientSendsResponseFormat(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			ResponseFormat struct {
+				Type       string `json:"type"`
+				JSONSchema struct {
+					Name   string          `json:"name"`
+					Schema json.RawMessage `json:"schema"`
+				} `json:"json_schema"`
+			} `json:"response_format"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body.ResponseFormat.Type != FormatJSONSchema || body.ResponseFormat.JSONSchema.Name != "response" || string(body.ResponseFormat.JSONSchema.Schema) != `{"type":"object"}` {
+			t.Errorf("unexpected response_format: %+v", body.ResponseFormat)
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"{}"}}]}`))
+	}))
+	defer srv.Close()
+
+	client := NewOpenAIClient(config.ProviderConfig{BaseURL: srv.URL})
+	_, err := client.Complete(context.Background(), ModelRequest{
+		Model:          "m",
+		Messages:       userTurn("x"),
+		ResponseFormat: &contracts.ResponseFormat{Type: FormatJSONSchema, Schema: json.RawMessage(`{"type":"object"}`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenAIClientSendsGenerationParams(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body["max_tokens"] != 64.0 || body["temperature"] != 0.0 || body["top_p"] != 0.9 || fmt.Sprint(body["stop"]) != "[END]" {
+			t.Errorf("unexpected generation params: %v", body)
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer srv.Close()
+
+	temp, topP := 0.0, 0.9
+	client := NewOpenAIClient(config.ProviderConfig{BaseURL: srv.URL})
+	if _, err := client.Complete(context.Background(), ModelRequest{Model: "m", Messages: userTurn("x"), MaxTokens: 64, Temperature: &temp, TopP: &topP, Stop: []string{"END"}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLlamaCppProviderListsModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"qwen2.5-7b-instruct-q4_k_m.gguf","object":"model"}]}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewModelClient(config.ProviderConfig{Type: "llamacpp", BaseURL: srv.URL + "/v1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	models, err := client.(modelLister).ListModels(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 1 || models[0] != "qwen2.5-7b-instruct-q4_k_m.gguf" {
+		t.Fatalf("unexpected models: %v", models)
+	}
+}
@@ -0,0 +1,190 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+)
+
+// Upstream attempt outcomes exported as metric labels.
+const (
+	attemptOutcomeOK        = "ok"
+	attemptOutcomeRetryable = "retryable_error"
+	attemptOutcomeError     = "error"
+)
+
+// RetryingClient retries transient upstream failures with capped exponential
+// backoff and jitter. Retry-After hints from the provider take precedence over
+// the computed delay; a hint longer than the max delay, or any wait that would
+// outlive the request deadline, ends the retries instead.
+type RetryingClient struct {
+	next        ModelClient
+	metrics     *Metrics
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	sleep       func(ctx context.Context, d time.Duration) error
+}
+
+func NewRetryingClient(next ModelClient, cfg config.RetryConfig, metrics *Metrics) *RetryingClient {
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	return &RetryingClient{
+		next:        next,
+		metrics:     metrics,
+		maxAttempts: maxAttempts,
+		baseDelay:   time.Duration(cfg.BaseDelayMS) * time.Millisecond,
+		maxDelay:    time.Duration(cfg.MaxDelayMS) * time.Millisecond,
+		sleep:       sleepContext,
+	}
+}
+
+func (c *RetryingClient) Supports(model string) bool {
+	if router, ok := c.next.(modelSupporter); ok {
+		return router.Supports(model)
+	}
+	return true
+}
+
+func (c *RetryingClient) Available(model string) bool {
+	if reporter, ok := c.next.(availabilityReporter); ok {
+		return reporter.Available(model)
+	}
+	return true
+}
+
+func (c *RetryingClient) ProviderHealth() []ProviderHealth {
+	if reporter, ok := c.next.(healthReporter); ok {
+		return reporter.ProviderHealth()
+	}
+	return nil
+}
+
+// CompleteDirect makes a single call through the wrapped client's direct
+// path, without retries.
+func (c *RetryingClient) CompleteDirect(ctx context.Context, req ModelRequest) (ModelResponse, error) {
+	if direct, ok := c.next.(directCompleter); ok {
+		return direct.CompleteDirect(ctx, req)
+	}
+	return c.next.Complete(ctx, req)
+}
+
+func (c *RetryingClient) Complete(ctx context.Context, req ModelRequest) (ModelResponse, error) {
+	return retryCall(ctx, c, req.Model, func() (ModelResponse, error) {
+		return c.next.Complete(ctx, req)
+	}, func() bool { return true })
+}
+
+// Stream retries only while nothing has been delivered to onDelta; once output
+// reached the caller a retry would duplicate it.
+func (c *RetryingClient) Stream(ctx context.Context, req ModelRequest, onDelta func(string) error) (ModelResponse, error) {
+	delivered := false
+	forward := func(delta string) error {
+		delivered = true
+		return onDelta(delta)
+	}
+	return retryCall(ctx, c, req.Model, func() (ModelResponse, error) {
+		return streamCompletion(ctx, c.next, req, forward)
+	}, func() bool { return !delivered })
+}
+
+func (c *RetryingClient) Embed(ctx context.Context, req EmbeddingRequest) (EmbeddingResponse, error) {
+	return retryCall(ctx, c, req.Model, func() (EmbeddingResponse, error) {
+		return embed(ctx, c.next, req)
+	}, func() bool { return true })
+}
+
+func retryCall[T any](ctx context.Context, c *RetryingClient, model string, call func() (T, error), retryable func() bool) (T, error) {
+	var zero T
+	for attempt := 1; ; attempt++ {
+		resp, err := call()
+		if err == nil {
+			c.observe(model, attemptOutcomeOK)
+			return resp, nil
+		}
+		if !isTransient(err) {
+			c.observe(model, attemptOutcomeError)
+			return zero, err
+		}
+		c.observe(model, attemptOutcomeRetryable)
+		if attempt >= c.maxAttempts || !retryable() {
+			return zero, err
+		}
+
+		delay := c.backoff(attempt, err)
+		if c.maxDelay > 0 && delay > c.maxDelay {
+			return zero, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			return zero, err
+		}
+		if sleepErr := c.sleep(ctx, delay); sleepErr != nil {
+			return zero, err
+		}
+	}
+}
+
+// backoff returns the wait before the next attempt: the provider's Retry-After
+// when present, otherwise base*2^(attempt-1) capped at maxDelay with "equal"
+// jitter (half fixed, half random) to spread synchronized retries.
+func (c *RetryingClient) backoff(attempt int, err error) time.Duration {
+	var appErr *AppError
+	if errors.As(err, &appErr) && appErr.RetryAfter > 0 {
+		return appErr.RetryAfter
+	}
+	delay := c.baseDelay << (attempt - 1)
+	if delay <= 0 || (c.maxDelay > 0 && delay > c.maxDelay) {
+		delay = c.maxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+func (c *RetryingClient) observe(model, outcome string) {
+	if c.metrics != nil {
+		c.metrics.UpstreamAttempts.WithLabelValues(model, outcome).Inc()
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given either as delay-seconds or
+// as an HTTP date.
+func parseRetryAfter(h http.Header) time.Duration {
+	v := strings.TrimSpace(h.Get("Retry-After"))
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
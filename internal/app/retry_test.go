@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// scriptedClient returns the queued errors in order, then succeeds.
+type scriptedClient struct {
+	errs  []error
+	calls int
+}
+
+func (c *scriptedClient) Complete(_ context.Context, req ModelRequest) (ModelResponse, error) {
+	c.calls++
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		return ModelResponse{}, err
+	}
+	return ModelResponse{Output: "ok"}, nil
+}
+
+func newTestRetryingClient(next ModelClient, cfg config.RetryConfig) (*RetryingClient, *Metrics, *[]time.Duration) {
+	metrics := NewMetrics(prometheus.NewRegistry())
+	c := NewRetryingClient(next, cfg, metrics)
+	var slept []time.Duration
+	c.sleep = func(_ context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	return c, metrics, &slept
+}
+
+func TestRetryingClientRetriesTransientErrors(t *testing.T) {
+	next := &scriptedClient{errs: []error{
+		upstreamStatusError("openai", http.StatusServiceUnavailable, ""),
+		upstreamStatusError("openai", http.StatusBadGateway, ""),
+	}}
+	c, metrics, slept := newTestRetryingClient(next, config.RetryConfig{MaxAttempts: 3, BaseDelayMS: 100, MaxDelayMS: 1000})
+
+	resp, err := c.Complete(context.Background(), ModelRequest{Model: "m"})
+	if err != nil || resp.Output != "ok" {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	if next.calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", next.calls)
+	}
+	if len(*slept) != 2 || (*slept)[0] < 50*time.Millisecond || (*slept)[0] > 100*time.Millisecond ||
+		(*slept)[1] < 100*time.Millisecond || (*slept)[1] > 200*time.Millisecond {
+		t.Fatalf("unexpected backoff delays: %v", *slept)
+	}
+	if got := testutil.ToFloat64(metrics.UpstreamAttempts.WithLabelValues("m", attemptOutcomeRetryable)); got != 2 {
+		t.Fatalf("expected 2 retryable attempts, got %v", got)
+	}
+}
+
+func TestRetryingClientDoesNotRetryPermanentErrors(t *testing.T) {
+	next := &scriptedClient{errs: []error{upstreamStatusError("openai", http.StatusBadRequest, "")}}
+	c, _, _ := newTestRetryingClient(next, config.RetryConfig{MaxAttempts: 3})
+
+	if _, err := c.Complete(context.Background(), ModelRequest{Model: "m"}); err == nil {
+		t.Fatal("expected error")
+	}
+	if next.calls != 1 {
+		t.Fatalf("expected 1 call, got %d", next.calls)
+	}
+}
+
+func TestRetryingClientHonorsRetryAfterAndDeadline(t *testing.T) {
+	limited := upstreamStatusError("openai", http.StatusTooManyRequests, "")
+	limited.RetryAfter = 2 * time.Second
+
+	next := &scriptedClient{errs: []error{limited}}
+	c, _, slept := newTestRetryingClient(next, config.RetryConfig{MaxAttempts: 3, BaseDelayMS: 10, MaxDelayMS: 5000})
+	if _, err := c.Complete(context.Background(), ModelRequest{Model: "m"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(*slept) != 1 || (*slept)[0] != 2*time.Second {
+		t.Fatalf("expected Retry-After delay, got %v", *slept)
+	}
+
+	next = &scriptedClient{errs: []error{limited}}
+	c, _, _ = newTestRetryingClient(next, config.RetryConfig{MaxAttempts: 3, BaseDelayMS: 10, MaxDelayMS: 5000})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := c.Complete(ctx, ModelRequest{Model: "m"}); err == nil {
+		t.Fatal("expected retry to stop before the deadline")
+	}
+	if next.calls != 1 {
+		t.Fatalf("expected 1 call, got %d", next.calls)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	h := http.Header{}
+	h.Set("Retry-After", "3")
+	if got := parseRetryAfter(h); got != 3*time.Second {
+		t.Fatalf("expected 3s, got %v", got)
+	}
+}
@@ -0,0 +1,240 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+)
+
+// CodeUnknownModel is returned for models that no provider route serves.
+const CodeUnknownModel = "unknown_model"
+
+// modelSupporter is implemented by clients that only serve a subset of model names.
+type modelSupporter interface {
+	Supports(model string) bool
+}
+
+// directCompleter is implemented by clients that can call a model's backend
+// bypassing retries, circuit breakers and balancer ejection, for calls whose
+// outcome must not affect other traffic.
+type directCompleter interface {
+	CompleteDirect(ctx context.Context, req ModelRequest) (ModelResponse, error)
+}
+
+// deploymentsBackend prefixes the backend names of balancers; provider names
+// may not use it.
+const deploymentsBackend = "deployments:"
+
+type prefixRoute struct {
+	prefix   string
+	provider string
+}
+
+// Router is a ModelClient that dispatches each model to a named provider backend.
+type Router struct {
+	backends map[string]ModelClient
+	exact    map[string]string
+	prefixes []prefixRoute
+	health   *HealthChecker
+	// direct routes the same models to the unguarded provider clients.
+	direct *Router
+}
+
+// NewRouter builds a backend per configured provider, guarded by its own
+// circuit breaker when one is configured and by its health checks, plus a
+// Balancer per entry of ModelDeployments, and validates that every route
+// targets a configured provider. Probes only run once RunHealthChecks is
+// called. metrics and logger may be nil.
+func NewRouter(cfg config.Config, metrics *Metrics, logger *slog.Logger) (*Router, error) {
+	health := NewHealthChecker(cfg.HealthCheck, metrics)
+	backends := make(map[string]ModelClient, len(cfg.Providers))
+	raw := make(map[string]ModelClient, len(cfg.Providers))
+	for _, p := range cfg.Providers {
+		if p.Name == "" {
+			return nil, fmt.Errorf("provider of type %q has no name", p.Type)
+		}
+		if strings.HasPrefix(p.Name, deploymentsBackend) {
+			return nil, fmt.Errorf("provider name %q uses the reserved prefix %q", p.Name, deploymentsBackend)
+		}
+		if _, dup := backends[p.Name]; dup {
+			return nil, fmt.Errorf("duplicate provider name %q", p.Name)
+		}
+		client, err := NewModelClient(p, logger)
+		if err != nil {
+			return nil, fmt.Errorf("provider %q: %w", p.Name, err)
+		}
+		health.Register(p.Name, client)
+		raw[p.Name] = client
+		if cfg.CircuitBreaker.FailureRatio > 0 {
+			client = newBreakerClient(p.Name, client, cfg.CircuitBreaker, metrics)
+		}
+		backends[p.Name] = &healthGate{provider: p.Name, next: client, health: health}
+	}
+	routes := cfg.ModelRoutes
+	if len(cfg.ModelDeployments) > 0 {
+		var err error
+		if routes, err = addDeployments(cfg, backends, metrics); err != nil {
+			return nil, err
+		}
+	}
+	r, err := newRouter(backends, routes)
+	if err != nil {
+		return nil, err
+	}
+	r.health = health
+	if len(cfg.ModelDeployments) > 0 {
+		// Direct balancers keep their own rotation and never eject.
+		directCfg := cfg
+		directCfg.Ejection = config.EjectionConfig{}
+		if _, err := addDeployments(directCfg, raw, nil); err != nil {
+			return nil, err
+		}
+	}
+	if r.direct, err = newRouter(raw, routes); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// addDeployments registers a Balancer backend per ModelDeployments entry and
+// returns the routes extended with them.
+func addDeployments(cfg config.Config, backends map[string]ModelClient, metrics *Metrics) (map[string]string, error) {
+	// Balancers share the provider backends and therefore their circuit
+	// breakers.
+	routes := make(map[string]string, len(cfg.ModelRoutes)+len(cfg.ModelDeployments))
+	for pattern, provider := range cfg.ModelRoutes {
+		routes[pattern] = provider
+	}
+	providers := make(map[string]ModelClient, len(backends))
+	for name, client := range backends {
+		providers[name] = client
+	}
+	for pattern, pool := range cfg.ModelDeployments {
+		if _, dup := routes[pattern]; dup {
+			return nil, fmt.Errorf("model %q has both a route and deployments", pattern)
+		}
+		balancer, err := NewBalancer(pool, providers, cfg.Ejection, metrics)
+		if err != nil {
+			return nil, fmt.Errorf("deployments for %q: %w", pattern, err)
+		}
+		name := deploymentsBackend + pattern
+		backends[name] = balancer
+		routes[pattern] = name
+	}
+	return routes, nil
+}
+
+func newRouter(backends map[string]ModelClient, routes map[string]string) (*Router, error) {
+	r := &Router{backends: backends, exact: make(map[string]string)}
+	for pattern, provider := range routes {
+		if _, ok := backends[provider]; !ok {
+			return nil, fmt.Errorf("route %q targets unknown provider %q", pattern, provider)
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			r.prefixes = append(r.prefixes, prefixRoute{prefix: prefix, provider: provider})
+			continue
+		}
+		r.exact[pattern] = provider
+	}
+	sort.Slice(r.prefixes, func(i, j int) bool {
+		if len(r.prefixes[i].prefix) != len(r.prefixes[j].prefix) {
+			return len(r.prefixes[i].prefix) > len(r.prefixes[j].prefix)
+		}
+		return r.prefixes[i].prefix < r.prefixes[j].prefix
+	})
+	return r, nil
+}
+
+// Resolve returns the provider name serving model.
+func (r *Router) Resolve(model string) (string, bool) {
+	if provider, ok := r.exact[model]; ok {
+		return provider, true
+	}
+	for _, route := range r.prefixes {
+		if strings.HasPrefix(model, route.prefix) {
+			return route.provider, true
+		}
+	}
+	return "", false
+}
+
+// RunHealthChecks probes the providers until ctx is done; see HealthChecker.
+func (r *Router) RunHealthChecks(ctx context.Context) {
+	if r.health != nil {
+		r.health.Run(ctx)
+	}
+}
+
+// ProviderHealth returns the provider health table, or nil when the router
+// was built without health checks.
+func (r *Router) ProviderHealth() []ProviderHealth {
+	if r.health == nil {
+		return nil
+	}
+	return r.health.Snapshot()
+}
+
+// Available reports whether model is routed to a backend that is not failing
+// its health checks.
+func (r *Router) Available(model string) bool {
+	provider, ok := r.Resolve(model)
+	if !ok {
+		return false
+	}
+	if gate, ok := r.backends[provider].(interface{ available() bool }); ok {
+		return gate.available()
+	}
+	return true
+}
+
+func (r *Router) Supports(model string) bool {
+	_, ok := r.Resolve(model)
+	return ok
+}
+
+func (r *Router) Complete(ctx context.Context, req ModelRequest) (ModelResponse, error) {
+	provider, ok := r.Resolve(req.Model)
+	if !ok {
+		return ModelResponse{}, unknownModelError(req.Model)
+	}
+	return r.backends[provider].Complete(ctx, req)
+}
+
+// CompleteDirect calls the provider client serving req.Model without its
+// circuit breaker, health gate or balancer ejection. Routers built without
+// direct clients complete normally.
+func (r *Router) CompleteDirect(ctx context.Context, req ModelRequest) (ModelResponse, error) {
+	if r.direct == nil {
+		return r.Complete(ctx, req)
+	}
+	return r.direct.Complete(ctx, req)
+}
+
+func (r *Router) Stream(ctx context.Context, req ModelRequest, onDelta func(string) error) (ModelResponse, error) {
+	provider, ok := r.Resolve(req.Model)
+	if !ok {
+		return ModelResponse{}, unknownModelError(req.Model)
+	}
+	return streamCompletion(ctx, r.backends[provider], req, onDelta)
+}
+
+func (r *Router) Embed(ctx context.Context, req EmbeddingRequest) (EmbeddingResponse, error) {
+	provider, ok := r.Resolve(req.Model)
+	if !ok {
+		return EmbeddingResponse{}, unknownModelError(req.Model)
+	}
+	return embed(ctx, r.backends[provider], req)
+}
+
+func unknownModelError(model string) *AppError {
+	return &AppError{
+		Code:       CodeUnknownModel,
+		Message:    fmt.Sprintf("no provider is configured for model %q", model),
+		HTTPStatus: http.StatusBadRequest,
+	}
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+)
+
+type namedClient string
+
+func (n namedClient) Complete(_ context.Context, _ ModelRequest) (ModelResponse, error) {
+	return ModelResponse{Output: string(n)}, nil
+}
+
+func TestRouterDispatchesByExactNameThenLongestPrefix(t *testing.T) {
+	r, err := newRouter(
+		map[string]ModelClient{"openai": namedClient("openai"), "anthropic": namedClient("anthropic"), "local": namedClient("local")},
+		map[string]string{"gpt-*": "openai", "gpt-4o-*": "local", "gpt-4o-mini": "anthropic", "claude-*": "anthropic"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"gpt-4.1-mini":      "openai",
+		"gpt-4o-large":      "local",
+		"gpt-4o-mini":       "anthropic",
+		"claude-3-5-sonnet": "anthropic",
+	}
+	for model, want := range cases {
+		resp, err := r.Complete(context.Background(), ModelRequest{Model: model})
+		if err != nil {
+			t.Fatalf("%s: %v", model, err)
+		}
+		if resp.Output != want {
+			t.Fatalf("%s: expected %s, got %s", model, want, resp.Output)
+		}
+	}
+}
+
+func TestRouterRejectsUnknownModel(t *testing.T) {
+	r, err := newRouter(map[string]ModelClient{"openai": namedClient("openai")}, map[string]string{"gpt-*": "openai"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Supports("llama3") {
+		t.Fatal("expected llama3 to be unsupported")
+	}
+	_, err = r.Complete(context.Background(), ModelRequest{Model: "llama3"})
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.Code != CodeUnknownModel {
+		t.Fatalf("expected %s, got %v", CodeUnknownModel, err)
+	}
+}
+
+func TestNewRouterValidatesRoutes(t *testing.T) {
+	providers := []config.ProviderConfig{{Name: "sim", Type: "simulated"}}
+	if _, err := NewRouter(config.Config{Providers: providers, ModelRoutes: map[string]string{"gpt-*": "missing"}}, nil, nil); err == nil {
+		t.Fatal("expected error for route to unknown provider")
+	}
+	if _, err := NewRouter(config.Config{Providers: append(providers, config.ProviderConfig{Name: "sim"})}, nil, nil); err == nil {
+		t.Fatal("expected error for duplicate provider name")
+	}
+}
@@ -5,7 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math/rand/v2"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/WaiperOK/llm-gateway-control-plane/internal/audit"
@@ -14,15 +17,17 @@ import (
 	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
 	"github.com/WaiperOK/llm-gateway-control-plane/internal/policy"
 	"github.com/WaiperOK/llm-gateway-control-plane/internal/ratelimit"
-	"github.com/WaiperOK/llm-gateway-control-plane/internal/redaction"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/shadow"
 	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
 )
 
-// AppError represents a typed API-level error.
+// AppError represents a typed API-level error. RetryAfter carries an upstream
+// retry hint and is zero for errors raised by the gateway itself.
 type AppError struct {
 	Code       string
 	Message    string
 	HTTPStatus int
+	RetryAfter time.Duration
 }
 
 func (e *AppError) Error() string { return e.Message }
@@ -38,30 +43,101 @@ type Service struct {
 	metrics      *Metrics
 	modelClient  ModelClient
 	defaultModel string
+	fallbacks    map[string][]string
+	maxReasks    int
+	hedging      map[string]config.HedgingConfig
+	latencies    *latencyWindow
+	catalog      map[string]config.ModelInfo
+	aliases      aliasResolver
+	shadowing    map[string]config.ShadowConfig
+	shadows      *shadow.Store
+	shadowSlots  chan struct{}
+	sample       func() float64
+	experiments  map[string][]experiment
+	jobs         *jobRunner
+	// background tracks work that outlives its request; see Shutdown.
+	background sync.WaitGroup
 }
 
+// estimatedOutputTokens is the output size assumed for pre-call budget checks
+// of requests that set no max_tokens.
+const estimatedOutputTokens = 120
+
 func NewService(cfg config.Config, logger *slog.Logger, metrics *Metrics, modelClient ModelClient) *Service {
 	teamDescriptors := make([]auth.TeamDescriptor, 0, len(cfg.Teams))
+	hedging := make(map[string]config.HedgingConfig)
+	shadowing := make(map[string]config.ShadowConfig)
 	for _, t := range cfg.Teams {
-		teamDescriptors = append(teamDescriptors, auth.TeamDescriptor{
+		if t.Hedging != nil && t.Hedging.Percentile > 0 {
+			hedging[t.Name] = *t.Hedging
+		}
+		if t.Shadow != nil && t.Shadow.Model != "" && t.Shadow.Percent > 0 {
+			if router, ok := modelClient.(modelSupporter); ok && !router.Supports(t.Shadow.Model) {
+				logger.Warn("shadow model is not routed to any provider, shadowing disabled", "team", t.Name, "model", t.Shadow.Model)
+			} else {
+				shadowing[t.Name] = *t.Shadow
+			}
+		}
+		descriptor := auth.TeamDescriptor{
 			Team:              t.Name,
 			APIKey:            t.APIKey,
 			AllowedModels:     t.AllowedModels,
+			AllowedTools:      t.AllowedTools,
 			RequestsPerMinute: t.RequestsPerMinute,
 			MonthlyBudgetUSD:  t.MonthlyBudgetUSD,
-		})
+		}
+		if g := t.Generation; g != nil {
+			descriptor.MaxTokens, descriptor.MinTemperature, descriptor.MaxTemperature = g.MaxTokens, g.MinTemperature, g.MaxTemperature
+		}
+		teamDescriptors = append(teamDescriptors, descriptor)
+	}
+
+	// Models served by local providers are free unless priced explicitly.
+	billingSvc := billing.NewService(cfg.PricingPer1KUSD)
+	for _, pattern := range localModelPatterns(cfg) {
+		billingSvc.SetDefaultPrice(pattern, 0)
+	}
+	applyCatalogPrices(billingSvc, cfg.ModelCatalog)
+	aliases := newAliasResolver(cfg)
+	for _, t := range cfg.Teams {
+		for _, m := range t.AllowedModels {
+			if resolved, ok := aliases.resolve(t.Name, m); ok {
+				m = resolved
+			}
+			if !billingSvc.Priced(m) {
+				logger.Warn("model has no configured price, billing at the fallback rate", "team", t.Name, "model", m, "price_per_1k_usd", billingSvc.UnitPrice(m))
+			}
+		}
+	}
+
+	shadows, err := shadow.NewStore(cfg.MaxAuditEvents, cfg.ShadowLogPath)
+	if err != nil {
+		logger.Error("shadow log unavailable, keeping shadow pairs in memory only", "path", cfg.ShadowLogPath, "err", err)
+		shadows, _ = shadow.NewStore(cfg.MaxAuditEvents, "")
 	}
 
 	return &Service{
 		logger:       logger,
 		auth:         auth.NewAPIKeyAuth(teamDescriptors),
-		policy:       policy.NewEngine(cfg.BlockedPatterns),
+		policy:       policy.NewEngine(cfg.BlockedPatterns, cfg.RolePatterns),
 		limiter:      ratelimit.NewLimiter(),
-		billing:      billing.NewService(cfg.PricingPer1KUSD),
+		billing:      billingSvc,
 		audit:        audit.NewStore(cfg.MaxAuditEvents),
 		metrics:      metrics,
 		modelClient:  modelClient,
 		defaultModel: cfg.DefaultModel,
+		fallbacks:    cfg.FallbackChains,
+		maxReasks:    cfg.Structured.MaxReasks,
+		hedging:      hedging,
+		latencies:    newLatencyWindow(),
+		catalog:      cfg.ModelCatalog,
+		aliases:      aliases,
+		shadowing:    shadowing,
+		shadows:      shadows,
+		shadowSlots:  make(chan struct{}, maxShadowCalls),
+		sample:       rand.Float64,
+		experiments:  newExperiments(cfg, logger),
+		jobs:         newJobRunner(cfg.Jobs, logger),
 	}
 }
 
@@ -81,17 +157,71 @@ func (s *Service) HandleCompletion(
 	requestID string,
 	principal auth.Principal,
 	req contracts.CompletionRequest,
+) (contracts.CompletionResponse, *AppError) {
+	return s.handleCompletion(ctx, requestID, principal, req, nil, admitAndServe)
+}
+
+// HandleCompletionStream runs the same pipeline as HandleCompletion but
+// forwards output to onDelta as it is generated, along with the model serving
+// it (which may differ from the requested one for aliases, auto selection or
+// fallbacks). Validation, policy, rate and
+// budget checks all happen before the first delta, so an error returned
+// without any delta having been sent can still be reported as a plain
+// response. Usage is settled when the stream ends, including partial output
+// when the caller disconnects.
+func (s *Service) HandleCompletionStream(
+	ctx context.Context,
+	requestID string,
+	principal auth.Principal,
+	req contracts.CompletionRequest,
+	onDelta func(model, delta string) error,
+) (contracts.CompletionResponse, *AppError) {
+	return s.handleCompletion(ctx, requestID, principal, req, &deltaSink{emit: onDelta}, admitAndServe)
+}
+
+// admission says how much of the completion pipeline handleCompletion runs.
+type admission int
+
+const (
+	// admitAndServe checks the request and serves it.
+	admitAndServe admission = iota
+	// admitOnly runs the checks up to the pre-call budget estimate, charging
+	// the rate limit, and stops before calling the model.
+	admitOnly
+	// serveAdmitted serves a request that passed admitOnly earlier; it is
+	// checked again but not charged to the rate limit twice.
+	serveAdmitted
+)
+
+func (s *Service) handleCompletion(
+	ctx context.Context,
+	requestID string,
+	principal auth.Principal,
+	req contracts.CompletionRequest,
+	sink *deltaSink,
+	mode admission,
 ) (contracts.CompletionResponse, *AppError) {
 	start := time.Now()
 	model := req.Model
 	if model == "" {
 		model = s.defaultModel
 	}
+	var alias string
+	if resolved, ok := s.aliases.resolve(principal.Team, model); ok {
+		alias, model = model, resolved
+	}
+	requestedModel := model
+	// Variant models are checked against the team's allowlist on their own,
+	// not through the alias the caller used.
+	arm, enrolled := s.assignExperiment(principal.Team, alias, model, req.User, start)
+	if enrolled {
+		model = arm.Model
+	}
 	status := "ok"
 
 	track := func(inputTokens, outputTokens int, cost float64) {
-		s.metrics.RequestsTotal.WithLabelValues(principal.Team, model, status).Inc()
-		s.metrics.LatencySec.WithLabelValues(principal.Team, model, status).Observe(time.Since(start).Seconds())
+		s.metrics.RequestsTotal.WithLabelValues(principal.Team, model, OperationCompletion, status).Inc()
+		s.metrics.LatencySec.WithLabelValues(principal.Team, model, OperationCompletion, status).Observe(time.Since(start).Seconds())
 		if inputTokens > 0 {
 			s.metrics.TokensTotal.WithLabelValues(principal.Team, model, "input").Add(float64(inputTokens))
 		}
@@ -101,135 +231,232 @@ func (s *Service) HandleCompletion(
 		if cost > 0 {
 			s.metrics.CostTotalUSD.WithLabelValues(principal.Team, model).Add(cost)
 		}
+		if alias != "" {
+			s.metrics.AliasRequests.WithLabelValues(principal.Team, alias, model, status).Inc()
+		}
+		if enrolled {
+			s.metrics.ExperimentRequests.WithLabelValues(arm.Experiment, arm.Variant, status).Inc()
+			s.metrics.ExperimentLatencySec.WithLabelValues(arm.Experiment, arm.Variant, status).Observe(time.Since(start).Seconds())
+			if cost > 0 {
+				s.metrics.ExperimentCostUSD.WithLabelValues(arm.Experiment, arm.Variant).Add(cost)
+			}
+		}
 	}
 
-	if req.Input == "" {
+	if appErr := validateCompletionInput(req); appErr != nil {
 		status = "bad_request"
 		track(0, 0, 0)
-		return contracts.CompletionResponse{}, &AppError{Code: "invalid_input", Message: "input is required", HTTPStatus: http.StatusBadRequest}
+		return contracts.CompletionResponse{}, appErr
 	}
-	if len([]rune(req.Input)) > 32000 {
+
+	format, appErr := s.responseFormat(req, sink != nil)
+	if appErr != nil {
 		status = "bad_request"
 		track(0, 0, 0)
-		return contracts.CompletionResponse{}, &AppError{Code: "input_too_large", Message: "input exceeds 32000 characters", HTTPStatus: http.StatusBadRequest}
+		return contracts.CompletionResponse{}, appErr
 	}
 
-	redacted := redaction.Scrub(req.Input)
-	decision := s.policy.Evaluate(policy.Input{Model: model, Prompt: req.Input, AllowedModels: principal.AllowedModels})
-	if !decision.Allowed {
-		status = "denied_policy"
+	if appErr := validateGenerationParams(req); appErr != nil {
+		status = "bad_request"
+		track(0, 0, 0)
+		return contracts.CompletionResponse{}, appErr
+	}
+
+	var selectionReason string
+	if model == AutoModel {
+		candidate := withGenerationParams(ModelRequest{Messages: conversation(req), Tools: req.Tools, ToolChoice: req.ToolChoice}, req, principal)
+		picked, appErr := s.selectModel(principal, candidate, approxInputTokens(req), sink != nil)
+		if appErr != nil {
+			status = "bad_request"
+			if appErr.Code == "budget_exceeded" {
+				status = "budget_exceeded"
+			}
+			track(0, 0, 0)
+			return contracts.CompletionResponse{}, appErr
+		}
+		model, selectionReason = picked.Model, picked.Reason
+		if picked.Name != picked.Model {
+			alias = picked.Name
+		}
+	}
+
+	if router, ok := s.modelClient.(modelSupporter); ok && !router.Supports(model) {
+		status = "bad_request"
+		track(0, 0, 0)
+		return contracts.CompletionResponse{}, unknownModelError(model)
+	}
+
+	modelReq := withGenerationParams(ModelRequest{Model: model, Messages: conversation(req), Tools: req.Tools, ToolChoice: req.ToolChoice}, req, principal)
+	if format != nil {
+		modelReq.ResponseFormat = req.ResponseFormat
+	}
+	redactedInput, redactedMessages := redactRequest(req)
+	var calledTools []string
+	// Losing hedged calls are billed on their own; the audited cost includes
+	// them.
+	record := func(reason string, cost float64, attempts []audit.Attempt) {
+		cost += extraCost(attempts)
 		s.audit.Add(audit.Event{
-			Timestamp:     time.Now().UTC(),
-			RequestID:     requestID,
-			Team:          principal.Team,
-			Model:         model,
-			Status:        status,
-			DenyReason:    decision.Reason,
-			RedactedInput: redacted.Text,
-			LatencyMS:     time.Since(start).Milliseconds(),
+			Timestamp:        time.Now().UTC(),
+			RequestID:        requestID,
+			Team:             principal.Team,
+			Operation:        OperationCompletion,
+			Model:            model,
+			RequestedModel:   requestedModel,
+			Alias:            alias,
+			SelectionReason:  selectionReason,
+			Experiment:       arm.Experiment,
+			Variant:          arm.Variant,
+			Status:           status,
+			DenyReason:       reason,
+			RedactedInput:    redactedInput,
+			RedactedMessages: redactedMessages,
+			CostUSD:          cost,
+			Tools:            toolNames(req.Tools),
+			ToolCalls:        calledTools,
+			Attempts:         attempts,
+			LatencyMS:        time.Since(start).Milliseconds(),
 		})
+	}
+
+	policyIn := policyInput(principal, model, modelReq)
+	if !enrolled {
+		policyIn.Alias = alias
+	}
+	decision := s.policy.Evaluate(policyIn)
+	if !decision.Allowed {
+		status = "denied_policy"
+		record(decision.Reason, 0, nil)
 		track(0, 0, 0)
 		return contracts.CompletionResponse{}, &AppError{Code: "policy_denied", Message: decision.Reason, HTTPStatus: http.StatusForbidden}
 	}
 
-	if allowed := s.limiter.Allow(principal.Team, principal.RequestsPerMinute, time.Now()); !allowed {
+	inputTokens := approxInputTokens(req)
+	if appErr := s.checkModelFit(model, modelReq, inputTokens, sink != nil); appErr != nil {
+		status = "bad_request"
+		record(appErr.Code, 0, nil)
+		track(0, 0, 0)
+		return contracts.CompletionResponse{}, appErr
+	}
+
+	if mode != serveAdmitted && !s.limiter.Allow(principal.Team, principal.RequestsPerMinute, time.Now()) {
 		status = "rate_limited"
-		s.audit.Add(audit.Event{
-			Timestamp:     time.Now().UTC(),
-			RequestID:     requestID,
-			Team:          principal.Team,
-			Model:         model,
-			Status:        status,
-			DenyReason:    "requests_per_minute_exceeded",
-			RedactedInput: redacted.Text,
-			LatencyMS:     time.Since(start).Milliseconds(),
-		})
+		record("requests_per_minute_exceeded", 0, nil)
 		track(0, 0, 0)
 		return contracts.CompletionResponse{}, &AppError{Code: "rate_limited", Message: "requests_per_minute_exceeded", HTTPStatus: http.StatusTooManyRequests}
 	}
 
-	inputTokens := billing.ApproxTokens(req.Input)
-	estimatedCost := s.billing.EstimateCost(model, inputTokens, 120)
+	estimatedCost := s.billing.EstimateCost(model, inputTokens, outputEstimate(modelReq))
 	if !s.billing.CanAfford(principal.Team, principal.MonthlyBudgetUSD, estimatedCost) {
 		status = "budget_exceeded"
-		s.audit.Add(audit.Event{
-			Timestamp:     time.Now().UTC(),
-			RequestID:     requestID,
-			Team:          principal.Team,
-			Model:         model,
-			Status:        status,
-			DenyReason:    "estimated_cost_exceeds_budget",
-			RedactedInput: redacted.Text,
-			LatencyMS:     time.Since(start).Milliseconds(),
-		})
+		record("estimated_cost_exceeds_budget", 0, nil)
 		track(0, 0, 0)
 		return contracts.CompletionResponse{}, &AppError{Code: "budget_exceeded", Message: "estimated_cost_exceeds_budget", HTTPStatus: http.StatusPaymentRequired}
 	}
+	if mode == admitOnly {
+		return contracts.CompletionResponse{}, nil
+	}
 
-	output, err := s.modelClient.Complete(ctx, model, req.Input)
+	var result ModelResponse
+	var usedModel string
+	var attempts []audit.Attempt
+	var err error
+	if format != nil {
+		result, usedModel, attempts, err = s.completeStructured(ctx, principal, modelReq, format, inputTokens)
+	} else {
+		result, usedModel, attempts, err = s.completeWithFallback(ctx, principal, modelReq, inputTokens, sink)
+	}
 	if err != nil {
+		upstreamErr := asUpstreamError(err)
 		status = "upstream_error"
-		s.logger.Error("model completion failed", "request_id", requestID, "team", principal.Team, "err", err)
-		s.audit.Add(audit.Event{
-			Timestamp:     time.Now().UTC(),
-			RequestID:     requestID,
-			Team:          principal.Team,
-			Model:         model,
-			Status:        status,
-			DenyReason:    "upstream_completion_failed",
-			RedactedInput: redacted.Text,
-			LatencyMS:     time.Since(start).Milliseconds(),
-		})
-		track(inputTokens, 0, 0)
-		return contracts.CompletionResponse{}, &AppError{Code: "upstream_error", Message: "upstream_completion_failed", HTTPStatus: http.StatusBadGateway}
+		if upstreamErr.Code == CodeSchemaValidationFailed {
+			status = CodeSchemaValidationFailed
+		}
+		if sink != nil && (sink.failed || ctx.Err() != nil) {
+			status = "client_disconnected"
+			upstreamErr = &AppError{Code: "client_disconnected", Message: "client disconnected during stream", HTTPStatus: statusClientClosedRequest}
+		}
+		// Streamed output that already reached the caller, and output of
+		// structured-output rounds that were rejected, is billed even though
+		// the request did not complete.
+		partialTokens, partialCost := 0, 0.0
+		switch {
+		case sink != nil && sink.sent:
+			model = usedModel
+			partialTokens = billing.ApproxTokens(sink.partial.String())
+		case result.OutputTokens > 0:
+			model = usedModel
+			inputTokens, partialTokens = result.InputTokens, result.OutputTokens
+		}
+		if partialTokens > 0 {
+			partialCost = s.billing.EstimateCost(model, inputTokens, partialTokens)
+			s.billing.Record(principal.Team, model, inputTokens, partialTokens, partialCost)
+		}
+		s.logger.Error("model completion failed", "request_id", requestID, "team", principal.Team, "code", upstreamErr.Code, "attempts", len(attempts), "err", err)
+		record(upstreamErr.Code, partialCost, attempts)
+		track(inputTokens, partialTokens, partialCost)
+		return contracts.CompletionResponse{}, upstreamErr
 	}
 
-	outputTokens := billing.ApproxTokens(output)
+	model = usedModel
+	output := result.Output
+	calledTools = toolCallNames(result.ToolCalls)
+	if result.InputTokens > 0 {
+		inputTokens = result.InputTokens
+	}
+	outputTokens := result.OutputTokens
+	if outputTokens == 0 {
+		outputTokens = billing.ApproxTokens(output)
+		for _, call := range result.ToolCalls {
+			outputTokens += billing.ApproxTokens(call.Name + " " + call.Arguments)
+		}
+	}
 	cost := s.billing.EstimateCost(model, inputTokens, outputTokens)
-	if !s.billing.CanAfford(principal.Team, principal.MonthlyBudgetUSD, cost) {
+	// A streamed response has already been delivered, so it is billed even
+	// when it overruns the remaining budget.
+	if sink == nil && !s.billing.CanAfford(principal.Team, principal.MonthlyBudgetUSD, cost) {
 		status = "budget_exceeded"
-		s.audit.Add(audit.Event{
-			Timestamp:     time.Now().UTC(),
-			RequestID:     requestID,
-			Team:          principal.Team,
-			Model:         model,
-			Status:        status,
-			DenyReason:    "actual_cost_exceeds_budget",
-			RedactedInput: redacted.Text,
-			LatencyMS:     time.Since(start).Milliseconds(),
-		})
+		record("actual_cost_exceeds_budget", 0, attempts)
 		track(inputTokens, outputTokens, 0)
 		return contracts.CompletionResponse{}, &AppError{Code: "budget_exceeded", Message: "actual_cost_exceeds_budget", HTTPStatus: http.StatusPaymentRequired}
 	}
 
 	s.billing.Record(principal.Team, model, inputTokens, outputTokens, cost)
-	s.audit.Add(audit.Event{
-		Timestamp:     time.Now().UTC(),
-		RequestID:     requestID,
-		Team:          principal.Team,
-		Model:         model,
-		Status:        status,
-		RedactedInput: redacted.Text,
-		CostUSD:       cost,
-		LatencyMS:     time.Since(start).Milliseconds(),
-	})
+	record("", cost, attempts)
 	track(inputTokens, outputTokens, cost)
+	s.mirror(principal, servedCompletion{
+		requestID:     requestID,
+		req:           modelReq,
+		inputTokens:   inputTokens,
+		redactedInput: redactedInput,
+		model:         model,
+		output:        comparableOutput(result),
+		latency:       time.Since(start),
+		cost:          cost,
+	})
 
 	return contracts.CompletionResponse{
-		RequestID:      requestID,
-		Team:           principal.Team,
-		Model:          model,
-		Output:         output,
-		InputTokens:    inputTokens,
-		OutputTokens:   outputTokens,
-		CostUSD:        cost,
-		PolicyDecision: "allow",
-		ProcessedAt:    time.Now().UTC(),
+		RequestID:       requestID,
+		Team:            principal.Team,
+		Model:           model,
+		RequestedModel:  requestedModel,
+		Alias:           alias,
+		SelectionReason: selectionReason,
+		Experiment:      arm.Experiment,
+		Variant:         arm.Variant,
+		Output:          output,
+		ToolCalls:       result.ToolCalls,
+		InputTokens:     inputTokens,
+		OutputTokens:    outputTokens,
+		CostUSD:         cost + extraCost(attempts),
+		PolicyDecision:  "allow",
+		ProcessedAt:     time.Now().UTC(),
 	}, nil
 }
 
 func (s *Service) Usage(principal auth.Principal) contracts.UsageResponse {
 	u := s.billing.GetUsage(principal.Team)
+	shadowUsage := s.billing.GetShadowUsage(principal.Team)
 	remaining := s.billing.RemainingBudget(principal.Team, principal.MonthlyBudgetUSD)
 	return contracts.UsageResponse{
 		Team:               principal.Team,
@@ -240,32 +467,132 @@ func (s *Service) Usage(principal auth.Principal) contracts.UsageResponse {
 		MonthlyBudgetUSD:   principal.MonthlyBudgetUSD,
 		RemainingBudgetUSD: remaining,
 		PerModel:           u.PerModelCostUSD,
+		ShadowRequests:     shadowUsage.TotalRequests,
+		ShadowCostUSD:      shadowUsage.TotalCostUSD,
 	}
 }
 
+// Readiness reports provider health. The gateway is ready while at least one
+// probed provider is healthy, or when none has been probed.
+func (s *Service) Readiness() contracts.ReadinessResponse {
+	resp := contracts.ReadinessResponse{Status: "ready", Providers: []contracts.ProviderHealthView{}}
+	reporter, ok := s.modelClient.(healthReporter)
+	if !ok {
+		return resp
+	}
+	probed, healthy := 0, 0
+	for _, h := range reporter.ProviderHealth() {
+		view := contracts.ProviderHealthView{
+			Provider:            h.Provider,
+			Status:              h.Status,
+			LatencyMS:           h.LatencyMS,
+			ConsecutiveFailures: h.ConsecutiveFailures,
+			LastError:           h.LastError,
+		}
+		if !h.LastCheckedAt.IsZero() {
+			checked := h.LastCheckedAt.UTC()
+			view.LastCheckedAt = &checked
+		}
+		resp.Providers = append(resp.Providers, view)
+		if h.Status != HealthUnknown {
+			probed++
+		}
+		if h.Status == HealthHealthy {
+			healthy++
+		}
+	}
+	if probed > 0 && healthy == 0 {
+		resp.Status = "not_ready"
+	}
+	return resp
+}
+
 func (s *Service) AuditEvents(principal auth.Principal, limit int) []contracts.AuditEventView {
 	events := s.audit.List(principal.Team, limit)
 	out := make([]contracts.AuditEventView, 0, len(events))
 	for _, ev := range events {
 		out = append(out, contracts.AuditEventView{
-			Timestamp:     ev.Timestamp,
-			RequestID:     ev.RequestID,
-			Team:          ev.Team,
-			Model:         ev.Model,
-			Status:        ev.Status,
-			DenyReason:    ev.DenyReason,
-			RedactedInput: ev.RedactedInput,
-			CostUSD:       ev.CostUSD,
-			LatencyMS:     ev.LatencyMS,
+			Timestamp:        ev.Timestamp,
+			RequestID:        ev.RequestID,
+			Team:             ev.Team,
+			Operation:        ev.Operation,
+			Model:            ev.Model,
+			RequestedModel:   ev.RequestedModel,
+			Alias:            ev.Alias,
+			SelectionReason:  ev.SelectionReason,
+			Experiment:       ev.Experiment,
+			Variant:          ev.Variant,
+			Status:           ev.Status,
+			DenyReason:       ev.DenyReason,
+			RedactedInput:    ev.RedactedInput,
+			RedactedMessages: messageViews(ev.RedactedMessages),
+			CostUSD:          ev.CostUSD,
+			LatencyMS:        ev.LatencyMS,
+			Tools:            ev.Tools,
+			ToolCalls:        ev.ToolCalls,
+			Attempts:         attemptViews(ev.Attempts),
 		})
 	}
 	return out
 }
 
+func attemptViews(attempts []audit.Attempt) []contracts.AuditAttemptView {
+	if len(attempts) == 0 {
+		return nil
+	}
+	out := make([]contracts.AuditAttemptView, 0, len(attempts))
+	for _, a := range attempts {
+		out = append(out, contracts.AuditAttemptView{Model: a.Model, Status: a.Status, Reason: a.Reason, LatencyMS: a.LatencyMS, CostUSD: a.CostUSD})
+	}
+	return out
+}
+
+func messageViews(msgs []audit.Message) []contracts.Message {
+	if len(msgs) == 0 {
+		return nil
+	}
+	out := make([]contracts.Message, 0, len(msgs))
+	for _, m := range msgs {
+		out = append(out, contracts.Message{Role: m.Role, Content: m.Content})
+	}
+	return out
+}
+
 func (e *AppError) WithRequestID(requestID string) contracts.ErrorResponse {
 	return contracts.ErrorResponse{Error: e.Message, Code: e.Code, RequestID: requestID}
 }
 
+// deltaSink forwards streamed chunks to the caller and remembers what was
+// delivered, so partial output can be billed if the stream breaks. model is
+// the model currently being streamed.
+type deltaSink struct {
+	emit    func(model, delta string) error
+	model   string
+	partial strings.Builder
+	sent    bool
+	failed  bool
+}
+
+func (d *deltaSink) write(delta string) error {
+	if err := d.emit(d.model, delta); err != nil {
+		d.failed = true
+		return err
+	}
+	d.sent = true
+	d.partial.WriteString(delta)
+	return nil
+}
+
+// asUpstreamError keeps typed provider errors and wraps anything else in a
+// generic upstream_error.
+func asUpstreamError(err error) *AppError {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return &AppError{Code: "upstream_error", Message: "upstream_completion_failed", HTTPStatus: http.StatusBadGateway}
+}
+
 func NewInternalError(err error) *AppError {
 	return &AppError{
 		Code:       "internal_error",
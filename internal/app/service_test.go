@@ -0,0 +1,82 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/auth"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// failingModels fails every call for the listed models with the given error.
+type failingModels map[string]*AppError
+
+func (f failingModels) Complete(ctx context.Context, req ModelRequest) (ModelResponse, error) {
+	if err, ok := f[req.Model]; ok {
+		return ModelResponse{}, err
+	}
+	return SimulatedModelClient{}.Complete(ctx, req)
+}
+
+func newTestService(t *testing.T, cfg config.Config, client ModelClient) (*Service, auth.Principal) {
+	t.Helper()
+	svc := NewService(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), NewMetrics(prometheus.NewRegistry()), client)
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-API-Key", cfg.Teams[0].APIKey)
+	principal, appErr := svc.Authenticate(r)
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	return svc, principal
+}
+
+func TestHandleCompletionStreamDeliversDeltasAndBills(t *testing.T) {
+	svc, principal := newTestService(t, config.Default(), SimulatedModelClient{})
+
+	var deltas []string
+	resp, appErr := svc.HandleCompletionStream(context.Background(), "req-1", principal,
+		contracts.CompletionRequest{Model: "gpt-4o-mini", Input: "check the login burst"},
+		func(_, d string) error {
+			deltas = append(deltas, d)
+			return nil
+		})
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	if len(deltas) < 2 {
+		t.Fatalf("expected several deltas, got %d", len(deltas))
+	}
+	if got := svc.Usage(principal); got.TotalRequests != 1 || got.TotalOutputTokens != int64(resp.OutputTokens) {
+		t.Fatalf("unexpected usage after stream: %+v", got)
+	}
+}
+
+func TestHandleCompletionStreamBillsPartialOutputOnDisconnect(t *testing.T) {
+	svc, principal := newTestService(t, config.Default(), SimulatedModelClient{})
+
+	sent := 0
+	_, appErr := svc.HandleCompletionStream(context.Background(), "req-1", principal,
+		contracts.CompletionRequest{Model: "gpt-4o-mini", Input: "check the login burst"},
+		func(_, _ string) error {
+			if sent == 2 {
+				return errors.New("broken pipe")
+			}
+			sent++
+			return nil
+		})
+	if appErr == nil || appErr.Code != "client_disconnected" {
+		t.Fatalf("expected client_disconnected, got %v", appErr)
+	}
+	if got := svc.Usage(principal); got.TotalRequests != 1 || got.TotalOutputTokens == 0 {
+		t.Fatalf("expected partial output to be billed, got %+v", got)
+	}
+	if ev := svc.AuditEvents(principal, 1)[0]; ev.Status != "client_disconnected" || ev.CostUSD <= 0 {
+		t.Fatalf("unexpected audit event: %+v", ev)
+	}
+}
@@ -0,0 +1,151 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/auth"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/billing"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/redaction"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/shadow"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+const (
+	// maxShadowCalls bounds the shadow calls in flight; completions served
+	// while it is reached are not mirrored.
+	maxShadowCalls = 32
+	// shadowTimeout bounds a single shadow call.
+	shadowTimeout = 60 * time.Second
+)
+
+// Shadow call outcomes reported in gateway_shadow_requests_total.
+const (
+	shadowOK      = "ok"
+	shadowFailed  = "failed"
+	shadowDropped = "dropped"
+)
+
+// servedCompletion is a completion served to the caller, as mirrored to the
+// team's shadow model.
+type servedCompletion struct {
+	requestID     string
+	req           ModelRequest
+	inputTokens   int
+	redactedInput string
+	model         string
+	output        string
+	latency       time.Duration
+	cost          float64
+}
+
+// mirror sends a sample of the team's served completions to its shadow model
+// in the background. It never blocks: when maxShadowCalls are in flight the
+// completion is dropped from the sample.
+func (s *Service) mirror(principal auth.Principal, served servedCompletion) {
+	cfg, ok := s.shadowing[principal.Team]
+	if !ok || s.sample()*100 >= cfg.Percent {
+		return
+	}
+	select {
+	case s.shadowSlots <- struct{}{}:
+	default:
+		s.metrics.ShadowRequests.WithLabelValues(principal.Team, cfg.Model, shadowDropped).Inc()
+		return
+	}
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		defer func() { <-s.shadowSlots }()
+		s.runShadow(principal.Team, cfg.Model, served)
+	}()
+}
+
+// runShadow calls model with the served request, bills the call to the
+// shadow ledger and stores the comparison pair. The call bypasses retries,
+// circuit breakers and balancer ejection where the client allows it, so a
+// failing shadow model cannot take capacity away from live traffic.
+func (s *Service) runShadow(team, model string, served servedCompletion) {
+	ctx, cancel := context.WithTimeout(context.Background(), shadowTimeout)
+	defer cancel()
+
+	req := served.req
+	req.Model = model
+	started := time.Now()
+	var resp ModelResponse
+	var err error
+	if direct, ok := s.modelClient.(directCompleter); ok {
+		resp, err = direct.CompleteDirect(ctx, req)
+	} else {
+		resp, err = s.modelClient.Complete(ctx, req)
+	}
+	pair := shadow.Pair{
+		Timestamp:        time.Now().UTC(),
+		RequestID:        served.requestID,
+		Team:             team,
+		PrimaryModel:     served.model,
+		ShadowModel:      model,
+		RedactedInput:    served.redactedInput,
+		PrimaryOutput:    redaction.Scrub(served.output).Text,
+		PrimaryLatencyMS: served.latency.Milliseconds(),
+		ShadowLatencyMS:  time.Since(started).Milliseconds(),
+		PrimaryCostUSD:   served.cost,
+	}
+	outcome := shadowOK
+	if err != nil {
+		outcome = shadowFailed
+		pair.ShadowError = asUpstreamError(err).Code
+	} else {
+		output := comparableOutput(resp)
+		inputTokens, outputTokens := served.inputTokens, resp.OutputTokens
+		if resp.InputTokens > 0 {
+			inputTokens = resp.InputTokens
+		}
+		if outputTokens == 0 {
+			outputTokens = billing.ApproxTokens(output)
+		}
+		pair.ShadowOutput = redaction.Scrub(output).Text
+		pair.ShadowCostUSD = s.billing.EstimateCost(model, inputTokens, outputTokens)
+		s.billing.RecordShadow(team, model, inputTokens, outputTokens, pair.ShadowCostUSD)
+	}
+	s.metrics.ShadowRequests.WithLabelValues(team, model, outcome).Inc()
+	if err := s.shadows.Add(pair); err != nil {
+		s.logger.Warn("shadow pair not written", "request_id", served.requestID, "err", err)
+	}
+}
+
+// comparableOutput renders a response's text and tool calls as one string.
+func comparableOutput(resp ModelResponse) string {
+	parts := []string{resp.Output}
+	for _, call := range resp.ToolCalls {
+		parts = append(parts, "tool_call "+call.Name+" "+call.Arguments)
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n"))
+}
+
+// ShadowPairs returns the team's most recent shadow comparison pairs.
+func (s *Service) ShadowPairs(principal auth.Principal, limit int) []contracts.ShadowPairView {
+	pairs := s.shadows.List(principal.Team, limit)
+	out := make([]contracts.ShadowPairView, 0, len(pairs))
+	for _, p := range pairs {
+		out = append(out, contracts.ShadowPairView(p))
+	}
+	return out
+}
+
+// Shutdown waits for background work such as shadow calls to finish, or for
+// ctx to be done, and then releases the service's files.
+func (s *Service) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return s.shadows.Close()
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestShadowTrafficIsComparedAndBilledSeparately(t *testing.T) {
+	cfg := config.Default()
+	cfg.Teams[0].Shadow = &config.ShadowConfig{Model: "gpt-4.1-mini", Percent: 50}
+	svc, principal := newTestService(t, cfg, SimulatedModelClient{})
+	ctx := context.Background()
+
+	svc.sample = func() float64 { return 0.7 }
+	if _, appErr := svc.HandleCompletion(ctx, "req-skipped", principal, contracts.CompletionRequest{Model: "gpt-4o-mini", Input: "hi"}); appErr != nil {
+		t.Fatal(appErr)
+	}
+	svc.sample = func() float64 { return 0.2 }
+	resp, appErr := svc.HandleCompletion(ctx, "req-1", principal, contracts.CompletionRequest{Model: "gpt-4o-mini", Input: "mail alice@example.com"})
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	if resp.Model != "gpt-4o-mini" {
+		t.Fatalf("expected the caller to get the primary model's answer, got %+v", resp)
+	}
+	if err := svc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	pairs := svc.ShadowPairs(principal, 10)
+	if len(pairs) != 1 {
+		t.Fatalf("expected only the sampled completion to be mirrored, got %+v", pairs)
+	}
+	p := pairs[0]
+	if p.RequestID != "req-1" || p.ShadowModel != "gpt-4.1-mini" || p.ShadowOutput == "" || p.ShadowCostUSD <= 0 || p.RedactedInput == "mail alice@example.com" {
+		t.Fatalf("unexpected shadow pair: %+v", p)
+	}
+
+	usage := svc.Usage(principal)
+	if usage.TotalRequests != 2 || usage.ShadowRequests != 1 || usage.ShadowCostUSD != p.ShadowCostUSD {
+		t.Fatalf("expected shadow usage apart from the team's, got %+v", usage)
+	}
+	if _, ok := usage.PerModel["gpt-4.1-mini"]; ok {
+		t.Fatalf("expected shadow cost to stay off the team's ledger, got %+v", usage.PerModel)
+	}
+}
+
+func TestShadowFailuresLeavePrimaryBreakerClosed(t *testing.T) {
+	cfg := config.Default()
+	cfg.Providers = []config.ProviderConfig{{Name: "sim", Type: "simulated", Scenario: &config.SimulationScenario{
+		Models: map[string]config.SimulatedBehavior{"gpt-4.1-mini": {ErrorRate: 1, Errors: []string{"unavailable"}}},
+	}}}
+	cfg.ModelRoutes = map[string]string{"gpt-*": "sim"}
+	cfg.CircuitBreaker = config.CircuitBreakerConfig{FailureRatio: 0.5, MinRequests: 1, CooldownMS: 60000}
+	cfg.Retry = config.RetryConfig{MaxAttempts: 3}
+	cfg.Teams[0].Shadow = &config.ShadowConfig{Model: "gpt-4.1-mini", Percent: 100}
+	router, err := NewRouter(cfg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, principal := newTestService(t, cfg, NewRetryingClient(router, cfg.Retry, nil))
+	ctx := context.Background()
+
+	for i := range 3 {
+		if _, appErr := svc.HandleCompletion(ctx, "req", principal, contracts.CompletionRequest{Model: "gpt-4o-mini", Input: "hi"}); appErr != nil {
+			t.Fatalf("request %d: expected the primary model to stay available, got %v", i, appErr)
+		}
+		if err := svc.Shutdown(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pairs := svc.ShadowPairs(principal, 10)
+	if len(pairs) != 3 || pairs[0].ShadowError == "" {
+		t.Fatalf("expected failed shadow pairs, got %+v", pairs)
+	}
+	if state := router.backends["sim"].(*healthGate).next.(*breakerClient).breaker.State(); state != BreakerClosed {
+		t.Fatalf("expected the breaker to stay closed, got %s", state)
+	}
+}
+
+func TestUnroutedShadowModelIsDisabledAtStartup(t *testing.T) {
+	cfg := config.Default()
+	cfg.Teams[0].Shadow = &config.ShadowConfig{Model: "gpt-4.1-mnii", Percent: 100}
+	router, err := newRouter(map[string]ModelClient{"sim": SimulatedModelClient{}}, map[string]string{"gpt-4o-mini": "sim", "gpt-4.1-mini": "sim"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logs bytes.Buffer
+	svc := NewService(cfg, slog.New(slog.NewTextHandler(&logs, nil)), NewMetrics(prometheus.NewRegistry()), router)
+
+	if _, ok := svc.shadowing[cfg.Teams[0].Name]; ok {
+		t.Fatal("expected the unrouted shadow model to be disabled")
+	}
+	if !strings.Contains(logs.String(), "gpt-4.1-mnii") {
+		t.Fatalf("expected a warning naming the shadow model, got %q", logs.String())
+	}
+}
@@ -0,0 +1,270 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/billing"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+)
+
+// Fault kinds a simulation scenario can inject.
+const (
+	FaultNone            = "ok"
+	FaultTimeout         = "timeout"
+	FaultRateLimited     = "rate_limited"
+	FaultUnavailable     = "unavailable"
+	FaultBadRequest      = "bad_request"
+	FaultAuthFailed      = "auth_failed"
+	FaultUnreachable     = "unreachable"
+	FaultInvalidResponse = "invalid_response"
+)
+
+var faultKinds = map[string]bool{
+	FaultNone: true, FaultTimeout: true, FaultRateLimited: true, FaultUnavailable: true,
+	FaultBadRequest: true, FaultAuthFailed: true, FaultUnreachable: true, FaultInvalidResponse: true,
+}
+
+var (
+	errSimulatedConnection = errors.New("simulated connection failure")
+	errSimulatedBody       = errors.New("simulated malformed body")
+)
+
+// fillerText pads simulated answers up to a scenario's OutputTokens.
+const fillerText = " the quick brown fox jumps over the lazy dog"
+
+// ScenarioClient is a SimulatedModelClient that follows a SimulationScenario:
+// it delays calls, fails them with upstream-shaped errors and pads or slowly
+// streams answers. Unary calls are bounded by the provider timeout like real
+// providers, so latencies beyond it surface as upstream_timeout.
+type ScenarioClient struct {
+	timeout time.Duration
+
+	mu        sync.Mutex
+	rng       *rand.Rand
+	exact     map[string]*scriptedModel
+	prefixes  []prefixBehavior
+	byDefault *scriptedModel
+}
+
+// scriptedModel is one scenario entry and its position in Script, which is
+// shared by every model the entry matches.
+type scriptedModel struct {
+	config.SimulatedBehavior
+	next int
+}
+
+type prefixBehavior struct {
+	prefix string
+	model  *scriptedModel
+}
+
+// NewScenarioClient validates p.Scenario and builds its client.
+func NewScenarioClient(p config.ProviderConfig) (*ScenarioClient, error) {
+	seed := p.Scenario.Seed
+	c := &ScenarioClient{
+		timeout: providerTimeout(p),
+		rng:     rand.New(rand.NewPCG(seed, seed)),
+		exact:   make(map[string]*scriptedModel),
+	}
+	for pattern, b := range p.Scenario.Models {
+		if err := validateBehavior(b); err != nil {
+			return nil, fmt.Errorf("scenario model %q: %w", pattern, err)
+		}
+		m := &scriptedModel{SimulatedBehavior: b}
+		switch prefix, ok := strings.CutSuffix(pattern, "*"); {
+		case pattern == "*":
+			c.byDefault = m
+		case ok:
+			c.prefixes = append(c.prefixes, prefixBehavior{prefix: prefix, model: m})
+		default:
+			c.exact[pattern] = m
+		}
+	}
+	// Longest prefix first, as for routes.
+	sort.Slice(c.prefixes, func(i, j int) bool {
+		if len(c.prefixes[i].prefix) != len(c.prefixes[j].prefix) {
+			return len(c.prefixes[i].prefix) > len(c.prefixes[j].prefix)
+		}
+		return c.prefixes[i].prefix < c.prefixes[j].prefix
+	})
+	return c, nil
+}
+
+func validateBehavior(b config.SimulatedBehavior) error {
+	if b.MinLatencyMS < 0 || b.MaxLatencyMS < 0 {
+		return errors.New("latencies must not be negative")
+	}
+	if b.MaxLatencyMS != 0 && b.MaxLatencyMS < b.MinLatencyMS {
+		return errors.New("max_latency_ms is below min_latency_ms")
+	}
+	if b.ErrorRate < 0 || b.ErrorRate > 1 {
+		return fmt.Errorf("error_rate %v is outside [0, 1]", b.ErrorRate)
+	}
+	for _, kind := range slices.Concat(b.Errors, b.Script) {
+		if !faultKinds[kind] {
+			return fmt.Errorf("unknown fault %q", kind)
+		}
+	}
+	return nil
+}
+
+// plan draws the latency and fault of the next call to model.
+func (c *ScenarioClient) plan(model string) (time.Duration, string, config.SimulatedBehavior) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m := c.lookup(model)
+	if m == nil {
+		return 0, FaultNone, config.SimulatedBehavior{}
+	}
+	latency := m.MinLatencyMS
+	if m.MaxLatencyMS > m.MinLatencyMS {
+		latency += c.rng.IntN(m.MaxLatencyMS - m.MinLatencyMS + 1)
+	}
+	fault := FaultNone
+	switch {
+	case m.next < len(m.Script):
+		fault = m.Script[m.next]
+		m.next++
+	case m.ErrorRate > 0 && c.rng.Float64() < m.ErrorRate:
+		fault = FaultUnavailable
+		if len(m.Errors) > 0 {
+			fault = m.Errors[c.rng.IntN(len(m.Errors))]
+		}
+	}
+	return time.Duration(latency) * time.Millisecond, fault, m.SimulatedBehavior
+}
+
+func (c *ScenarioClient) lookup(model string) *scriptedModel {
+	if m, ok := c.exact[model]; ok {
+		return m
+	}
+	for _, p := range c.prefixes {
+		if strings.HasPrefix(model, p.prefix) {
+			return p.model
+		}
+	}
+	return c.byDefault
+}
+
+// begin waits out the planned latency and returns the planned fault, if any.
+// ctx should carry the call's deadline.
+func (c *ScenarioClient) begin(ctx context.Context, model string) (config.SimulatedBehavior, error) {
+	latency, fault, b := c.plan(model)
+	if fault == FaultTimeout {
+		// A hung upstream: only the deadline ends the call.
+		<-ctx.Done()
+		return b, upstreamTransportError("simulated", ctx.Err())
+	}
+	if err := sleepContext(ctx, latency); err != nil {
+		return b, upstreamTransportError("simulated", err)
+	}
+	return b, simulatedFault(fault, b)
+}
+
+func simulatedFault(kind string, b config.SimulatedBehavior) error {
+	switch kind {
+	case FaultRateLimited:
+		err := upstreamStatusError("simulated", http.StatusTooManyRequests, "simulated rate limit")
+		err.RetryAfter = time.Duration(b.RetryAfterMS) * time.Millisecond
+		return err
+	case FaultUnavailable:
+		return upstreamStatusError("simulated", http.StatusServiceUnavailable, "simulated outage")
+	case FaultBadRequest:
+		return upstreamStatusError("simulated", http.StatusBadRequest, "simulated bad request")
+	case FaultAuthFailed:
+		return upstreamStatusError("simulated", http.StatusUnauthorized, "simulated invalid key")
+	case FaultUnreachable:
+		return upstreamTransportError("simulated", errSimulatedConnection)
+	case FaultInvalidResponse:
+		return upstreamDecodeError("simulated", errSimulatedBody)
+	}
+	return nil
+}
+
+func (c *ScenarioClient) Complete(ctx context.Context, req ModelRequest) (ModelResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	b, err := c.begin(ctx, req.Model)
+	if err != nil {
+		return ModelResponse{}, err
+	}
+	return answer(ctx, req, b)
+}
+
+// Stream waits and fails like Complete before the first chunk, then emits
+// the answer word by word, pausing StreamChunkDelayMS between words.
+func (c *ScenarioClient) Stream(ctx context.Context, req ModelRequest, onDelta func(string) error) (ModelResponse, error) {
+	startCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	b, err := c.begin(startCtx, req.Model)
+	cancel()
+	if err != nil {
+		return ModelResponse{}, err
+	}
+	resp, err := answer(ctx, req, b)
+	if err != nil {
+		return ModelResponse{}, err
+	}
+	delay := time.Duration(b.StreamChunkDelayMS) * time.Millisecond
+	for i, w := range strings.SplitAfter(resp.Output, " ") {
+		if i > 0 {
+			if err := sleepContext(ctx, delay); err != nil {
+				return ModelResponse{}, upstreamTransportError("simulated", err)
+			}
+		}
+		if err := onDelta(w); err != nil {
+			return ModelResponse{}, err
+		}
+	}
+	return resp, nil
+}
+
+func (c *ScenarioClient) Embed(ctx context.Context, req EmbeddingRequest) (EmbeddingResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	if _, err := c.begin(ctx, req.Model); err != nil {
+		return EmbeddingResponse{}, err
+	}
+	return SimulatedModelClient{}.Embed(ctx, req)
+}
+
+// Probe always succeeds; scenarios fault model calls, not health checks.
+func (c *ScenarioClient) Probe(context.Context) error { return nil }
+
+// answer is the simulated answer padded to the behavior's OutputTokens and
+// then limited by req's max_tokens and stop sequences; tool calls and
+// structured output are left as they are.
+func answer(ctx context.Context, req ModelRequest, b config.SimulatedBehavior) (ModelResponse, error) {
+	resp, err := SimulatedModelClient{}.Complete(ctx, req)
+	if err != nil {
+		return ModelResponse{}, err
+	}
+	if len(resp.ToolCalls) == 0 && req.ResponseFormat == nil {
+		resp.Output = limitOutput(padOutput(resp.Output, b.OutputTokens), req)
+	}
+	return resp, nil
+}
+
+// padOutput appends filler until output is about tokens tokens long.
+func padOutput(output string, tokens int) string {
+	missing := tokens - billing.ApproxTokens(output)
+	if missing <= 0 {
+		return output
+	}
+	var b strings.Builder
+	b.WriteString(output)
+	for b.Len() < len(output)+4*missing {
+		b.WriteString(fillerText)
+	}
+	return b.String()
+}
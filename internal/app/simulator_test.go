@@ -0,0 +1,117 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/billing"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+)
+
+func scenarioClient(t *testing.T, timeoutMS int, scenario config.SimulationScenario) *ScenarioClient {
+	t.Helper()
+	c, err := NewScenarioClient(config.ProviderConfig{Type: "simulated", TimeoutMS: timeoutMS, Scenario: &scenario})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// outcomes returns the error code (or "ok") of n calls to model.
+func outcomes(c ModelClient, model string, n int) []string {
+	var out []string
+	for range n {
+		_, err := c.Complete(context.Background(), ModelRequest{Model: model, Messages: userTurn("hi")})
+		if err != nil {
+			out = append(out, asUpstreamError(err).Code)
+			continue
+		}
+		out = append(out, FaultNone)
+	}
+	return out
+}
+
+func TestScenarioClientIsDeterministicForASeed(t *testing.T) {
+	scenario := config.SimulationScenario{Seed: 42, Models: map[string]config.SimulatedBehavior{
+		"*": {ErrorRate: 0.5, Errors: []string{FaultRateLimited, FaultUnavailable, FaultUnreachable}},
+	}}
+	first := outcomes(scenarioClient(t, 0, scenario), "gpt-4o-mini", 40)
+	second := outcomes(scenarioClient(t, 0, scenario), "gpt-4o-mini", 40)
+	if strings.Join(first, ",") != strings.Join(second, ",") {
+		t.Fatalf("expected identical runs:\n%v\n%v", first, second)
+	}
+	kinds := make(map[string]bool)
+	for _, o := range first {
+		kinds[o] = true
+	}
+	if len(kinds) != 4 {
+		t.Fatalf("expected successes and all three faults, got %v", first)
+	}
+}
+
+func TestScenarioClientFollowsScriptPerEntry(t *testing.T) {
+	c := scenarioClient(t, 0, config.SimulationScenario{Models: map[string]config.SimulatedBehavior{
+		"gpt-*":       {Script: []string{FaultUnavailable, FaultInvalidResponse, FaultNone, FaultBadRequest}},
+		"gpt-4o-mini": {Script: []string{FaultAuthFailed}},
+	}})
+	got := outcomes(c, "gpt-4.1-mini", 5)
+	want := []string{CodeUpstreamUnavailable, CodeUpstreamInvalidResponse, FaultNone, CodeUpstreamBadRequest, FaultNone}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got := outcomes(c, "gpt-4o-mini", 2); got[0] != CodeUpstreamAuthFailed || got[1] != FaultNone {
+		t.Fatalf("expected the exact entry to win over the prefix, got %v", got)
+	}
+	if got := outcomes(c, "claude-3-5-sonnet", 1); got[0] != FaultNone {
+		t.Fatalf("expected unmatched models to succeed, got %v", got)
+	}
+}
+
+func TestScenarioClientTimesOutAndDelays(t *testing.T) {
+	c := scenarioClient(t, 30, config.SimulationScenario{Models: map[string]config.SimulatedBehavior{
+		"hung": {Script: []string{FaultTimeout}},
+		"slow": {MinLatencyMS: 60, MaxLatencyMS: 80},
+		"ok":   {MinLatencyMS: 10, MaxLatencyMS: 10},
+	}})
+	for _, model := range []string{"hung", "slow"} {
+		if got := outcomes(c, model, 1); got[0] != CodeUpstreamTimeout {
+			t.Fatalf("expected %s to time out, got %v", model, got)
+		}
+	}
+	started := time.Now()
+	if got := outcomes(c, "ok", 1); got[0] != FaultNone || time.Since(started) < 10*time.Millisecond {
+		t.Fatalf("expected a delayed success, got %v after %v", got, time.Since(started))
+	}
+}
+
+func TestScenarioClientPadsAndSlowlyStreamsOutput(t *testing.T) {
+	c := scenarioClient(t, 0, config.SimulationScenario{Models: map[string]config.SimulatedBehavior{
+		"*": {OutputTokens: 200, StreamChunkDelayMS: 1},
+	}})
+	var chunks int
+	started := time.Now()
+	resp, err := c.Stream(context.Background(), ModelRequest{Model: "gpt-4o-mini", Messages: userTurn("hi")}, func(string) error {
+		chunks++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := billing.ApproxTokens(resp.Output); n < 200 || n > 220 {
+		t.Fatalf("expected about 200 output tokens, got %d", n)
+	}
+	if chunks < 50 || time.Since(started) < time.Duration(chunks-1)*time.Millisecond {
+		t.Fatalf("expected a slow word-by-word stream, got %d chunks in %v", chunks, time.Since(started))
+	}
+}
+
+func TestNewModelClientRejectsInvalidScenario(t *testing.T) {
+	_, err := NewModelClient(config.ProviderConfig{Type: "simulated", Scenario: &config.SimulationScenario{
+		Models: map[string]config.SimulatedBehavior{"*": {Script: []string{"meltdown"}}},
+	}}, nil)
+	if err == nil || !strings.Contains(err.Error(), `unknown fault "meltdown"`) {
+		t.Fatalf("expected the unknown fault to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/audit"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/auth"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/billing"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/schema"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+// Response format types accepted in CompletionRequest.ResponseFormat.
+const (
+	FormatText       = "text"
+	FormatJSONObject = "json_object"
+	FormatJSONSchema = "json_schema"
+)
+
+// CodeSchemaValidationFailed is returned when the model's output still does
+// not match the requested response format after all re-asks.
+const CodeSchemaValidationFailed = "schema_validation_failed"
+
+// Structured output validation outcomes exported as metric labels.
+const (
+	validationOutcomeValid   = "valid"
+	validationOutcomeInvalid = "invalid"
+)
+
+// outputFormat is a validated response_format: schema is nil for
+// json_object, which only requires a JSON object.
+type outputFormat struct {
+	schema *schema.Schema
+	reasks int
+}
+
+// responseFormat validates the request's response_format and returns nil
+// when plain text was asked for.
+func (s *Service) responseFormat(req contracts.CompletionRequest, streaming bool) (*outputFormat, *AppError) {
+	rf := req.ResponseFormat
+	if rf == nil || rf.Type == "" || rf.Type == FormatText {
+		return nil, nil
+	}
+	if streaming {
+		return nil, &AppError{Code: "invalid_response_format", Message: "response_format cannot be combined with stream", HTTPStatus: http.StatusBadRequest}
+	}
+	format := &outputFormat{reasks: s.maxReasks}
+	if rf.MaxReasks != nil && *rf.MaxReasks >= 0 && *rf.MaxReasks < format.reasks {
+		format.reasks = *rf.MaxReasks
+	}
+	switch rf.Type {
+	case FormatJSONObject:
+	case FormatJSONSchema:
+		if len(rf.Schema) == 0 {
+			return nil, &AppError{Code: "invalid_response_format", Message: "json_schema response_format requires schema", HTTPStatus: http.StatusBadRequest}
+		}
+		compiled, err := schema.Compile(rf.Schema)
+		if err != nil {
+			return nil, &AppError{Code: "invalid_response_format", Message: "invalid schema: " + err.Error(), HTTPStatus: http.StatusBadRequest}
+		}
+		format.schema = compiled
+	default:
+		return nil, &AppError{Code: "invalid_response_format", Message: "unsupported response_format type: " + rf.Type, HTTPStatus: http.StatusBadRequest}
+	}
+	return format, nil
+}
+
+// check validates output and returns it normalized: surrounding whitespace
+// and a Markdown code fence, which models often add, are stripped.
+func (f *outputFormat) check(output string) (string, error) {
+	doc := stripCodeFence(output)
+	if f.schema != nil {
+		return doc, f.schema.ValidateJSON([]byte(doc))
+	}
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(doc), &obj); err != nil {
+		return doc, &schema.ValidationError{Path: "$", Message: "expected a JSON object"}
+	}
+	return doc, nil
+}
+
+func stripCodeFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") || !strings.HasSuffix(s, "```") || len(s) < 6 {
+		return s
+	}
+	body := strings.TrimSuffix(s[3:], "```")
+	if nl := strings.IndexByte(body, '\n'); nl >= 0 && !strings.ContainsAny(body[:nl], "{[\"") {
+		body = body[nl+1:]
+	}
+	return strings.TrimSpace(body)
+}
+
+// completeStructured runs the completion and validates its output, re-asking
+// the model with the validation error up to format.reasks times. The token
+// counts returned cover every round so that re-asks are billed; they are set
+// even when the call fails.
+func (s *Service) completeStructured(
+	ctx context.Context,
+	principal auth.Principal,
+	req ModelRequest,
+	format *outputFormat,
+	inputTokens int,
+) (ModelResponse, string, []audit.Attempt, error) {
+	var all []audit.Attempt
+	spentIn, spentOut := 0, 0
+	roundInput := inputTokens
+	for round := 0; ; round++ {
+		resp, used, attempts, err := s.completeWithFallback(ctx, principal, req, roundInput, nil)
+		all = append(all, attempts...)
+		if err != nil {
+			return ModelResponse{InputTokens: spentIn, OutputTokens: spentOut}, used, all, err
+		}
+		in, out := resp.InputTokens, resp.OutputTokens
+		if in == 0 {
+			in = roundInput
+		}
+		if out == 0 {
+			out = billing.ApproxTokens(resp.Output)
+		}
+		spentIn, spentOut = spentIn+in, spentOut+out
+		if len(resp.ToolCalls) > 0 {
+			resp.InputTokens, resp.OutputTokens = spentIn, spentOut
+			return resp, used, all, nil
+		}
+
+		doc, verr := format.check(resp.Output)
+		if verr == nil {
+			s.metrics.StructuredOutputChecks.WithLabelValues(used, validationOutcomeValid).Inc()
+			resp.Output, resp.InputTokens, resp.OutputTokens = doc, spentIn, spentOut
+			return resp, used, all, nil
+		}
+		s.metrics.StructuredOutputChecks.WithLabelValues(used, validationOutcomeInvalid).Inc()
+		if round >= format.reasks || ctx.Err() != nil {
+			return ModelResponse{InputTokens: spentIn, OutputTokens: spentOut}, used, all, schemaValidationError(verr, round+1)
+		}
+
+		correction := fmt.Sprintf("Your previous response did not match the required format (%v). Reply again with only the corrected JSON.", verr)
+		req.Model = used
+		req.Messages = append(slices.Clip(req.Messages),
+			contracts.Message{Role: RoleAssistant, Content: resp.Output},
+			contracts.Message{Role: RoleUser, Content: correction},
+		)
+		roundInput += billing.ApproxMessageTokens(resp.Output) + billing.ApproxMessageTokens(correction)
+	}
+}
+
+func schemaValidationError(err error, tries int) *AppError {
+	return &AppError{
+		Code:       CodeSchemaValidationFailed,
+		Message:    fmt.Sprintf("model output did not match response_format after %d attempt(s): %v", tries, err),
+		HTTPStatus: http.StatusBadGateway,
+	}
+}
+
+// formatInstruction describes the expected output for providers without a
+// native structured output mode.
+func formatInstruction(rf *contracts.ResponseFormat) string {
+	switch {
+	case rf == nil:
+		return ""
+	case rf.Type == FormatJSONObject:
+		return "Respond with only a JSON object, without any surrounding text."
+	case rf.Type == FormatJSONSchema:
+		return "Respond with only a JSON document, without any surrounding text, that matches this JSON Schema:\n" + string(rf.Schema)
+	}
+	return ""
+}
+
+// simulatedStructuredOutput returns a minimal document conforming to the
+// requested format, so the simulated backend honours response_format.
+func simulatedStructuredOutput(req ModelRequest) (string, bool) {
+	rf := req.ResponseFormat
+	if rf == nil {
+		return "", false
+	}
+	var doc any
+	switch rf.Type {
+	case FormatJSONObject:
+		doc = map[string]string{"summary": lastUserContent(req.Messages)}
+	case FormatJSONSchema:
+		compiled, err := schema.Compile(rf.Schema)
+		if err != nil {
+			return "", false
+		}
+		if doc, err = compiled.Example(); err != nil {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return "", false
+	}
+	return string(out), true
+}
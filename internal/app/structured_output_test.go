@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const verdictSchema = `{"type":"object","properties":{"verdict":{"type":"string","enum":["allow","deny"]}},"required":["verdict"],"additionalProperties":false}`
+
+// cannedOutputs answers with the queued outputs in order and records the
+// conversations it was sent.
+type cannedOutputs struct {
+	outputs []string
+	seen    [][]contracts.Message
+}
+
+func (c *cannedOutputs) Complete(_ context.Context, req ModelRequest) (ModelResponse, error) {
+	c.seen = append(c.seen, req.Messages)
+	out := c.outputs[0]
+	if len(c.outputs) > 1 {
+		c.outputs = c.outputs[1:]
+	}
+	return ModelResponse{Output: out, InputTokens: 10, OutputTokens: 5}, nil
+}
+
+func structuredRequest() contracts.CompletionRequest {
+	return contracts.CompletionRequest{
+		Model:          "gpt-4o-mini",
+		Input:          "should 10.0.0.8 be blocked?",
+		ResponseFormat: &contracts.ResponseFormat{Type: FormatJSONSchema, Schema: json.RawMessage(verdictSchema)},
+	}
+}
+
+func TestStructuredOutputSimulatedConforms(t *testing.T) {
+	svc, principal := newTestService(t, config.Default(), SimulatedModelClient{})
+	resp, appErr := svc.HandleCompletion(context.Background(), "req-1", principal, structuredRequest())
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	if resp.Output != `{"verdict":"allow"}` {
+		t.Fatalf("unexpected output: %s", resp.Output)
+	}
+	if got := testutil.ToFloat64(svc.metrics.StructuredOutputChecks.WithLabelValues("gpt-4o-mini", validationOutcomeValid)); got != 1 {
+		t.Fatalf("expected one valid check, got %v", got)
+	}
+}
+
+func TestStructuredOutputReasksWithValidationError(t *testing.T) {
+	client := &cannedOutputs{outputs: []string{`{"verdict":"maybe"}`, "```json\n{\"verdict\":\"deny\"}\n```"}}
+	svc, principal := newTestService(t, config.Default(), client)
+
+	resp, appErr := svc.HandleCompletion(context.Background(), "req-1", principal, structuredRequest())
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	if resp.Output != `{"verdict":"deny"}` {
+		t.Fatalf("expected the fence to be stripped, got %q", resp.Output)
+	}
+	if resp.InputTokens != 20 || resp.OutputTokens != 10 {
+		t.Fatalf("expected both rounds to be counted, got in=%d out=%d", resp.InputTokens, resp.OutputTokens)
+	}
+	if len(client.seen) != 2 || len(client.seen[1]) != 3 {
+		t.Fatalf("expected a re-ask with the rejected answer and a correction, got %+v", client.seen)
+	}
+	if correction := client.seen[1][2].Content; !strings.Contains(correction, "$.verdict") {
+		t.Fatalf("correction does not name the failing field: %q", correction)
+	}
+}
+
+func TestStructuredOutputFailsAfterReasksAndBillsRounds(t *testing.T) {
+	cfg := config.Default()
+	cfg.Structured.MaxReasks = 1
+	client := &cannedOutputs{outputs: []string{"sure, here you go"}}
+	svc, principal := newTestService(t, cfg, client)
+
+	_, appErr := svc.HandleCompletion(context.Background(), "req-1", principal, structuredRequest())
+	if appErr == nil || appErr.Code != CodeSchemaValidationFailed {
+		t.Fatalf("expected %s, got %v", CodeSchemaValidationFailed, appErr)
+	}
+	if len(client.seen) != 2 {
+		t.Fatalf("expected 2 rounds, got %d", len(client.seen))
+	}
+	if got := svc.Usage(principal); got.TotalOutputTokens != 10 || got.TotalCostUSD <= 0 {
+		t.Fatalf("expected rejected rounds to be billed, got %+v", got)
+	}
+	if ev := svc.AuditEvents(principal, 1)[0]; ev.Status != CodeSchemaValidationFailed {
+		t.Fatalf("unexpected audit status %q", ev.Status)
+	}
+	if got := testutil.ToFloat64(svc.metrics.StructuredOutputChecks.WithLabelValues("gpt-4o-mini", validationOutcomeInvalid)); got != 2 {
+		t.Fatalf("expected two invalid checks, got %v", got)
+	}
+}
+
+func TestResponseFormatValidation(t *testing.T) {
+	svc, principal := newTestService(t, config.Default(), SimulatedModelClient{})
+	zero := 0
+	cases := []struct {
+		name string
+		rf   *contracts.ResponseFormat
+		ok   bool
+	}{
+		{"json object", &contracts.ResponseFormat{Type: FormatJSONObject}, true},
+		{"max reasks lowered", &contracts.ResponseFormat{Type: FormatJSONObject, MaxReasks: &zero}, true},
+		{"unknown type", &contracts.ResponseFormat{Type: "xml"}, false},
+		{"missing schema", &contracts.ResponseFormat{Type: FormatJSONSchema}, false},
+		{"bad schema", &contracts.ResponseFormat{Type: FormatJSONSchema, Schema: json.RawMessage(`{"type":"date"}`)}, false},
+	}
+	for _, tc := range cases {
+		_, appErr := svc.HandleCompletion(context.Background(), "req-1", principal, contracts.CompletionRequest{Model: "gpt-4o-mini", Input: "hi", ResponseFormat: tc.rf})
+		if (appErr == nil) != tc.ok {
+			t.Errorf("%s: got %v", tc.name, appErr)
+		}
+	}
+
+	_, appErr := svc.HandleCompletionStream(context.Background(), "req-2", principal, structuredRequest(), func(_, _ string) error { return nil })
+	if appErr == nil || appErr.Code != "invalid_response_format" {
+		t.Fatalf("expected streaming to be rejected, got %v", appErr)
+	}
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/billing"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+// Tool choice modes besides naming a specific tool.
+const (
+	ToolChoiceAuto     = "auto"
+	ToolChoiceNone     = "none"
+	ToolChoiceRequired = "required"
+)
+
+// toolNamePattern matches the tool names accepted by both OpenAI and Anthropic.
+var toolNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// validateTools checks tool declarations and that tool_choice refers to a
+// declared tool when it names one.
+func validateTools(tools []contracts.Tool, choice string) *AppError {
+	seen := make(map[string]struct{}, len(tools))
+	for _, t := range tools {
+		if !toolNamePattern.MatchString(t.Name) {
+			return &AppError{Code: "invalid_tools", Message: fmt.Sprintf("invalid tool name %q", t.Name), HTTPStatus: http.StatusBadRequest}
+		}
+		if _, dup := seen[t.Name]; dup {
+			return &AppError{Code: "invalid_tools", Message: fmt.Sprintf("duplicate tool %q", t.Name), HTTPStatus: http.StatusBadRequest}
+		}
+		seen[t.Name] = struct{}{}
+		if len(t.Parameters) > 0 {
+			var schema map[string]any
+			if err := json.Unmarshal(t.Parameters, &schema); err != nil {
+				return &AppError{Code: "invalid_tools", Message: fmt.Sprintf("tool %q parameters must be a JSON Schema object", t.Name), HTTPStatus: http.StatusBadRequest}
+			}
+		}
+	}
+	switch choice {
+	case "", ToolChoiceAuto, ToolChoiceNone:
+	case ToolChoiceRequired:
+		if len(tools) == 0 {
+			return &AppError{Code: "invalid_tools", Message: "tool_choice requires tools", HTTPStatus: http.StatusBadRequest}
+		}
+	default:
+		if _, ok := seen[choice]; !ok {
+			return &AppError{Code: "invalid_tools", Message: fmt.Sprintf("tool_choice names undeclared tool %q", choice), HTTPStatus: http.StatusBadRequest}
+		}
+	}
+	return nil
+}
+
+func toolNames(tools []contracts.Tool) []string {
+	if len(tools) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(tools))
+	for _, t := range tools {
+		out = append(out, t.Name)
+	}
+	return out
+}
+
+func toolCallNames(calls []contracts.ToolCall) []string {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(calls))
+	for _, c := range calls {
+		out = append(out, c.Name)
+	}
+	return out
+}
+
+// toolTokens estimates the prompt tokens taken by tool declarations.
+func toolTokens(tools []contracts.Tool) int {
+	n := 0
+	for _, t := range tools {
+		n += billing.ApproxTokens(t.Name + " " + t.Description + " " + string(t.Parameters))
+	}
+	return n
+}
+
+// simulatedToolCall picks the call SimulatedModelClient makes, if any: tools
+// must be offered, not disabled, and the conversation must end on a user turn.
+func simulatedToolCall(req ModelRequest) (contracts.ToolCall, bool) {
+	if len(req.Tools) == 0 || req.ToolChoice == ToolChoiceNone || len(req.Messages) == 0 {
+		return contracts.ToolCall{}, false
+	}
+	if req.Messages[len(req.Messages)-1].Role != RoleUser {
+		return contracts.ToolCall{}, false
+	}
+	name := req.Tools[0].Name
+	switch req.ToolChoice {
+	case "", ToolChoiceAuto, ToolChoiceRequired:
+	default:
+		name = req.ToolChoice
+	}
+	args, _ := json.Marshal(map[string]string{"input": lastUserContent(req.Messages)})
+	return contracts.ToolCall{
+		ID:        fmt.Sprintf("call_%d", len(req.Messages)),
+		Name:      name,
+		Arguments: string(args),
+	}, true
+}
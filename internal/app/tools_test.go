@@ -0,0 +1,83 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+func TestValidateTools(t *testing.T) {
+	search := contracts.Tool{Name: "search_logs", Parameters: json.RawMessage(`{"type":"object"}`)}
+	cases := []struct {
+		name   string
+		tools  []contracts.Tool
+		choice string
+		ok     bool
+	}{
+		{"valid", []contracts.Tool{search}, "auto", true},
+		{"named choice", []contracts.Tool{search}, "search_logs", true},
+		{"bad name", []contracts.Tool{{Name: "run shell"}}, "", false},
+		{"duplicate", []contracts.Tool{search, search}, "", false},
+		{"schema not an object", []contracts.Tool{{Name: "x", Parameters: json.RawMessage(`[1]`)}}, "", false},
+		{"undeclared choice", []contracts.Tool{search}, "lookup_ip", false},
+		{"required without tools", nil, "required", false},
+	}
+	for _, tc := range cases {
+		if err := validateTools(tc.tools, tc.choice); (err == nil) != tc.ok {
+			t.Errorf("%s: got %v", tc.name, err)
+		}
+	}
+}
+
+func TestHandleCompletionReturnsAndAuditsToolCalls(t *testing.T) {
+	svc, principal := newTestService(t, config.Default(), SimulatedModelClient{})
+	req := contracts.CompletionRequest{
+		Model:    "gpt-4o-mini",
+		Messages: []contracts.Message{{Role: RoleUser, Content: "who logged in from 10.0.0.8?"}},
+		Tools:    []contracts.Tool{{Name: "search_logs"}, {Name: "lookup_ip"}},
+	}
+	resp, appErr := svc.HandleCompletion(context.Background(), "req-1", principal, req)
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "search_logs" || resp.Output != "" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	// Returning the tool result yields a text answer.
+	req.Messages = append(req.Messages,
+		contracts.Message{Role: RoleAssistant, ToolCalls: resp.ToolCalls},
+		contracts.Message{Role: RoleTool, ToolCallID: resp.ToolCalls[0].ID, Content: "alice at 09:14"},
+	)
+	resp, appErr = svc.HandleCompletion(context.Background(), "req-2", principal, req)
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	if len(resp.ToolCalls) != 0 || resp.Output == "" {
+		t.Fatalf("expected a text answer, got %+v", resp)
+	}
+
+	events := svc.AuditEvents(principal, 2)
+	if len(events) != 2 || len(events[1].Tools) != 2 || len(events[1].ToolCalls) != 1 || events[1].ToolCalls[0] != "search_logs" {
+		t.Fatalf("unexpected audit events: %+v", events)
+	}
+}
+
+func TestHandleCompletionDeniesToolsOutsideTeamAllowlist(t *testing.T) {
+	svc, principal := newTestService(t, config.Default(), SimulatedModelClient{})
+	_, appErr := svc.HandleCompletion(context.Background(), "req-1", principal, contracts.CompletionRequest{
+		Model: "gpt-4o-mini",
+		Input: "clean up the host",
+		Tools: []contracts.Tool{{Name: "run_shell"}},
+	})
+	if appErr == nil || appErr.Code != "policy_denied" || appErr.Message != "tool_not_allowed_for_team" {
+		t.Fatalf("expected tool_not_allowed_for_team, got %v", appErr)
+	}
+	events := svc.AuditEvents(principal, 1)
+	if len(events) != 1 || len(events[0].Tools) != 1 || events[0].Tools[0] != "run_shell" {
+		t.Fatalf("expected denied tool in audit, got %+v", events)
+	}
+}
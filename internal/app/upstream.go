@@ -0,0 +1,114 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+)
+
+const defaultProviderTimeout = 60 * time.Second
+
+// Upstream error codes returned when a provider call fails.
+const (
+	CodeUpstreamTimeout         = "upstream_timeout"
+	CodeUpstreamRateLimited     = "upstream_rate_limited"
+	CodeUpstreamAuthFailed      = "upstream_auth_failed"
+	CodeUpstreamBadRequest      = "upstream_bad_request"
+	CodeUpstreamUnavailable     = "upstream_unavailable"
+	CodeUpstreamUnreachable     = "upstream_unreachable"
+	CodeUpstreamInvalidResponse = "upstream_invalid_response"
+	CodeRequestCanceled         = "request_canceled"
+)
+
+// maxUpstreamBodyBytes caps how much of a provider response is buffered.
+const maxUpstreamBodyBytes = 8 << 20
+
+// maxStreamToolCalls caps the tool call index accepted in a provider stream.
+const maxStreamToolCalls = 128
+
+var (
+	errNoChoices  = errors.New("response contained no choices")
+	errStreamDone = errors.New("stream done")
+)
+
+// statusClientClosedRequest mirrors the de-facto 499 used by proxies when the
+// caller goes away before the upstream answers.
+const statusClientClosedRequest = 499
+
+// upstreamStatusError maps a non-2xx provider response to an AppError.
+func upstreamStatusError(provider string, status int, detail string) *AppError {
+	msg := fmt.Sprintf("%s upstream returned status %d", provider, status)
+	if detail != "" {
+		msg += ": " + detail
+	}
+	switch {
+	case status == http.StatusTooManyRequests:
+		return &AppError{Code: CodeUpstreamRateLimited, Message: msg, HTTPStatus: http.StatusTooManyRequests}
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return &AppError{Code: CodeUpstreamAuthFailed, Message: msg, HTTPStatus: http.StatusBadGateway}
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		return &AppError{Code: CodeUpstreamTimeout, Message: msg, HTTPStatus: http.StatusGatewayTimeout}
+	case status >= 500:
+		return &AppError{Code: CodeUpstreamUnavailable, Message: msg, HTTPStatus: http.StatusBadGateway}
+	default:
+		return &AppError{Code: CodeUpstreamBadRequest, Message: msg, HTTPStatus: http.StatusBadRequest}
+	}
+}
+
+// upstreamTransportError maps a failed round trip (no HTTP response) to an AppError.
+func upstreamTransportError(provider string, err error) *AppError {
+	if errors.Is(err, context.Canceled) {
+		return &AppError{Code: CodeRequestCanceled, Message: "request canceled before upstream responded", HTTPStatus: statusClientClosedRequest}
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &AppError{Code: CodeUpstreamTimeout, Message: fmt.Sprintf("%s upstream timed out", provider), HTTPStatus: http.StatusGatewayTimeout}
+	}
+	return &AppError{Code: CodeUpstreamUnreachable, Message: fmt.Sprintf("%s upstream unreachable", provider), HTTPStatus: http.StatusBadGateway}
+}
+
+func upstreamDecodeError(provider string, err error) *AppError {
+	return &AppError{
+		Code:       CodeUpstreamInvalidResponse,
+		Message:    fmt.Sprintf("%s upstream returned an invalid response: %v", provider, err),
+		HTTPStatus: http.StatusBadGateway,
+	}
+}
+
+// streamError maps a failure while consuming a provider stream. Errors already
+// typed (upstream events, or the caller's onDelta refusing more data) pass
+// through unchanged.
+func streamError(provider string, err error) error {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return err
+	}
+	var netErr net.Error
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr) {
+		return upstreamTransportError(provider, err)
+	}
+	return err
+}
+
+func providerTimeout(p config.ProviderConfig) time.Duration {
+	if p.TimeoutMS > 0 {
+		return time.Duration(p.TimeoutMS) * time.Millisecond
+	}
+	return defaultProviderTimeout
+}
+
+// newProviderHTTPClient bounds the wait for response headers rather than the
+// whole exchange, so long streams are not cut off; unary calls apply the full
+// timeout through their context instead.
+func newProviderHTTPClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = timeout
+	return &http.Client{Transport: transport}
+}
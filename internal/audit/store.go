@@ -5,17 +5,54 @@ import (
 	"time"
 )
 
-// Event is a single audited gateway action.
+// Attempt is one upstream call (or skipped candidate) made for an event.
+// CostUSD is set for calls billed in addition to the one that served the
+// request, such as the losing call of a hedged pair.
+type Attempt struct {
+	Model     string
+	Status    string
+	Reason    string
+	LatencyMS int64
+	CostUSD   float64
+}
+
+// Message is a redacted conversation turn.
+type Message struct {
+	Role    string
+	Content string
+}
+
+// Event is a single audited gateway action. Model is the model that served the
+// request (the requested one if none did); RequestedModel is what the caller
+// asked for, after resolving Alias, the virtual model name the caller used, if
+// any. SelectionReason explains the model picked for an "auto" request.
+// Experiment and Variant name the A/B experiment arm the request was
+// assigned to, if any.
+// Attempts lists every upstream candidate tried, in order.
+// RedactedMessages is set for multi-turn requests, one entry per turn.
+// Operation is the kind of call audited ("completion", "embedding"). Tools
+// lists the tool names declared on the request and ToolCalls the names of the
+// tools the model called.
 type Event struct {
-	Timestamp     time.Time
-	RequestID     string
-	Team          string
-	Model         string
-	Status        string
-	DenyReason    string
-	RedactedInput string
-	CostUSD       float64
-	LatencyMS     int64
+	Timestamp        time.Time
+	RequestID        string
+	Team             string
+	Operation        string
+	Model            string
+	RequestedModel   string
+	Alias            string
+	SelectionReason  string
+	Experiment       string
+	Variant          string
+	Status           string
+	DenyReason       string
+	RedactedInput    string
+	RedactedMessages []Message
+	CostUSD          float64
+	LatencyMS        int64
+	Tools            []string
+	ToolCalls        []string
+	Attempts         []Attempt
 }
 
 // Store is an in-memory bounded audit log.
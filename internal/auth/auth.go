@@ -11,12 +11,18 @@ var (
 	ErrInvalidAPIKey = errors.New("invalid api key")
 )
 
-// Principal is an authenticated gateway caller.
+// Principal is an authenticated gateway caller. MaxTokens, MinTemperature
+// and MaxTemperature are the team's generation limits; zero and nil mean
+// unlimited.
 type Principal struct {
 	Team              string
 	AllowedModels     map[string]struct{}
+	AllowedTools      map[string]struct{}
 	RequestsPerMinute int
 	MonthlyBudgetUSD  float64
+	MaxTokens         int
+	MinTemperature    *float64
+	MaxTemperature    *float64
 }
 
 // TeamDescriptor is used to construct API key auth map.
@@ -24,30 +30,53 @@ type TeamDescriptor struct {
 	Team              string
 	APIKey            string
 	AllowedModels     []string
+	AllowedTools      []string
 	RequestsPerMinute int
 	MonthlyBudgetUSD  float64
+	MaxTokens         int
+	MinTemperature    *float64
+	MaxTemperature    *float64
 }
 
 // APIKeyAuth authenticates callers by API key.
 type APIKeyAuth struct {
-	byKey map[string]Principal
+	byKey  map[string]Principal
+	byTeam map[string]Principal
 }
 
 func NewAPIKeyAuth(teams []TeamDescriptor) *APIKeyAuth {
 	byKey := make(map[string]Principal, len(teams))
+	byTeam := make(map[string]Principal, len(teams))
 	for _, t := range teams {
 		models := make(map[string]struct{}, len(t.AllowedModels))
 		for _, m := range t.AllowedModels {
 			models[m] = struct{}{}
 		}
-		byKey[t.APIKey] = Principal{
+		tools := make(map[string]struct{}, len(t.AllowedTools))
+		for _, name := range t.AllowedTools {
+			tools[name] = struct{}{}
+		}
+		principal := Principal{
 			Team:              t.Team,
 			AllowedModels:     models,
+			AllowedTools:      tools,
 			RequestsPerMinute: t.RequestsPerMinute,
 			MonthlyBudgetUSD:  t.MonthlyBudgetUSD,
+			MaxTokens:         t.MaxTokens,
+			MinTemperature:    t.MinTemperature,
+			MaxTemperature:    t.MaxTemperature,
 		}
+		byKey[t.APIKey] = principal
+		byTeam[t.Team] = principal
 	}
-	return &APIKeyAuth{byKey: byKey}
+	return &APIKeyAuth{byKey: byKey, byTeam: byTeam}
+}
+
+// Principal returns the principal of team, for work done on its behalf
+// after the request that authenticated it has ended.
+func (a *APIKeyAuth) Principal(team string) (Principal, bool) {
+	principal, ok := a.byTeam[team]
+	return principal, ok
 }
 
 func (a *APIKeyAuth) Authenticate(r *http.Request) (Principal, error) {
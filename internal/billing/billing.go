@@ -15,21 +15,37 @@ type TeamUsage struct {
 	PerModelCostUSD   map[string]float64
 }
 
-// Service stores usage counters and pricing metadata.
+// Service stores usage counters and pricing metadata. Shadow traffic is kept
+// on a separate ledger that does not count against team budgets.
 type Service struct {
-	mu      sync.Mutex
-	pricing map[string]float64
-	usage   map[string]*TeamUsage
+	mu       sync.Mutex
+	pricing  map[string]float64
+	defaults map[string]float64
+	split    map[string]modelPrice
+	usage    map[string]*TeamUsage
+	shadow   map[string]*TeamUsage
 }
 
+// modelPrice is a model's separate input and output price per 1K tokens.
+type modelPrice struct {
+	input  float64
+	output float64
+}
+
+// fallbackPricePer1K applies to models with neither a price nor a default.
+const fallbackPricePer1K = 0.005
+
 func NewService(pricing map[string]float64) *Service {
 	copyPricing := make(map[string]float64, len(pricing))
 	for k, v := range pricing {
 		copyPricing[k] = v
 	}
 	return &Service{
-		pricing: copyPricing,
-		usage:   make(map[string]*TeamUsage),
+		pricing:  copyPricing,
+		defaults: make(map[string]float64),
+		split:    make(map[string]modelPrice),
+		usage:    make(map[string]*TeamUsage),
+		shadow:   make(map[string]*TeamUsage),
 	}
 }
 
@@ -46,16 +62,84 @@ func ApproxTokens(s string) int {
 	return n
 }
 
+// MessageOverheadTokens approximates the per-turn framing tokens (role markers,
+// separators) providers add around each chat message.
+const MessageOverheadTokens = 4
+
+// ApproxMessageTokens estimates the tokens of one chat turn, framing included.
+func ApproxMessageTokens(content string) int {
+	return ApproxTokens(content) + MessageOverheadTokens
+}
+
+// SetDefaultPrice prices the models matching pattern, an exact model name or
+// a prefix ending in "*", unless they have a configured price. The longest
+// matching prefix wins.
+func (s *Service) SetDefaultPrice(pattern string, pricePer1K float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaults[pattern] = pricePer1K
+}
+
+// SetModelPrice prices model's input and output tokens separately, taking
+// precedence over its unit price.
+func (s *Service) SetModelPrice(model string, inputPer1K, outputPer1K float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.split[model] = modelPrice{input: inputPer1K, output: outputPer1K}
+}
+
 func (s *Service) UnitPrice(model string) float64 {
+	price, _ := s.unitPrice(model)
+	return price
+}
+
+// Priced reports whether model has a configured or default price rather than
+// the fallback price.
+func (s *Service) Priced(model string) bool {
+	s.mu.Lock()
+	_, ok := s.split[model]
+	s.mu.Unlock()
+	if ok {
+		return true
+	}
+	_, ok = s.unitPrice(model)
+	return ok
+}
+
+func (s *Service) unitPrice(model string) (float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if p, ok := s.pricing[model]; ok {
-		return p
+		return p, true
+	}
+	if p, ok := s.defaults[model]; ok {
+		return p, true
+	}
+	price, longest := fallbackPricePer1K, -1
+	for pattern, p := range s.defaults {
+		prefix, ok := strings.CutSuffix(pattern, "*")
+		if ok && len(prefix) > longest && strings.HasPrefix(model, prefix) {
+			price, longest = p, len(prefix)
+		}
+	}
+	return price, longest >= 0
+}
+
+// Prices returns the per-1K price of model's input and output tokens.
+func (s *Service) Prices(model string) (input, output float64) {
+	s.mu.Lock()
+	p, ok := s.split[model]
+	s.mu.Unlock()
+	if ok {
+		return p.input, p.output
 	}
-	return 0.005
+	unit := s.UnitPrice(model)
+	return unit, unit
 }
 
 func (s *Service) EstimateCost(model string, inputTokens, outputTokens int) float64 {
-	pricePer1K := s.UnitPrice(model)
-	return float64(inputTokens+outputTokens) / 1000.0 * pricePer1K
+	inputPer1K, outputPer1K := s.Prices(model)
+	return float64(inputTokens)/1000.0*inputPer1K + float64(outputTokens)/1000.0*outputPer1K
 }
 
 func (s *Service) RemainingBudget(team string, monthlyBudgetUSD float64) float64 {
@@ -72,16 +156,34 @@ func (s *Service) CanAfford(team string, monthlyBudgetUSD, estimatedCost float64
 }
 
 func (s *Service) Record(team, model string, inputTokens, outputTokens int, cost float64) {
+	s.record(s.usage, team, model, inputTokens, outputTokens, cost, true)
+}
+
+// RecordOverhead bills an upstream call made on behalf of a request that is
+// recorded on its own, such as the losing call of a hedged pair. It adds
+// tokens and cost without counting another request.
+func (s *Service) RecordOverhead(team, model string, inputTokens, outputTokens int, cost float64) {
+	s.record(s.usage, team, model, inputTokens, outputTokens, cost, false)
+}
+
+// RecordShadow bills a shadow call to team's shadow ledger.
+func (s *Service) RecordShadow(team, model string, inputTokens, outputTokens int, cost float64) {
+	s.record(s.shadow, team, model, inputTokens, outputTokens, cost, true)
+}
+
+func (s *Service) record(ledger map[string]*TeamUsage, team, model string, inputTokens, outputTokens int, cost float64, request bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	u := s.usage[team]
+	u := ledger[team]
 	if u == nil {
 		u = &TeamUsage{PerModelCostUSD: make(map[string]float64)}
-		s.usage[team] = u
+		ledger[team] = u
 	}
 
-	u.TotalRequests++
+	if request {
+		u.TotalRequests++
+	}
 	u.TotalInputTokens += int64(inputTokens)
 	u.TotalOutputTokens += int64(outputTokens)
 	u.TotalCostUSD += cost
@@ -89,10 +191,19 @@ func (s *Service) Record(team, model string, inputTokens, outputTokens int, cost
 }
 
 func (s *Service) GetUsage(team string) TeamUsage {
+	return s.usageOf(s.usage, team)
+}
+
+// GetShadowUsage returns team's shadow ledger.
+func (s *Service) GetShadowUsage(team string) TeamUsage {
+	return s.usageOf(s.shadow, team)
+}
+
+func (s *Service) usageOf(ledger map[string]*TeamUsage, team string) TeamUsage {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	u := s.usage[team]
+	u := ledger[team]
 	if u == nil {
 		return TeamUsage{PerModelCostUSD: map[string]float64{}}
 	}
@@ -25,3 +25,45 @@ func TestBudgetCheck(t *testing.T) {
 		t.Fatal("expected budget exceed")
 	}
 }
+
+func TestRecordOverheadDoesNotCountRequests(t *testing.T) {
+	svc := NewService(map[string]float64{"model-a": 0.01})
+	svc.Record("team-a", "model-a", 100, 50, 0.0015)
+	svc.RecordOverhead("team-a", "model-a", 100, 0, 0.001)
+	u := svc.GetUsage("team-a")
+	if u.TotalRequests != 1 || u.TotalInputTokens != 200 || u.TotalCostUSD != 0.0025 {
+		t.Fatalf("unexpected usage: %+v", u)
+	}
+}
+
+func TestDefaultPriceDoesNotOverrideConfiguredPrice(t *testing.T) {
+	svc := NewService(map[string]float64{"llama3:70b": 0.002})
+	svc.SetDefaultPrice("llama3*", 0)
+	svc.SetDefaultPrice("llama3:8b*", 0.001)
+	cases := map[string]float64{
+		"llama3:70b":     0.002,
+		"llama3":         0,
+		"llama3:8b-q4":   0.001,
+		"gpt-4o-mini":    fallbackPricePer1K,
+		"mistral:latest": fallbackPricePer1K,
+	}
+	for model, want := range cases {
+		if got := svc.UnitPrice(model); got != want {
+			t.Errorf("UnitPrice(%q) = %v, want %v", model, got, want)
+		}
+	}
+}
+
+func TestShadowLedgerIsSeparateFromBudget(t *testing.T) {
+	svc := NewService(map[string]float64{"model-a": 0.01})
+	svc.RecordShadow("team-a", "model-a", 1000, 0, 0.01)
+	if u := svc.GetUsage("team-a"); u.TotalRequests != 0 || u.TotalCostUSD != 0 {
+		t.Fatalf("expected shadow cost to stay off the main ledger, got %+v", u)
+	}
+	if u := svc.GetShadowUsage("team-a"); u.TotalRequests != 1 || u.PerModelCostUSD["model-a"] != 0.01 {
+		t.Fatalf("unexpected shadow usage: %+v", u)
+	}
+	if !svc.CanAfford("team-a", 0.01, 0.01) {
+		t.Fatal("expected shadow cost not to count against the budget")
+	}
+}
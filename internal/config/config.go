@@ -5,29 +5,282 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // TeamConfig represents tenant-specific gateway limits and permissions.
+// AllowedTools lists the tool names a team may expose to models; requests
+// declaring any other tool are denied. Hedging, when set, opts the team into
+// hedged completions. Generation caps the generation parameters the team may
+// request. ModelAliases overrides or adds to the global aliases for the team.
+// Shadow, when set, mirrors part of the team's traffic to a candidate model.
 type TeamConfig struct {
-	Name              string   `json:"name"`
-	APIKey            string   `json:"api_key"`
-	AllowedModels     []string `json:"allowed_models"`
-	RequestsPerMinute int      `json:"requests_per_minute"`
-	MonthlyBudgetUSD  float64  `json:"monthly_budget_usd"`
+	Name              string            `json:"name"`
+	APIKey            string            `json:"api_key"`
+	AllowedModels     []string          `json:"allowed_models"`
+	AllowedTools      []string          `json:"allowed_tools,omitempty"`
+	RequestsPerMinute int               `json:"requests_per_minute"`
+	MonthlyBudgetUSD  float64           `json:"monthly_budget_usd"`
+	Hedging           *HedgingConfig    `json:"hedging,omitempty"`
+	Generation        *GenerationLimits `json:"generation,omitempty"`
+	ModelAliases      map[string]string `json:"model_aliases,omitempty"`
+	Shadow            *ShadowConfig     `json:"shadow,omitempty"`
+}
+
+// ShadowConfig mirrors Percent (0-100) of a team's successful completions to
+// Model in the background. Shadow calls never affect the caller's response,
+// and their cost is kept off the team's budget. A Model no provider route
+// serves disables shadowing for the team, with a warning at startup.
+type ShadowConfig struct {
+	Model   string  `json:"model"`
+	Percent float64 `json:"percent"`
+}
+
+// ExperimentConfig splits Team's completions requested as Model between
+// Variants. A request is assigned by a hash of its caller-supplied user key,
+// so a user keeps its variant; requests without one are not enrolled. Start
+// and End bound when the experiment runs; zero leaves that side open.
+type ExperimentConfig struct {
+	Name     string              `json:"name"`
+	Team     string              `json:"team"`
+	Model    string              `json:"model"`
+	Variants []ExperimentVariant `json:"variants"`
+	Start    time.Time           `json:"start"`
+	End      time.Time           `json:"end"`
+}
+
+// ExperimentVariant serves Weight shares of an experiment's requests with
+// Model.
+type ExperimentVariant struct {
+	Name   string `json:"name"`
+	Model  string `json:"model"`
+	Weight int    `json:"weight"`
+}
+
+// GenerationLimits bounds per-request generation parameters. MaxTokens is the
+// largest max_tokens a request may ask for and the max_tokens of requests that
+// set none; zero means no ceiling. A request's temperature must lie within
+// [MinTemperature, MaxTemperature] where those are set.
+type GenerationLimits struct {
+	MaxTokens      int      `json:"max_tokens,omitempty"`
+	MinTemperature *float64 `json:"min_temperature,omitempty"`
+	MaxTemperature *float64 `json:"max_temperature,omitempty"`
+}
+
+// HedgingConfig sends a second, identical completion call when the first has
+// not returned after the Percentile (e.g. 0.95) of the model's recent
+// latencies, never sooner than MinDelayMS. MinDelayMS is also the delay used
+// until enough latencies have been observed.
+type HedgingConfig struct {
+	Percentile float64 `json:"percentile"`
+	MinDelayMS int     `json:"min_delay_ms"`
+}
+
+// ProviderConfig describes a named upstream model backend.
+// Type is one of "simulated" (default), "openai", "anthropic", "ollama",
+// "llamacpp" or "replay". SystemPrompt and MaxTokens are only used by providers that
+// require them; API selects the Ollama endpoint ("chat", the default, or
+// "generate"). With DiscoverModels, every model the backend lists at startup
+// is routed to it unless another route already matches. Scenario scripts the
+// behavior of a simulated provider. Cassette names the file a "replay"
+// provider serves calls from; on any other provider it records every call
+// to that file. Cassettes hold prompts and responses verbatim, unlike the
+// redacted audit log, so treat them as sensitive. ReplayLatency makes
+// replayed calls take their recorded time.
+type ProviderConfig struct {
+	Name           string `json:"name"`
+	Type           string `json:"type"`
+	BaseURL        string `json:"base_url"`
+	APIKey         string `json:"api_key"`
+	TimeoutMS      int    `json:"timeout_ms"`
+	SystemPrompt   string `json:"system_prompt,omitempty"`
+	MaxTokens      int    `json:"max_tokens,omitempty"`
+	API            string `json:"api,omitempty"`
+	DiscoverModels bool   `json:"discover_models,omitempty"`
+
+	Scenario      *SimulationScenario `json:"scenario,omitempty"`
+	Cassette      string              `json:"cassette,omitempty"`
+	ReplayLatency bool                `json:"replay_latency,omitempty"`
+}
+
+// SimulationScenario makes a simulated provider slow or failing. Models maps
+// exact model names, prefixes ending in "*", or "*" for any model to their
+// behavior. Random draws come from Seed, so a scenario replays identically
+// for the same sequence of calls.
+type SimulationScenario struct {
+	Seed   uint64                       `json:"seed"`
+	Models map[string]SimulatedBehavior `json:"models"`
+}
+
+// SimulatedBehavior describes one model of a scenario. Latency is drawn
+// uniformly from [MinLatencyMS, MaxLatencyMS]. Script lists the outcomes of
+// the first calls in order ("ok" or an error kind); later calls fail with
+// probability ErrorRate, with a kind drawn from Errors ("unavailable" when
+// empty). Error kinds are "timeout", "rate_limited", "unavailable",
+// "bad_request", "auth_failed", "unreachable" and "invalid_response".
+// OutputTokens pads answers to about that many tokens, and streams pause
+// StreamChunkDelayMS between chunks.
+type SimulatedBehavior struct {
+	MinLatencyMS       int      `json:"min_latency_ms,omitempty"`
+	MaxLatencyMS       int      `json:"max_latency_ms,omitempty"`
+	ErrorRate          float64  `json:"error_rate,omitempty"`
+	Errors             []string `json:"errors,omitempty"`
+	Script             []string `json:"script,omitempty"`
+	RetryAfterMS       int      `json:"retry_after_ms,omitempty"`
+	OutputTokens       int      `json:"output_tokens,omitempty"`
+	StreamChunkDelayMS int      `json:"stream_chunk_delay_ms,omitempty"`
+}
+
+// RetryConfig controls retries of transient upstream failures. MaxAttempts
+// includes the first call; 1 disables retries.
+type RetryConfig struct {
+	MaxAttempts int `json:"max_attempts"`
+	BaseDelayMS int `json:"base_delay_ms"`
+	MaxDelayMS  int `json:"max_delay_ms"`
+}
+
+// CircuitBreakerConfig controls the per-provider circuit breaker. A provider's
+// circuit opens once at least MinRequests calls were seen in the current
+// WindowMS and the share of failures reaches FailureRatio; after CooldownMS one
+// probe call is let through. A zero FailureRatio disables the breaker.
+type CircuitBreakerConfig struct {
+	FailureRatio float64 `json:"failure_ratio"`
+	MinRequests  int     `json:"min_requests"`
+	WindowMS     int     `json:"window_ms"`
+	CooldownMS   int     `json:"cooldown_ms"`
+}
+
+// HealthCheckConfig controls active provider probes. A provider is marked
+// unhealthy after UnhealthyThreshold failed probes in a row and healthy again
+// after HealthyThreshold successful ones. A zero IntervalMS disables probing.
+type HealthCheckConfig struct {
+	IntervalMS         int `json:"interval_ms"`
+	TimeoutMS          int `json:"timeout_ms"`
+	UnhealthyThreshold int `json:"unhealthy_threshold"`
+	HealthyThreshold   int `json:"healthy_threshold"`
+}
+
+// StructuredOutputConfig bounds the re-asks made when a model's output does
+// not match the requested response_format. Zero disables re-asking.
+type StructuredOutputConfig struct {
+	MaxReasks int `json:"max_reasks"`
+}
+
+// JobsConfig controls asynchronous completion jobs. Workers jobs run at once
+// and up to QueueSize more wait; submissions beyond that are refused. With a
+// StatePath, jobs are saved to that file and resumed after a restart.
+// Webhook callbacks are signed with WebhookSecret and are refused while it
+// is empty. When WebhookHosts is set, webhook URLs must name one of its
+// hosts; only those hosts may resolve to loopback, private or link-local
+// addresses.
+type JobsConfig struct {
+	Workers       int      `json:"workers"`
+	QueueSize     int      `json:"queue_size"`
+	StatePath     string   `json:"state_path,omitempty"`
+	WebhookSecret string   `json:"webhook_secret,omitempty"`
+	WebhookHosts  []string `json:"webhook_hosts,omitempty"`
+}
+
+// DeploymentConfig is one weighted upstream serving a balanced model.
+// Provider names an entry in Providers; Model overrides the model name sent
+// upstream when the deployment knows the model under another name. Weight
+// defaults to 1.
+type DeploymentConfig struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model,omitempty"`
+	Weight   int    `json:"weight"`
+}
+
+// BalancerConfig spreads a model's traffic over several deployments. Strategy
+// is "weighted_round_robin" (default) or "least_outstanding".
+type BalancerConfig struct {
+	Strategy    string             `json:"strategy"`
+	Deployments []DeploymentConfig `json:"deployments"`
+}
+
+// EjectionConfig takes a balanced deployment out of rotation for EjectMS once
+// it fails ConsecutiveErrors calls in a row with retryable errors. A zero
+// ConsecutiveErrors disables ejection.
+type EjectionConfig struct {
+	ConsecutiveErrors int `json:"consecutive_errors"`
+	EjectMS           int `json:"eject_ms"`
+}
+
+// ModelInfo is a model catalog entry. Mode is "completion" (default) or
+// "embedding"; embedding models are not served completions, and other catalog
+// models are not served embeddings. Requests whose estimated input plus
+// max_tokens exceed ContextWindow, or whose max_tokens exceeds
+// MaxOutputTokens, are rejected; zero means unknown. Streaming and
+// tool requests are rejected when SupportsStreaming or SupportsTools is
+// false, and allowed when unset. Modalities (e.g. "text", "image") and Provider are
+// descriptive. Input and output prices, when set, replace the model's
+// PricingPer1KUSD entry for that side.
+type ModelInfo struct {
+	Mode                string   `json:"mode,omitempty"`
+	Provider            string   `json:"provider,omitempty"`
+	ContextWindow       int      `json:"context_window,omitempty"`
+	MaxOutputTokens     int      `json:"max_output_tokens,omitempty"`
+	Modalities          []string `json:"modalities,omitempty"`
+	SupportsStreaming   *bool    `json:"supports_streaming,omitempty"`
+	SupportsTools       *bool    `json:"supports_tools,omitempty"`
+	InputPricePer1KUSD  *float64 `json:"input_price_per_1k_usd,omitempty"`
+	OutputPricePer1KUSD *float64 `json:"output_price_per_1k_usd,omitempty"`
 }
 
 // Config is runtime gateway configuration.
+//
+// ModelRoutes maps a model name, or a prefix pattern ending in "*", to a
+// provider name from Providers. Exact names win over patterns, and the longest
+// matching pattern wins among patterns. ModelDeployments routes a model name or
+// pattern to a balanced set of deployments instead; a pattern may appear in
+// only one of the two.
+//
+// BlockedPatterns apply to every turn, system turns included. RolePatterns
+// adds patterns per role on top of them.
+//
+// FallbackChains lists, per requested model, the models tried in order when
+// the upstream call fails with a retryable error.
+//
+// ModelCatalog describes models by exact name; models without an entry are
+// served without capability checks.
+//
+// ModelAliases maps virtual model names (e.g. "fast") to the model they stand
+// for. Completion requests naming an alias are served by its model, and an
+// alias in a team's AllowedModels allows its model when requested through it.
+//
+// ShadowLogPath, when set, is the file every shadow comparison pair is
+// appended to as a JSON line.
+//
+// Experiments are A/B tests between models for a team; variant models are
+// subject to the team's AllowedModels like any other model.
 type Config struct {
-	ListenAddr      string             `json:"listen_addr"`
-	DefaultModel    string             `json:"default_model"`
-	MaxAuditEvents  int                `json:"max_audit_events"`
-	BlockedPatterns []string           `json:"blocked_patterns"`
-	PricingPer1KUSD map[string]float64 `json:"pricing_per_1k_usd"`
-	Teams           []TeamConfig       `json:"teams"`
+	ListenAddr       string                    `json:"listen_addr"`
+	DefaultModel     string                    `json:"default_model"`
+	MaxAuditEvents   int                       `json:"max_audit_events"`
+	BlockedPatterns  []string                  `json:"blocked_patterns"`
+	RolePatterns     map[string][]string       `json:"blocked_patterns_by_role"`
+	PricingPer1KUSD  map[string]float64        `json:"pricing_per_1k_usd"`
+	ModelCatalog     map[string]ModelInfo      `json:"model_catalog,omitempty"`
+	ModelAliases     map[string]string         `json:"model_aliases,omitempty"`
+	Teams            []TeamConfig              `json:"teams"`
+	Providers        []ProviderConfig          `json:"providers"`
+	ModelRoutes      map[string]string         `json:"model_routes"`
+	ModelDeployments map[string]BalancerConfig `json:"model_deployments,omitempty"`
+	Ejection         EjectionConfig            `json:"ejection"`
+	HealthCheck      HealthCheckConfig         `json:"health_check"`
+	FallbackChains   map[string][]string       `json:"fallback_chains"`
+	Retry            RetryConfig               `json:"retry"`
+	CircuitBreaker   CircuitBreakerConfig      `json:"circuit_breaker"`
+	Structured       StructuredOutputConfig    `json:"structured_output"`
+	ShadowLogPath    string                    `json:"shadow_log_path,omitempty"`
+	Experiments      []ExperimentConfig        `json:"experiments,omitempty"`
+	Jobs             JobsConfig                `json:"jobs"`
 }
 
 // Default returns a safe local-first configuration.
 func Default() Config {
+	supported, unsupported := true, false
 	return Config{
 		ListenAddr:     ":8080",
 		DefaultModel:   "gpt-4o-mini",
@@ -38,16 +291,72 @@ func Default() Config {
 			`(?i)exfiltrate\s+secrets?`,
 			`(?i)bypass\s+policy`,
 		},
+		RolePatterns: map[string][]string{
+			"system": {`(?i)exfiltrate\s+secrets?`},
+		},
 		PricingPer1KUSD: map[string]float64{
 			"gpt-4o-mini":       0.0030,
 			"gpt-4.1-mini":      0.0045,
 			"claude-3-5-sonnet": 0.0060,
+			// Embedding models are billed on input tokens only.
+			"text-embedding-3-small": 0.00002,
+		},
+		ModelCatalog: map[string]ModelInfo{
+			"gpt-4o-mini": {
+				Provider: "openai", ContextWindow: 128000, MaxOutputTokens: 16384,
+				Modalities: []string{"text", "image"}, SupportsStreaming: &supported, SupportsTools: &supported,
+			},
+			"gpt-4.1-mini": {
+				Provider: "openai", ContextWindow: 1047576, MaxOutputTokens: 32768,
+				Modalities: []string{"text", "image"}, SupportsStreaming: &supported, SupportsTools: &supported,
+			},
+			"claude-3-5-sonnet": {
+				Provider: "anthropic", ContextWindow: 200000, MaxOutputTokens: 8192,
+				Modalities: []string{"text", "image"}, SupportsStreaming: &supported, SupportsTools: &supported,
+			},
+			"text-embedding-3-small": {
+				Mode: "embedding", Provider: "openai", ContextWindow: 8191,
+				Modalities: []string{"text"}, SupportsStreaming: &unsupported, SupportsTools: &unsupported,
+			},
+		},
+		ModelAliases: map[string]string{
+			"fast":  "gpt-4o-mini",
+			"cheap": "gpt-4o-mini",
+			"smart": "gpt-4.1-mini",
+		},
+		Providers: []ProviderConfig{
+			{Name: "simulated", Type: "simulated"},
 		},
+		ModelRoutes: map[string]string{
+			"gpt-*":            "simulated",
+			"claude-*":         "simulated",
+			"text-embedding-*": "simulated",
+		},
+		FallbackChains: map[string][]string{
+			"gpt-4.1-mini": {"gpt-4o-mini", "claude-3-5-sonnet"},
+		},
+		Retry: RetryConfig{MaxAttempts: 3, BaseDelayMS: 200, MaxDelayMS: 2000},
+		CircuitBreaker: CircuitBreakerConfig{
+			FailureRatio: 0.5,
+			MinRequests:  10,
+			WindowMS:     30000,
+			CooldownMS:   15000,
+		},
+		Ejection: EjectionConfig{ConsecutiveErrors: 3, EjectMS: 30000},
+		HealthCheck: HealthCheckConfig{
+			IntervalMS:         15000,
+			TimeoutMS:          5000,
+			UnhealthyThreshold: 2,
+			HealthyThreshold:   1,
+		},
+		Structured: StructuredOutputConfig{MaxReasks: 2},
+		Jobs:       JobsConfig{Workers: 4, QueueSize: 100},
 		Teams: []TeamConfig{
 			{
 				Name:              "red-team",
 				APIKey:            "demo-red-key",
-				AllowedModels:     []string{"gpt-4o-mini", "gpt-4.1-mini"},
+				AllowedModels:     []string{"gpt-4o-mini", "gpt-4.1-mini", "text-embedding-3-small"},
+				AllowedTools:      []string{"search_logs", "lookup_ip"},
 				RequestsPerMinute: 60,
 				MonthlyBudgetUSD:  75,
 			},
@@ -57,13 +366,20 @@ func Default() Config {
 				AllowedModels:     []string{"gpt-4o-mini", "claude-3-5-sonnet"},
 				RequestsPerMinute: 30,
 				MonthlyBudgetUSD:  40,
+				ModelAliases:      map[string]string{"smart": "claude-3-5-sonnet"},
 			},
 		},
 	}
 }
 
-// Load returns env-overridden config. GATEWAY_TEAMS_JSON and GATEWAY_PRICING_JSON
-// allow full replacement for teams/pricing.
+// Load returns env-overridden config. GATEWAY_TEAMS_JSON, GATEWAY_PRICING_JSON,
+// GATEWAY_MODEL_CATALOG_JSON, GATEWAY_MODEL_ALIASES_JSON,
+// GATEWAY_PROVIDERS_JSON, GATEWAY_MODEL_ROUTES_JSON,
+// GATEWAY_MODEL_DEPLOYMENTS_JSON, GATEWAY_FALLBACK_CHAINS_JSON and
+// GATEWAY_EXPERIMENTS_JSON allow full replacement for
+// teams/pricing/catalog/aliases/providers/routes/deployments/fallbacks/experiments;
+// GATEWAY_SIMULATION_SCENARIO_JSON sets the scenario of simulated providers
+// that have none.
 func Load() Config {
 	cfg := Default()
 
@@ -73,11 +389,88 @@ func Load() Config {
 	if v := os.Getenv("GATEWAY_DEFAULT_MODEL"); v != "" {
 		cfg.DefaultModel = v
 	}
+	if v := os.Getenv("GATEWAY_SHADOW_LOG_PATH"); v != "" {
+		cfg.ShadowLogPath = v
+	}
+	if v := os.Getenv("GATEWAY_JOBS_STATE_PATH"); v != "" {
+		cfg.Jobs.StatePath = v
+	}
+	if v := os.Getenv("GATEWAY_JOBS_WEBHOOK_SECRET"); v != "" {
+		cfg.Jobs.WebhookSecret = v
+	}
+	if v := os.Getenv("GATEWAY_JOBS_WEBHOOK_HOSTS"); v != "" {
+		cfg.Jobs.WebhookHosts = nil
+		for _, host := range strings.Split(v, ",") {
+			if host = strings.TrimSpace(host); host != "" {
+				cfg.Jobs.WebhookHosts = append(cfg.Jobs.WebhookHosts, host)
+			}
+		}
+	}
+	if v := os.Getenv("GATEWAY_JOBS_WORKERS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.Jobs.Workers = n
+		}
+	}
+	if v := os.Getenv("GATEWAY_JOBS_QUEUE_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.Jobs.QueueSize = n
+		}
+	}
 	if v := os.Getenv("GATEWAY_MAX_AUDIT_EVENTS"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n > 0 {
 			cfg.MaxAuditEvents = n
 		}
 	}
+	if v := os.Getenv("GATEWAY_RETRY_MAX_ATTEMPTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.Retry.MaxAttempts = n
+		}
+	}
+	if v := os.Getenv("GATEWAY_RETRY_BASE_DELAY_MS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.Retry.BaseDelayMS = n
+		}
+	}
+	if v := os.Getenv("GATEWAY_RETRY_MAX_DELAY_MS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.Retry.MaxDelayMS = n
+		}
+	}
+	if v := os.Getenv("GATEWAY_BREAKER_FAILURE_RATIO"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 && f <= 1 {
+			cfg.CircuitBreaker.FailureRatio = f
+		}
+	}
+	if v := os.Getenv("GATEWAY_BREAKER_COOLDOWN_MS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.CircuitBreaker.CooldownMS = n
+		}
+	}
+	if v := os.Getenv("GATEWAY_EJECTION_CONSECUTIVE_ERRORS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.Ejection.ConsecutiveErrors = n
+		}
+	}
+	if v := os.Getenv("GATEWAY_EJECTION_MS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.Ejection.EjectMS = n
+		}
+	}
+	if v := os.Getenv("GATEWAY_HEALTH_CHECK_INTERVAL_MS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.HealthCheck.IntervalMS = n
+		}
+	}
+	if v := os.Getenv("GATEWAY_HEALTH_CHECK_TIMEOUT_MS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.HealthCheck.TimeoutMS = n
+		}
+	}
+	if v := os.Getenv("GATEWAY_STRUCTURED_OUTPUT_MAX_REASKS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.Structured.MaxReasks = n
+		}
+	}
 	if v := os.Getenv("GATEWAY_TEAMS_JSON"); v != "" {
 		var teams []TeamConfig
 		if err := json.Unmarshal([]byte(v), &teams); err != nil {
@@ -94,6 +487,74 @@ func Load() Config {
 			cfg.PricingPer1KUSD = pricing
 		}
 	}
+	if v := os.Getenv("GATEWAY_MODEL_CATALOG_JSON"); v != "" {
+		catalog := make(map[string]ModelInfo)
+		if err := json.Unmarshal([]byte(v), &catalog); err != nil {
+			log.Printf("invalid GATEWAY_MODEL_CATALOG_JSON, using defaults: %v", err)
+		} else {
+			cfg.ModelCatalog = catalog
+		}
+	}
+	if v := os.Getenv("GATEWAY_MODEL_ALIASES_JSON"); v != "" {
+		aliases := make(map[string]string)
+		if err := json.Unmarshal([]byte(v), &aliases); err != nil {
+			log.Printf("invalid GATEWAY_MODEL_ALIASES_JSON, using defaults: %v", err)
+		} else {
+			cfg.ModelAliases = aliases
+		}
+	}
+	if v := os.Getenv("GATEWAY_PROVIDERS_JSON"); v != "" {
+		var providers []ProviderConfig
+		if err := json.Unmarshal([]byte(v), &providers); err != nil {
+			log.Printf("invalid GATEWAY_PROVIDERS_JSON, using defaults: %v", err)
+		} else if len(providers) > 0 {
+			cfg.Providers = providers
+		}
+	}
+	if v := os.Getenv("GATEWAY_SIMULATION_SCENARIO_JSON"); v != "" {
+		var scenario SimulationScenario
+		if err := json.Unmarshal([]byte(v), &scenario); err != nil {
+			log.Printf("invalid GATEWAY_SIMULATION_SCENARIO_JSON, ignoring: %v", err)
+		} else {
+			for i, p := range cfg.Providers {
+				if (p.Type == "" || p.Type == "simulated") && p.Scenario == nil {
+					cfg.Providers[i].Scenario = &scenario
+				}
+			}
+		}
+	}
+	if v := os.Getenv("GATEWAY_MODEL_ROUTES_JSON"); v != "" {
+		routes := make(map[string]string)
+		if err := json.Unmarshal([]byte(v), &routes); err != nil {
+			log.Printf("invalid GATEWAY_MODEL_ROUTES_JSON, using defaults: %v", err)
+		} else if len(routes) > 0 {
+			cfg.ModelRoutes = routes
+		}
+	}
+	if v := os.Getenv("GATEWAY_MODEL_DEPLOYMENTS_JSON"); v != "" {
+		deployments := make(map[string]BalancerConfig)
+		if err := json.Unmarshal([]byte(v), &deployments); err != nil {
+			log.Printf("invalid GATEWAY_MODEL_DEPLOYMENTS_JSON, using defaults: %v", err)
+		} else {
+			cfg.ModelDeployments = deployments
+		}
+	}
+	if v := os.Getenv("GATEWAY_FALLBACK_CHAINS_JSON"); v != "" {
+		chains := make(map[string][]string)
+		if err := json.Unmarshal([]byte(v), &chains); err != nil {
+			log.Printf("invalid GATEWAY_FALLBACK_CHAINS_JSON, using defaults: %v", err)
+		} else {
+			cfg.FallbackChains = chains
+		}
+	}
+	if v := os.Getenv("GATEWAY_EXPERIMENTS_JSON"); v != "" {
+		var experiments []ExperimentConfig
+		if err := json.Unmarshal([]byte(v), &experiments); err != nil {
+			log.Printf("invalid GATEWAY_EXPERIMENTS_JSON, using defaults: %v", err)
+		} else {
+			cfg.Experiments = experiments
+		}
+	}
 
 	return cfg
 }
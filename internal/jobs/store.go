@@ -0,0 +1,181 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+// Job states. Queued and running jobs are unfinished; the others are final.
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// ErrNotFound is returned for jobs the store does not hold.
+var ErrNotFound = errors.New("job not found")
+
+// Job is an asynchronous completion. Request is kept only until the job
+// finishes; Result or Error then holds its outcome. WebhookStatus reports the
+// callback to WebhookURL, if any.
+type Job struct {
+	ID            string                        `json:"id"`
+	RequestID     string                        `json:"request_id"`
+	Team          string                        `json:"team"`
+	Model         string                        `json:"model"`
+	Status        string                        `json:"status"`
+	Request       *contracts.CompletionRequest  `json:"request,omitempty"`
+	Result        *contracts.CompletionResponse `json:"result,omitempty"`
+	Error         *contracts.ErrorResponse      `json:"error,omitempty"`
+	WebhookURL    string                        `json:"webhook_url,omitempty"`
+	WebhookStatus string                        `json:"webhook_status,omitempty"`
+	CreatedAt     time.Time                     `json:"created_at"`
+	StartedAt     *time.Time                    `json:"started_at,omitempty"`
+	FinishedAt    *time.Time                    `json:"finished_at,omitempty"`
+}
+
+// Finished reports whether j reached a final state.
+func (j Job) Finished() bool {
+	return j.Status != StatusQueued && j.Status != StatusRunning
+}
+
+// Store holds jobs in memory and, when it has a path, rewrites the file at
+// path with every job after each change, so jobs survive a restart.
+// Finished jobs are dropped once they are older than the retention.
+type Store struct {
+	mu        sync.Mutex
+	path      string
+	retention time.Duration
+	jobs      map[string]Job
+}
+
+// NewStore loads the jobs saved at path, if any. Jobs that were running when
+// they were saved are queued again. An empty path keeps jobs in memory only.
+func NewStore(path string, retention time.Duration) (*Store, error) {
+	s := &Store{path: path, retention: retention, jobs: make(map[string]Job)}
+	if path == "" {
+		return s, nil
+	}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var saved []Job
+	if err := json.Unmarshal(raw, &saved); err != nil {
+		return nil, err
+	}
+	for _, j := range saved {
+		if j.Status == StatusRunning {
+			j.Status, j.StartedAt = StatusQueued, nil
+		}
+		s.jobs[j.ID] = j
+	}
+	return s, nil
+}
+
+// Create adds j. The error reports a failed save, in which case j is still
+// held in memory.
+func (s *Store) Create(j Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(time.Now())
+	s.jobs[j.ID] = j
+	return s.save()
+}
+
+// Get returns the job with id.
+func (s *Store) Get(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	return j, ok
+}
+
+// Update applies fn to the job with id and saves the result. When fn fails
+// the job is left unchanged and fn's error is returned.
+func (s *Store) Update(id string, fn func(*Job) error) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	if err := fn(&j); err != nil {
+		return Job{}, err
+	}
+	s.jobs[id] = j
+	return j, s.save()
+}
+
+// Delete removes the job with id.
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+	return s.save()
+}
+
+// Queued returns the IDs of queued jobs, oldest first.
+func (s *Store) Queued() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var queued []Job
+	for _, j := range s.jobs {
+		if j.Status == StatusQueued {
+			queued = append(queued, j)
+		}
+	}
+	slices.SortFunc(queued, func(a, b Job) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	ids := make([]string, 0, len(queued))
+	for _, j := range queued {
+		ids = append(ids, j.ID)
+	}
+	return ids
+}
+
+func (s *Store) prune(now time.Time) {
+	if s.retention <= 0 {
+		return
+	}
+	for id, j := range s.jobs {
+		if j.Finished() && j.FinishedAt != nil && now.Sub(*j.FinishedAt) > s.retention {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// save writes every job to a temporary file and renames it over path, so a
+// crash never leaves a partial file behind.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	all := make([]Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		all = append(all, j)
+	}
+	slices.SortFunc(all, func(a, b Job) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	raw, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
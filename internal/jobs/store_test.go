@@ -0,0 +1,49 @@
+package jobs
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreRestoresJobsAndRequeuesRunningOnes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	s, err := NewStore(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	old := now.Add(-2 * time.Hour)
+	for _, j := range []Job{
+		{ID: "job-old", Status: StatusSucceeded, CreatedAt: old, FinishedAt: &old},
+		{ID: "job-1", Status: StatusQueued, CreatedAt: now},
+		{ID: "job-2", Status: StatusQueued, CreatedAt: now.Add(time.Second)},
+	} {
+		if err := s.Create(j); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Update("job-1", func(j *Job) error {
+		j.Status, j.StartedAt = StatusRunning, &now
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Update("missing", func(*Job) error { return nil }); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	restored, err := NewStore(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j, ok := restored.Get("job-1"); !ok || j.Status != StatusQueued || j.StartedAt != nil {
+		t.Fatalf("expected the running job to be queued again, got %+v", j)
+	}
+	if got := restored.Queued(); len(got) != 2 || got[0] != "job-1" || got[1] != "job-2" {
+		t.Fatalf("expected queued jobs oldest first, got %v", got)
+	}
+	if _, ok := restored.Get("job-old"); ok {
+		t.Fatal("expected the expired finished job to be pruned")
+	}
+}
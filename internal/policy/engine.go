@@ -8,38 +8,105 @@ type Decision struct {
 	Reason  string
 }
 
-// Input carries all context required for policy checks.
+// Message is a single conversation turn subject to policy checks.
+type Message struct {
+	Role    string
+	Content string
+}
+
+// Input carries all context required for policy checks. Prompt is a legacy
+// single-blob input and is checked like a user turn. Tools are the names of
+// the tools the caller exposes to the model; each must be in AllowedTools.
+// MaxTokens and Temperature are the requested generation parameters (zero and
+// nil when unset), checked against the team's MaxTokensLimit and temperature
+// range (zero and nil when unlimited). Alias is the virtual name Model was
+// requested under, if any; an allowed alias allows its model.
 type Input struct {
-	Model         string
-	Prompt        string
-	AllowedModels map[string]struct{}
+	Model          string
+	Alias          string
+	Prompt         string
+	Messages       []Message
+	Tools          []string
+	AllowedModels  map[string]struct{}
+	AllowedTools   map[string]struct{}
+	MaxTokens      int
+	Temperature    *float64
+	MaxTokensLimit int
+	MinTemperature *float64
+	MaxTemperature *float64
 }
 
-// Engine evaluates request policy.
+// Engine evaluates request policy. Blocked patterns apply to every turn,
+// system turns included, since callers supply their own system prompts.
+// Role patterns add checks for a given role on top of the blocked patterns.
 type Engine struct {
 	blocked []*regexp.Regexp
+	byRole  map[string][]*regexp.Regexp
+}
+
+func NewEngine(patterns []string, rolePatterns map[string][]string) *Engine {
+	byRole := make(map[string][]*regexp.Regexp, len(rolePatterns))
+	for role, ps := range rolePatterns {
+		byRole[role] = compile(ps)
+	}
+	return &Engine{blocked: compile(patterns), byRole: byRole}
 }
 
-func NewEngine(patterns []string) *Engine {
-	blocked := make([]*regexp.Regexp, 0, len(patterns))
+func compile(patterns []string) []*regexp.Regexp {
+	out := make([]*regexp.Regexp, 0, len(patterns))
 	for _, p := range patterns {
 		re, err := regexp.Compile(p)
 		if err != nil {
 			continue
 		}
-		blocked = append(blocked, re)
+		out = append(out, re)
 	}
-	return &Engine{blocked: blocked}
+	return out
 }
 
 func (e *Engine) Evaluate(in Input) Decision {
-	if _, ok := in.AllowedModels[in.Model]; !ok {
+	if !in.modelAllowed() {
 		return Decision{Allowed: false, Reason: "model_not_allowed_for_team"}
 	}
-	for _, re := range e.blocked {
-		if re.MatchString(in.Prompt) {
+	for _, tool := range in.Tools {
+		if _, ok := in.AllowedTools[tool]; !ok {
+			return Decision{Allowed: false, Reason: "tool_not_allowed_for_team"}
+		}
+	}
+	if in.MaxTokensLimit > 0 && in.MaxTokens > in.MaxTokensLimit {
+		return Decision{Allowed: false, Reason: "max_tokens_exceeds_team_limit"}
+	}
+	if t := in.Temperature; t != nil && ((in.MinTemperature != nil && *t < *in.MinTemperature) || (in.MaxTemperature != nil && *t > *in.MaxTemperature)) {
+		return Decision{Allowed: false, Reason: "temperature_not_allowed_for_team"}
+	}
+	if in.Prompt != "" && e.blockedFor("user", in.Prompt) {
+		return Decision{Allowed: false, Reason: "blocked_pattern_detected"}
+	}
+	for _, m := range in.Messages {
+		if e.blockedFor(m.Role, m.Content) {
 			return Decision{Allowed: false, Reason: "blocked_pattern_detected"}
 		}
 	}
 	return Decision{Allowed: true}
 }
+
+func (e *Engine) blockedFor(role, text string) bool {
+	return matchAny(e.blocked, text) || matchAny(e.byRole[role], text)
+}
+
+func matchAny(res []*regexp.Regexp, text string) bool {
+	for _, re := range res {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+func (in Input) modelAllowed() bool {
+	if _, ok := in.AllowedModels[in.Model]; ok {
+		return true
+	}
+	_, ok := in.AllowedModels[in.Alias]
+	return in.Alias != "" && ok
+}
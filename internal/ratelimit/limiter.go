@@ -43,3 +43,19 @@ func (l *Limiter) Allow(team string, requestsPerMinute int, now time.Time) bool
 	l.buckets[team] = b
 	return true
 }
+
+// Release gives back one request taken by Allow in the minute containing now,
+// for requests refused after they were counted.
+func (l *Limiter) Release(team string, now time.Time) {
+	window := now.UTC().Truncate(time.Minute)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[team]
+	if !ok || !b.windowStart.Equal(window) || b.count == 0 {
+		return
+	}
+	b.count--
+	l.buckets[team] = b
+}
@@ -0,0 +1,554 @@
+// Package schema validates JSON values against the subset of JSON Schema used
+// to describe structured model output: type, properties, required,
+// additionalProperties, items, enum, const, allOf/anyOf/oneOf, and the
+// string, number and array bounds. References ($ref) are not supported.
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a compiled JSON Schema node.
+type Schema struct {
+	types        []string
+	properties   map[string]*Schema
+	required     []string
+	additional   *Schema
+	noAdditional bool
+	items        *Schema
+	enum         []any
+	constant     any
+	hasConst     bool
+	allOf        []*Schema
+	anyOf        []*Schema
+	oneOf        []*Schema
+	minLength    *int
+	maxLength    *int
+	pattern      *regexp.Regexp
+	minimum      *float64
+	maximum      *float64
+	exclMinimum  *float64
+	exclMaximum  *float64
+	minItems     *int
+	maxItems     *int
+}
+
+// ValidationError reports the first place a value does not conform. Path
+// uses "$" for the root, ".name" for properties and "[i]" for array items.
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string { return e.Path + ": " + e.Message }
+
+var knownTypes = map[string]struct{}{
+	"object": {}, "array": {}, "string": {}, "number": {}, "integer": {}, "boolean": {}, "null": {},
+}
+
+// Compile parses a JSON Schema document.
+func Compile(raw []byte) (*Schema, error) {
+	var doc any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("schema is not valid JSON: %w", err)
+	}
+	return compile(doc, "$")
+}
+
+func compile(node any, path string) (*Schema, error) {
+	if b, ok := node.(bool); ok {
+		// true accepts anything; false accepts nothing.
+		if b {
+			return &Schema{}, nil
+		}
+		return &Schema{anyOf: []*Schema{}}, nil
+	}
+	obj, ok := node.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: schema must be an object", path)
+	}
+	if _, ok := obj["$ref"]; ok {
+		return nil, fmt.Errorf("%s: $ref is not supported", path)
+	}
+
+	s := &Schema{}
+	switch t := obj["type"].(type) {
+	case nil:
+	case string:
+		s.types = []string{t}
+	case []any:
+		for _, v := range t {
+			name, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s.type: must be a string or an array of strings", path)
+			}
+			s.types = append(s.types, name)
+		}
+	default:
+		return nil, fmt.Errorf("%s.type: must be a string or an array of strings", path)
+	}
+	for _, t := range s.types {
+		if _, ok := knownTypes[t]; !ok {
+			return nil, fmt.Errorf("%s.type: unknown type %q", path, t)
+		}
+	}
+
+	if props, ok := obj["properties"]; ok {
+		m, ok := props.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s.properties: must be an object", path)
+		}
+		s.properties = make(map[string]*Schema, len(m))
+		for name, sub := range m {
+			child, err := compile(sub, path+".properties."+name)
+			if err != nil {
+				return nil, err
+			}
+			s.properties[name] = child
+		}
+	}
+	if req, ok := obj["required"]; ok {
+		list, ok := req.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s.required: must be an array of strings", path)
+		}
+		for _, v := range list {
+			name, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s.required: must be an array of strings", path)
+			}
+			s.required = append(s.required, name)
+		}
+	}
+	switch ap := obj["additionalProperties"].(type) {
+	case nil:
+	case bool:
+		s.noAdditional = !ap
+	default:
+		child, err := compile(ap, path+".additionalProperties")
+		if err != nil {
+			return nil, err
+		}
+		s.additional = child
+	}
+	if items, ok := obj["items"]; ok {
+		child, err := compile(items, path+".items")
+		if err != nil {
+			return nil, err
+		}
+		s.items = child
+	}
+	if enum, ok := obj["enum"]; ok {
+		list, ok := enum.([]any)
+		if !ok || len(list) == 0 {
+			return nil, fmt.Errorf("%s.enum: must be a non-empty array", path)
+		}
+		s.enum = list
+	}
+	if c, ok := obj["const"]; ok {
+		s.constant, s.hasConst = c, true
+	}
+	for _, kw := range []string{"allOf", "anyOf", "oneOf"} {
+		raw, ok := obj[kw]
+		if !ok {
+			continue
+		}
+		list, ok := raw.([]any)
+		if !ok || len(list) == 0 {
+			return nil, fmt.Errorf("%s.%s: must be a non-empty array", path, kw)
+		}
+		subs := make([]*Schema, 0, len(list))
+		for i, sub := range list {
+			child, err := compile(sub, fmt.Sprintf("%s.%s[%d]", path, kw, i))
+			if err != nil {
+				return nil, err
+			}
+			subs = append(subs, child)
+		}
+		switch kw {
+		case "allOf":
+			s.allOf = subs
+		case "anyOf":
+			s.anyOf = subs
+		case "oneOf":
+			s.oneOf = subs
+		}
+	}
+
+	var err error
+	if s.minLength, err = intKeyword(obj, "minLength", path); err != nil {
+		return nil, err
+	}
+	if s.maxLength, err = intKeyword(obj, "maxLength", path); err != nil {
+		return nil, err
+	}
+	if s.minItems, err = intKeyword(obj, "minItems", path); err != nil {
+		return nil, err
+	}
+	if s.maxItems, err = intKeyword(obj, "maxItems", path); err != nil {
+		return nil, err
+	}
+	if s.minimum, err = numberKeyword(obj, "minimum", path); err != nil {
+		return nil, err
+	}
+	if s.maximum, err = numberKeyword(obj, "maximum", path); err != nil {
+		return nil, err
+	}
+	if s.exclMinimum, err = numberKeyword(obj, "exclusiveMinimum", path); err != nil {
+		return nil, err
+	}
+	if s.exclMaximum, err = numberKeyword(obj, "exclusiveMaximum", path); err != nil {
+		return nil, err
+	}
+	if p, ok := obj["pattern"]; ok {
+		expr, ok := p.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s.pattern: must be a string", path)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("%s.pattern: %v", path, err)
+		}
+		s.pattern = re
+	}
+	return s, nil
+}
+
+func intKeyword(obj map[string]any, key, path string) (*int, error) {
+	v, ok := obj[key]
+	if !ok {
+		return nil, nil
+	}
+	f, ok := v.(float64)
+	if !ok || f < 0 || f != math.Trunc(f) {
+		return nil, fmt.Errorf("%s.%s: must be a non-negative integer", path, key)
+	}
+	n := int(f)
+	return &n, nil
+}
+
+func numberKeyword(obj map[string]any, key, path string) (*float64, error) {
+	v, ok := obj[key]
+	if !ok {
+		return nil, nil
+	}
+	f, ok := v.(float64)
+	if !ok {
+		return nil, fmt.Errorf("%s.%s: must be a number", path, key)
+	}
+	return &f, nil
+}
+
+// ValidateJSON parses raw and validates the result.
+func (s *Schema) ValidateJSON(raw []byte) error {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return &ValidationError{Path: "$", Message: "not valid JSON: " + err.Error()}
+	}
+	return s.Validate(v)
+}
+
+// Validate checks a value decoded by encoding/json (numbers as float64).
+func (s *Schema) Validate(v any) error {
+	return s.validate(v, "$")
+}
+
+func (s *Schema) validate(v any, path string) error {
+	if len(s.types) > 0 && !matchesAnyType(v, s.types) {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("expected %s, got %s", strings.Join(s.types, " or "), typeOf(v))}
+	}
+	if s.hasConst && !reflect.DeepEqual(v, s.constant) {
+		return &ValidationError{Path: path, Message: "does not match const"}
+	}
+	if s.enum != nil {
+		found := false
+		for _, e := range s.enum {
+			if reflect.DeepEqual(v, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return &ValidationError{Path: path, Message: "not one of the enum values"}
+		}
+	}
+	for _, sub := range s.allOf {
+		if err := sub.validate(v, path); err != nil {
+			return err
+		}
+	}
+	if s.anyOf != nil {
+		var first error
+		for _, sub := range s.anyOf {
+			err := sub.validate(v, path)
+			if err == nil {
+				first = nil
+				break
+			}
+			if first == nil {
+				first = err
+			}
+		}
+		if first != nil || len(s.anyOf) == 0 {
+			return &ValidationError{Path: path, Message: "does not match any allowed schema"}
+		}
+	}
+	if s.oneOf != nil {
+		matched := 0
+		for _, sub := range s.oneOf {
+			if sub.validate(v, path) == nil {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("must match exactly one schema, matched %d", matched)}
+		}
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		return s.validateObject(val, path)
+	case []any:
+		if s.minItems != nil && len(val) < *s.minItems {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("must have at least %d items", *s.minItems)}
+		}
+		if s.maxItems != nil && len(val) > *s.maxItems {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("must have at most %d items", *s.maxItems)}
+		}
+		if s.items != nil {
+			for i, item := range val {
+				if err := s.items.validate(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		n := utf8.RuneCountInString(val)
+		if s.minLength != nil && n < *s.minLength {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("must be at least %d characters", *s.minLength)}
+		}
+		if s.maxLength != nil && n > *s.maxLength {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("must be at most %d characters", *s.maxLength)}
+		}
+		if s.pattern != nil && !s.pattern.MatchString(val) {
+			return &ValidationError{Path: path, Message: "does not match pattern " + s.pattern.String()}
+		}
+	case float64:
+		if s.minimum != nil && val < *s.minimum {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("must be >= %v", *s.minimum)}
+		}
+		if s.maximum != nil && val > *s.maximum {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("must be <= %v", *s.maximum)}
+		}
+		if s.exclMinimum != nil && val <= *s.exclMinimum {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("must be > %v", *s.exclMinimum)}
+		}
+		if s.exclMaximum != nil && val >= *s.exclMaximum {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("must be < %v", *s.exclMaximum)}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateObject(obj map[string]any, path string) error {
+	for _, name := range s.required {
+		if _, ok := obj[name]; !ok {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("missing required property %q", name)}
+		}
+	}
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		childPath := path + "." + name
+		if prop, ok := s.properties[name]; ok {
+			if err := prop.validate(obj[name], childPath); err != nil {
+				return err
+			}
+			continue
+		}
+		if s.noAdditional {
+			return &ValidationError{Path: childPath, Message: "additional property not allowed"}
+		}
+		if s.additional != nil {
+			if err := s.additional.validate(obj[name], childPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func matchesAnyType(v any, types []string) bool {
+	for _, t := range types {
+		switch t {
+		case "object":
+			if _, ok := v.(map[string]any); ok {
+				return true
+			}
+		case "array":
+			if _, ok := v.([]any); ok {
+				return true
+			}
+		case "string":
+			if _, ok := v.(string); ok {
+				return true
+			}
+		case "number":
+			if _, ok := v.(float64); ok {
+				return true
+			}
+		case "integer":
+			if f, ok := v.(float64); ok && f == math.Trunc(f) {
+				return true
+			}
+		case "boolean":
+			if _, ok := v.(bool); ok {
+				return true
+			}
+		case "null":
+			if v == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func typeOf(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return "unknown"
+}
+
+// ErrNoExample is returned by Example when it cannot build a conforming value.
+var ErrNoExample = errors.New("schema: cannot build a conforming example")
+
+// Example builds a small value that conforms to the schema, for simulated
+// backends and documentation. Patterns are not taken into account, so the
+// result is re-validated and ErrNoExample returned if it does not conform.
+func (s *Schema) Example() (any, error) {
+	v := s.example()
+	if err := s.Validate(v); err != nil {
+		return nil, ErrNoExample
+	}
+	return v, nil
+}
+
+func (s *Schema) example() any {
+	switch {
+	case s.hasConst:
+		return s.constant
+	case s.enum != nil:
+		return s.enum[0]
+	case len(s.anyOf) > 0:
+		return s.anyOf[0].example()
+	case len(s.oneOf) > 0:
+		return s.oneOf[0].example()
+	case len(s.allOf) > 0 && len(s.types) == 0 && s.properties == nil:
+		return s.allOf[0].example()
+	}
+
+	kind := ""
+	for _, t := range s.types {
+		if t != "null" {
+			kind = t
+			break
+		}
+	}
+	if kind == "" {
+		switch {
+		case len(s.types) > 0:
+			return nil
+		case s.properties != nil || s.required != nil:
+			kind = "object"
+		case s.items != nil:
+			kind = "array"
+		default:
+			return map[string]any{}
+		}
+	}
+
+	switch kind {
+	case "object":
+		obj := make(map[string]any)
+		names := s.required
+		if len(names) == 0 {
+			for name := range s.properties {
+				names = append(names, name)
+			}
+		}
+		for _, name := range names {
+			if prop, ok := s.properties[name]; ok {
+				obj[name] = prop.example()
+			} else {
+				obj[name] = ""
+			}
+		}
+		return obj
+	case "array":
+		n := 0
+		if s.minItems != nil {
+			n = *s.minItems
+		}
+		arr := make([]any, 0, n)
+		for i := 0; i < n; i++ {
+			if s.items != nil {
+				arr = append(arr, s.items.example())
+			} else {
+				arr = append(arr, nil)
+			}
+		}
+		return arr
+	case "string":
+		str := "example"
+		if s.minLength != nil && len(str) < *s.minLength {
+			str += strings.Repeat("x", *s.minLength-len(str))
+		}
+		if s.maxLength != nil && len(str) > *s.maxLength {
+			str = str[:*s.maxLength]
+		}
+		return str
+	case "number", "integer":
+		n := 0.0
+		switch {
+		case s.minimum != nil:
+			n = *s.minimum
+		case s.exclMinimum != nil:
+			n = *s.exclMinimum + 1
+		case s.maximum != nil && *s.maximum < 0:
+			n = *s.maximum
+		case s.exclMaximum != nil && *s.exclMaximum <= 0:
+			n = *s.exclMaximum - 1
+		}
+		if kind == "integer" {
+			n = math.Ceil(n)
+		}
+		return n
+	case "boolean":
+		return false
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+)
+
+const ticketSchema = `{
+	"type": "object",
+	"properties": {
+		"severity": {"type": "string", "enum": ["low", "medium", "high"]},
+		"score": {"type": "integer", "minimum": 0, "maximum": 10},
+		"tags": {"type": "array", "items": {"type": "string", "minLength": 1}, "minItems": 1},
+		"owner": {"type": ["string", "null"]}
+	},
+	"required": ["severity", "score", "tags"],
+	"additionalProperties": false
+}`
+
+func TestValidateJSON(t *testing.T) {
+	s, err := Compile([]byte(ticketSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		doc  string
+		path string
+	}{
+		{`{"severity":"high","score":7,"tags":["auth"],"owner":null}`, ""},
+		{`{"severity":"urgent","score":7,"tags":["auth"]}`, "$.severity"},
+		{`{"severity":"high","score":7.5,"tags":["auth"]}`, "$.score"},
+		{`{"severity":"high","score":11,"tags":["auth"]}`, "$.score"},
+		{`{"severity":"high","score":1,"tags":[""]}`, "$.tags[0]"},
+		{`{"severity":"high","score":1,"tags":[]}`, "$.tags"},
+		{`{"severity":"high","tags":["a"]}`, "$"},
+		{`{"severity":"high","score":1,"tags":["a"],"extra":true}`, "$.extra"},
+		{`not json`, "$"},
+	}
+	for _, tc := range cases {
+		err := s.ValidateJSON([]byte(tc.doc))
+		if tc.path == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tc.doc, err)
+			}
+			continue
+		}
+		var verr *ValidationError
+		if !errors.As(err, &verr) || verr.Path != tc.path {
+			t.Errorf("%s: expected error at %s, got %v", tc.doc, tc.path, err)
+		}
+	}
+}
+
+func TestCompileRejectsInvalidSchemas(t *testing.T) {
+	for _, doc := range []string{
+		`[]`,
+		`{"type":"date"}`,
+		`{"properties":{"a":{"$ref":"#/$defs/a"}}}`,
+		`{"pattern":"("}`,
+		`{"minLength":-1}`,
+	} {
+		if _, err := Compile([]byte(doc)); err == nil {
+			t.Errorf("expected %s to be rejected", doc)
+		}
+	}
+}
+
+func TestExampleConforms(t *testing.T) {
+	s, err := Compile([]byte(ticketSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := s.Example()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Validate(v); err != nil {
+		t.Fatalf("example does not conform: %v", err)
+	}
+}
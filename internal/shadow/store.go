@@ -0,0 +1,110 @@
+package shadow
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// Pair is a completion served by a team's primary model together with the
+// answer of its shadow model to the same request. Input and outputs are
+// redacted. ShadowError is set instead of ShadowOutput when the shadow call
+// failed.
+type Pair struct {
+	Timestamp        time.Time `json:"timestamp"`
+	RequestID        string    `json:"request_id"`
+	Team             string    `json:"team"`
+	PrimaryModel     string    `json:"primary_model"`
+	ShadowModel      string    `json:"shadow_model"`
+	RedactedInput    string    `json:"redacted_input"`
+	PrimaryOutput    string    `json:"primary_output"`
+	ShadowOutput     string    `json:"shadow_output,omitempty"`
+	ShadowError      string    `json:"shadow_error,omitempty"`
+	PrimaryLatencyMS int64     `json:"primary_latency_ms"`
+	ShadowLatencyMS  int64     `json:"shadow_latency_ms"`
+	PrimaryCostUSD   float64   `json:"primary_cost_usd"`
+	ShadowCostUSD    float64   `json:"shadow_cost_usd"`
+}
+
+// Store keeps the most recent pairs in memory and, when it has a file,
+// appends every pair to it as a JSON line for offline comparison.
+type Store struct {
+	mu       sync.Mutex
+	maxPairs int
+	pairs    []Pair
+	file     *os.File
+}
+
+// NewStore keeps up to maxPairs pairs and appends them to path unless path
+// is empty.
+func NewStore(maxPairs int, path string) (*Store, error) {
+	if maxPairs <= 0 {
+		maxPairs = 1000
+	}
+	s := &Store{maxPairs: maxPairs}
+	if path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		s.file = f
+	}
+	return s, nil
+}
+
+// Add stores p; the error reports a failed file write, in which case p is
+// still kept in memory.
+func (s *Store) Add(p Pair) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pairs = append(s.pairs, p)
+	if len(s.pairs) > s.maxPairs {
+		start := len(s.pairs) - s.maxPairs
+		s.pairs = append([]Pair(nil), s.pairs[start:]...)
+	}
+	if s.file == nil {
+		return nil
+	}
+	line, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+// List returns up to limit of team's most recent pairs, newest first.
+func (s *Store) List(team string, limit int) []Pair {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 500 {
+		limit = 500
+	}
+
+	out := make([]Pair, 0, limit)
+	for i := len(s.pairs) - 1; i >= 0 && len(out) < limit; i-- {
+		if team != "" && s.pairs[i].Team != team {
+			continue
+		}
+		out = append(out, s.pairs[i])
+	}
+	return out
+}
+
+// Close closes the store's file, if any.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,46 @@
+package shadow
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreAppendsPairsAndListsNewestFirst(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shadow.jsonl")
+	s, err := NewStore(2, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"req-1", "req-2", "req-3"} {
+		if err := s.Add(Pair{RequestID: id, Team: "red-team"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = s.Add(Pair{RequestID: "req-4", Team: "blue-team"})
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := s.List("red-team", 10); len(got) != 1 || got[0].RequestID != "req-3" {
+		t.Fatalf("expected only the newest retained pair, got %+v", got)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	lines := 0
+	for sc := bufio.NewScanner(f); sc.Scan(); lines++ {
+		var p Pair
+		if err := json.Unmarshal(sc.Bytes(), &p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if lines != 4 {
+		t.Fatalf("expected every pair in the file, got %d lines", lines)
+	}
+}
@@ -33,16 +33,34 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) routes() {
 	h.mux.HandleFunc("/healthz", h.handleHealth)
+	h.mux.HandleFunc("/readyz", h.handleReady)
 	h.mux.Handle("/metrics", promhttp.Handler())
 	h.mux.HandleFunc("/v1/gateway/completions", h.handleCompletion)
+	h.mux.HandleFunc("/v1/chat/completions", h.handleChatCompletions)
+	h.mux.HandleFunc("/v1/embeddings", h.handleEmbeddings)
+	h.mux.HandleFunc("/v1/models", h.handleModels)
 	h.mux.HandleFunc("/v1/teams/me/usage", h.handleUsage)
 	h.mux.HandleFunc("/v1/audit", h.handleAudit)
+	h.mux.HandleFunc("/v1/shadow", h.handleShadow)
+	h.mux.HandleFunc("/v1/jobs", h.handleJobs)
+	h.mux.HandleFunc("/v1/jobs/{id}", h.handleJob)
 }
 
 func (h *Handler) handleHealth(w http.ResponseWriter, _ *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
+// handleReady reports provider health and fails while no provider is usable,
+// so load balancers can take the gateway out of rotation.
+func (h *Handler) handleReady(w http.ResponseWriter, _ *http.Request) {
+	resp := h.app.Readiness()
+	code := http.StatusOK
+	if resp.Status != "ready" {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, resp)
+}
+
 func (h *Handler) handleCompletion(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeJSON(w, http.StatusMethodNotAllowed, contracts.ErrorResponse{Error: "method not allowed", Code: "method_not_allowed"})
@@ -63,6 +81,11 @@ func (h *Handler) handleCompletion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Stream {
+		h.streamCompletion(w, r, requestID, principal, req)
+		return
+	}
+
 	resp, appErr := h.app.HandleCompletion(r.Context(), requestID, principal, req)
 	if appErr != nil {
 		writeJSON(w, appErr.HTTPStatus, appErr.WithRequestID(requestID))
@@ -71,6 +94,69 @@ func (h *Handler) handleCompletion(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, resp)
 }
 
+// webhookURLHeader names the URL a job's result is posted to when it
+// finishes.
+const webhookURLHeader = "X-Webhook-URL"
+
+// handleJobs queues a completion as an asynchronous job. The body is the
+// same as for /v1/gateway/completions.
+func (h *Handler) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, contracts.ErrorResponse{Error: "method not allowed", Code: "method_not_allowed"})
+		return
+	}
+	requestID := reqID()
+	principal, authErr := h.app.Authenticate(r)
+	if authErr != nil {
+		writeJSON(w, authErr.HTTPStatus, authErr.WithRequestID(requestID))
+		return
+	}
+
+	var req contracts.CompletionRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, contracts.ErrorResponse{Error: "invalid JSON body", Code: "invalid_json", RequestID: requestID})
+		return
+	}
+
+	job, appErr := h.app.SubmitJob(r.Context(), requestID, principal, req, strings.TrimSpace(r.Header.Get(webhookURLHeader)))
+	if appErr != nil {
+		writeJSON(w, appErr.HTTPStatus, appErr.WithRequestID(requestID))
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// handleJob returns (GET) or cancels (DELETE) a job.
+func (h *Handler) handleJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		writeJSON(w, http.StatusMethodNotAllowed, contracts.ErrorResponse{Error: "method not allowed", Code: "method_not_allowed"})
+		return
+	}
+	requestID := reqID()
+	principal, authErr := h.app.Authenticate(r)
+	if authErr != nil {
+		writeJSON(w, authErr.HTTPStatus, authErr.WithRequestID(requestID))
+		return
+	}
+
+	var (
+		job    contracts.JobView
+		appErr *app.AppError
+	)
+	if r.Method == http.MethodDelete {
+		job, appErr = h.app.CancelJob(principal, r.PathValue("id"))
+	} else {
+		job, appErr = h.app.Job(principal, r.PathValue("id"))
+	}
+	if appErr != nil {
+		writeJSON(w, appErr.HTTPStatus, appErr.WithRequestID(requestID))
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
 func (h *Handler) handleUsage(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeJSON(w, http.StatusMethodNotAllowed, contracts.ErrorResponse{Error: "method not allowed", Code: "method_not_allowed"})
@@ -106,6 +192,27 @@ func (h *Handler) handleAudit(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]any{"events": h.app.AuditEvents(principal, limit)})
 }
 
+func (h *Handler) handleShadow(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, contracts.ErrorResponse{Error: "method not allowed", Code: "method_not_allowed"})
+		return
+	}
+	requestID := reqID()
+	principal, authErr := h.app.Authenticate(r)
+	if authErr != nil {
+		writeJSON(w, authErr.HTTPStatus, authErr.WithRequestID(requestID))
+		return
+	}
+
+	limit := 50
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil {
+			limit = n
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"pairs": h.app.ShadowPairs(principal, limit)})
+}
+
 func writeJSON(w http.ResponseWriter, code int, payload any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
@@ -0,0 +1,315 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/app"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/auth"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+// handleChatCompletions is an OpenAI-compatible facade over the completion
+// pipeline, so OpenAI SDKs can use the gateway by changing only the base URL.
+func (h *Handler) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeOpenAIError(w, &app.AppError{Code: "method_not_allowed", Message: "method not allowed", HTTPStatus: http.StatusMethodNotAllowed})
+		return
+	}
+	requestID := reqID()
+	principal, authErr := h.app.Authenticate(r)
+	if authErr != nil {
+		writeOpenAIError(w, authErr)
+		return
+	}
+
+	var req contracts.ChatCompletionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeOpenAIError(w, &app.AppError{Code: "invalid_json", Message: "invalid JSON body: " + err.Error(), HTTPStatus: http.StatusBadRequest})
+		return
+	}
+	completionReq, param, appErr := fromChatCompletionRequest(req)
+	if appErr != nil {
+		writeJSON(w, appErr.HTTPStatus, openAIError(appErr, param))
+		return
+	}
+
+	if req.Stream {
+		includeUsage := req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+		h.streamChatCompletion(w, r, requestID, principal, completionReq, includeUsage)
+		return
+	}
+
+	resp, appErr := h.app.HandleCompletion(r.Context(), requestID, principal, completionReq)
+	if appErr != nil {
+		writeOpenAIError(w, appErr)
+		return
+	}
+	writeJSON(w, http.StatusOK, contracts.ChatCompletionResponse{
+		ID:      chatCompletionID(requestID),
+		Object:  "chat.completion",
+		Created: resp.ProcessedAt.Unix(),
+		Model:   resp.Model,
+		Choices: []contracts.ChatChoice{{
+			Index:        0,
+			Message:      contracts.ChatResponseMessage{Role: "assistant", Content: resp.Output, ToolCalls: chatToolCalls(resp.ToolCalls)},
+			FinishReason: finishReason(resp),
+		}},
+		Usage: chatUsage(resp),
+	})
+}
+
+func (h *Handler) streamChatCompletion(w http.ResponseWriter, r *http.Request, requestID string, principal auth.Principal, req contracts.CompletionRequest, includeUsage bool) {
+	sse := newSSEWriter(w)
+	id := chatCompletionID(requestID)
+	created := time.Now().Unix()
+	first := true
+
+	resp, appErr := h.app.HandleCompletionStream(r.Context(), requestID, principal, req, func(model, delta string) error {
+		chunk := contracts.ChatDelta{Content: delta}
+		if first {
+			chunk.Role = "assistant"
+			first = false
+		}
+		return sse.event("", contracts.ChatCompletionChunk{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   model,
+			Choices: []contracts.ChatChunkChoice{{Index: 0, Delta: chunk}},
+		})
+	})
+	if appErr != nil {
+		if !sse.started {
+			writeOpenAIError(w, appErr)
+			return
+		}
+		_ = sse.event("", openAIError(appErr, ""))
+		return
+	}
+
+	// Tool calls are only known once the upstream call finished, so they are
+	// sent whole in one chunk ahead of the finish chunk.
+	if len(resp.ToolCalls) > 0 {
+		calls := make([]contracts.ChatToolCallDelta, 0, len(resp.ToolCalls))
+		for i, call := range chatToolCalls(resp.ToolCalls) {
+			calls = append(calls, contracts.ChatToolCallDelta{Index: i, ID: call.ID, Type: call.Type, Function: call.Function})
+		}
+		delta := contracts.ChatDelta{ToolCalls: calls}
+		if first {
+			delta.Role = "assistant"
+		}
+		if err := sse.event("", contracts.ChatCompletionChunk{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   resp.Model,
+			Choices: []contracts.ChatChunkChoice{{Index: 0, Delta: delta}},
+		}); err != nil {
+			return
+		}
+	}
+	stop := finishReason(resp)
+	final := contracts.ChatCompletionChunk{
+		ID:      id,
+		Object:  "chat.completion.chunk",
+		Created: created,
+		Model:   resp.Model,
+		Choices: []contracts.ChatChunkChoice{{Index: 0, FinishReason: &stop}},
+	}
+	if err := sse.event("", final); err != nil {
+		return
+	}
+	if includeUsage {
+		usage := chatUsage(resp)
+		if err := sse.event("", contracts.ChatCompletionChunk{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   resp.Model,
+			Choices: []contracts.ChatChunkChoice{},
+			Usage:   &usage,
+		}); err != nil {
+			return
+		}
+	}
+	_ = sse.write("", []byte("[DONE]"))
+}
+
+// handleEmbeddings serves OpenAI-compatible embeddings so RAG pipelines can be
+// governed by the same team budgets and allowlists as completions.
+func (h *Handler) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeOpenAIError(w, &app.AppError{Code: "method_not_allowed", Message: "method not allowed", HTTPStatus: http.StatusMethodNotAllowed})
+		return
+	}
+	requestID := reqID()
+	principal, authErr := h.app.Authenticate(r)
+	if authErr != nil {
+		writeOpenAIError(w, authErr)
+		return
+	}
+
+	var req contracts.EmbeddingRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeOpenAIError(w, &app.AppError{Code: "invalid_json", Message: "invalid JSON body: " + err.Error(), HTTPStatus: http.StatusBadRequest})
+		return
+	}
+	resp, appErr := h.app.HandleEmbedding(r.Context(), requestID, principal, req)
+	if appErr != nil {
+		writeOpenAIError(w, appErr)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleModels lists the caller's models in OpenAI format, extended with the
+// model catalog, so SDKs and UIs can discover what a team key may use.
+func (h *Handler) handleModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeOpenAIError(w, &app.AppError{Code: "method_not_allowed", Message: "method not allowed", HTTPStatus: http.StatusMethodNotAllowed})
+		return
+	}
+	principal, authErr := h.app.Authenticate(r)
+	if authErr != nil {
+		writeOpenAIError(w, authErr)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.app.Models(principal))
+}
+
+// fromChatCompletionRequest maps an OpenAI chat request onto the gateway
+// request. Turns, function tools, tool_choice and response_format are passed
+// through as structured fields; "developer" turns are treated as system
+// turns. temperature, top_p, stop and max_tokens (max_completion_tokens when
+// both are set) are forwarded; other sampling parameters are accepted for SDK
+// compatibility but ignored. On failure it also returns the offending
+// parameter.
+func fromChatCompletionRequest(req contracts.ChatCompletionRequest) (contracts.CompletionRequest, string, *app.AppError) {
+	if len(req.Messages) == 0 {
+		return contracts.CompletionRequest{}, "messages", invalidRequest("messages must not be empty")
+	}
+	if req.N != nil && *req.N != 1 {
+		return contracts.CompletionRequest{}, "n", invalidRequest("only n=1 is supported")
+	}
+	tools := make([]contracts.Tool, 0, len(req.Tools))
+	for _, t := range req.Tools {
+		if t.Type != "function" {
+			return contracts.CompletionRequest{}, "tools", invalidRequest("only function tools are supported")
+		}
+		tools = append(tools, contracts.Tool{Name: t.Function.Name, Description: t.Function.Description, Parameters: t.Function.Parameters})
+	}
+	var format *contracts.ResponseFormat
+	if rf := req.ResponseFormat; rf != nil {
+		format = &contracts.ResponseFormat{Type: rf.Type}
+		if rf.JSONSchema != nil {
+			format.Name, format.Schema = rf.JSONSchema.Name, rf.JSONSchema.Schema
+		}
+		if rf.Type == app.FormatJSONSchema && len(format.Schema) == 0 {
+			return contracts.CompletionRequest{}, "response_format", invalidRequest("response_format.json_schema.schema is required")
+		}
+	}
+
+	messages := make([]contracts.Message, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		role := m.Role
+		switch role {
+		case app.RoleSystem, app.RoleUser, app.RoleAssistant, app.RoleTool:
+		case "developer":
+			role = app.RoleSystem
+		default:
+			return contracts.CompletionRequest{}, "messages", invalidRequest("unsupported message role: " + m.Role)
+		}
+		msg := contracts.Message{Role: role, Content: string(m.Content), ToolCallID: m.ToolCallID}
+		for _, call := range m.ToolCalls {
+			msg.ToolCalls = append(msg.ToolCalls, contracts.ToolCall{ID: call.ID, Name: call.Function.Name, Arguments: call.Function.Arguments})
+		}
+		messages = append(messages, msg)
+	}
+	if len(tools) == 0 {
+		tools = nil
+	}
+	maxTokens := req.MaxTokens
+	if req.MaxCompletionTokens != nil {
+		maxTokens = req.MaxCompletionTokens
+	}
+	return contracts.CompletionRequest{
+		Model:          req.Model,
+		Messages:       messages,
+		Tools:          tools,
+		ToolChoice:     string(req.ToolChoice),
+		ResponseFormat: format,
+		MaxTokens:      maxTokens,
+		Temperature:    req.Temperature,
+		TopP:           req.TopP,
+		Stop:           req.Stop,
+		User:           req.User,
+	}, "", nil
+}
+
+func invalidRequest(msg string) *app.AppError {
+	return &app.AppError{Code: "invalid_request", Message: msg, HTTPStatus: http.StatusBadRequest}
+}
+
+func chatCompletionID(requestID string) string {
+	return "chatcmpl-" + strings.TrimPrefix(requestID, "req-")
+}
+
+func chatToolCalls(calls []contracts.ToolCall) []contracts.ChatToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]contracts.ChatToolCall, 0, len(calls))
+	for _, call := range calls {
+		out = append(out, contracts.ChatToolCall{
+			ID:       call.ID,
+			Type:     "function",
+			Function: contracts.ChatFunctionCall{Name: call.Name, Arguments: call.Arguments},
+		})
+	}
+	return out
+}
+
+func finishReason(resp contracts.CompletionResponse) string {
+	if len(resp.ToolCalls) > 0 {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+func chatUsage(resp contracts.CompletionResponse) contracts.ChatUsage {
+	return contracts.ChatUsage{
+		PromptTokens:     resp.InputTokens,
+		CompletionTokens: resp.OutputTokens,
+		TotalTokens:      resp.InputTokens + resp.OutputTokens,
+	}
+}
+
+func writeOpenAIError(w http.ResponseWriter, appErr *app.AppError) {
+	writeJSON(w, appErr.HTTPStatus, openAIError(appErr, ""))
+}
+
+// openAIError shapes a gateway error as an OpenAI error object; the gateway
+// error code is kept in "code" and the type follows OpenAI's conventions.
+func openAIError(appErr *app.AppError, param string) contracts.OpenAIErrorResponse {
+	errType := "api_error"
+	switch {
+	case appErr.HTTPStatus == http.StatusUnauthorized:
+		errType = "authentication_error"
+	case appErr.HTTPStatus == http.StatusForbidden:
+		errType = "permission_error"
+	case appErr.HTTPStatus == http.StatusPaymentRequired:
+		errType = "insufficient_quota"
+	case appErr.HTTPStatus == http.StatusTooManyRequests:
+		errType = "rate_limit_error"
+	case appErr.HTTPStatus >= 400 && appErr.HTTPStatus < 500:
+		errType = "invalid_request_error"
+	}
+	out := contracts.OpenAIErrorResponse{Error: contracts.OpenAIError{Message: appErr.Message, Type: errType, Code: appErr.Code}}
+	if param != "" {
+		out.Error.Param = &param
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/app"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/auth"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+// sseWriter lazily switches a response to text/event-stream on the first
+// event, so errors raised before any output can still be sent as plain JSON.
+type sseWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	started bool
+}
+
+func newSSEWriter(w http.ResponseWriter) *sseWriter {
+	return &sseWriter{w: w, rc: http.NewResponseController(w)}
+}
+
+func (s *sseWriter) event(name string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return s.write(name, data)
+}
+
+func (s *sseWriter) write(name string, data []byte) error {
+	if !s.started {
+		h := s.w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("Connection", "keep-alive")
+		h.Set("X-Accel-Buffering", "no")
+		s.w.WriteHeader(http.StatusOK)
+		s.started = true
+	}
+	if name != "" {
+		if _, err := fmt.Fprintf(s.w, "event: %s\n", name); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(s.w, "data: %s\n\n", data); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// streamCompletion serves a completion as SSE: a "delta" event per chunk, then
+// a "done" event carrying the final CompletionResponse, or an "error" event if
+// the stream fails after it started.
+func (h *Handler) streamCompletion(w http.ResponseWriter, r *http.Request, requestID string, principal auth.Principal, req contracts.CompletionRequest) {
+	sse := newSSEWriter(w)
+	resp, appErr := h.app.HandleCompletionStream(r.Context(), requestID, principal, req, func(_, delta string) error {
+		return sse.event("delta", contracts.StreamDelta{Delta: delta})
+	})
+	if appErr != nil {
+		writeStreamError(w, sse, requestID, appErr)
+		return
+	}
+	if err := sse.event("done", resp); err != nil {
+		h.logger.Warn("stream completion not delivered", "request_id", requestID, "err", err)
+	}
+}
+
+func writeStreamError(w http.ResponseWriter, sse *sseWriter, requestID string, appErr *app.AppError) {
+	if !sse.started {
+		writeJSON(w, appErr.HTTPStatus, appErr.WithRequestID(requestID))
+		return
+	}
+	_ = sse.event("error", appErr.WithRequestID(requestID))
+}
@@ -1,24 +1,95 @@
 package contracts
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // CompletionRequest is a normalized request accepted by the gateway.
+// Either Input (a single user turn) or Messages (a conversation with system,
+// user, assistant and tool turns) must be set. Stream switches the response to
+// Server-Sent Events. Tools declares functions the model may call; ToolChoice
+// is "auto" (default), "none", "required" or the name of one declared tool.
+// ResponseFormat requests structured output and cannot be combined with Stream.
+// MaxTokens, Temperature, TopP and Stop are passed to the provider; unset
+// values leave the provider's defaults. Model may name an alias, or "auto" to
+// let the gateway pick the cheapest allowed model that fits.
 type CompletionRequest struct {
-	Model string `json:"model"`
-	Input string `json:"input"`
+	Model          string          `json:"model"`
+	Input          string          `json:"input,omitempty"`
+	Messages       []Message       `json:"messages,omitempty"`
+	Tools          []Tool          `json:"tools,omitempty"`
+	ToolChoice     string          `json:"tool_choice,omitempty"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	Stream         bool            `json:"stream,omitempty"`
+	MaxTokens      *int            `json:"max_tokens,omitempty"`
+	Temperature    *float64        `json:"temperature,omitempty"`
+	TopP           *float64        `json:"top_p,omitempty"`
+	Stop           []string        `json:"stop,omitempty"`
+	User           string          `json:"user,omitempty"`
 }
 
-// CompletionResponse is returned for successful requests.
+// ResponseFormat asks for structured output. Type is "text" (default),
+// "json_object" (any JSON object) or "json_schema" (JSON matching Schema).
+// MaxReasks lowers how many times the model is asked to correct output that
+// does not conform; it cannot exceed the gateway's configured limit.
+type ResponseFormat struct {
+	Type      string          `json:"type"`
+	Name      string          `json:"name,omitempty"`
+	Schema    json.RawMessage `json:"schema,omitempty"`
+	MaxReasks *int            `json:"max_reasks,omitempty"`
+}
+
+// Message is one conversation turn. Assistant turns may carry the ToolCalls
+// the model made; a "tool" turn returns the result for ToolCallID.
+type Message struct {
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+// Tool declares a function the model may call. Parameters is a JSON Schema
+// object describing the arguments.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// ToolCall is a model's request to invoke a tool. Arguments is a JSON object
+// encoded as a string.
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// StreamDelta is the payload of a "delta" event in a streamed completion.
+type StreamDelta struct {
+	Delta string `json:"delta"`
+}
+
+// CompletionResponse is returned for successful requests. Model is the model
+// that served the request; SelectionReason explains the pick for "auto".
+// Experiment and Variant are set when the request was enrolled in an A/B
+// experiment.
 type CompletionResponse struct {
-	RequestID      string    `json:"request_id"`
-	Team           string    `json:"team"`
-	Model          string    `json:"model"`
-	Output         string    `json:"output"`
-	InputTokens    int       `json:"input_tokens"`
-	OutputTokens   int       `json:"output_tokens"`
-	CostUSD        float64   `json:"cost_usd"`
-	PolicyDecision string    `json:"policy_decision"`
-	ProcessedAt    time.Time `json:"processed_at"`
+	RequestID       string     `json:"request_id"`
+	Team            string     `json:"team"`
+	Model           string     `json:"model"`
+	RequestedModel  string     `json:"requested_model"`
+	Alias           string     `json:"alias,omitempty"`
+	SelectionReason string     `json:"selection_reason,omitempty"`
+	Experiment      string     `json:"experiment,omitempty"`
+	Variant         string     `json:"variant,omitempty"`
+	Output          string     `json:"output"`
+	ToolCalls       []ToolCall `json:"tool_calls,omitempty"`
+	InputTokens     int        `json:"input_tokens"`
+	OutputTokens    int        `json:"output_tokens"`
+	CostUSD         float64    `json:"cost_usd"`
+	PolicyDecision  string     `json:"policy_decision"`
+	ProcessedAt     time.Time  `json:"processed_at"`
 }
 
 // ErrorResponse is used for policy/rate/budget and validation errors.
@@ -28,7 +99,8 @@ type ErrorResponse struct {
 	RequestID string `json:"request_id,omitempty"`
 }
 
-// UsageResponse returns current team usage and budget state.
+// UsageResponse returns current team usage and budget state. Shadow calls
+// are reported separately and do not count against the budget.
 type UsageResponse struct {
 	Team               string             `json:"team"`
 	TotalRequests      int64              `json:"total_requests"`
@@ -38,17 +110,91 @@ type UsageResponse struct {
 	MonthlyBudgetUSD   float64            `json:"monthly_budget_usd"`
 	RemainingBudgetUSD float64            `json:"remaining_budget_usd"`
 	PerModel           map[string]float64 `json:"per_model_cost_usd"`
+	ShadowRequests     int64              `json:"shadow_requests,omitempty"`
+	ShadowCostUSD      float64            `json:"shadow_cost_usd,omitempty"`
+}
+
+// ReadinessResponse is returned by /readyz. Status is "ready" unless every
+// probed provider is failing its health checks.
+type ReadinessResponse struct {
+	Status    string               `json:"status"`
+	Providers []ProviderHealthView `json:"providers"`
+}
+
+// ProviderHealthView is one provider's health-probe state. Status is
+// "unknown" (not probed yet, or not probeable), "healthy" or "unhealthy";
+// LastError is the gateway error code of the last failed probe.
+type ProviderHealthView struct {
+	Provider            string     `json:"provider"`
+	Status              string     `json:"status"`
+	LastCheckedAt       *time.Time `json:"last_checked_at,omitempty"`
+	LatencyMS           int64      `json:"latency_ms"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
 }
 
 // AuditEventView is a scrubbed view returned by audit API.
 type AuditEventView struct {
-	Timestamp     time.Time `json:"timestamp"`
-	RequestID     string    `json:"request_id"`
-	Team          string    `json:"team"`
-	Model         string    `json:"model"`
-	Status        string    `json:"status"`
-	DenyReason    string    `json:"deny_reason,omitempty"`
-	RedactedInput string    `json:"redacted_input"`
-	CostUSD       float64   `json:"cost_usd"`
-	LatencyMS     int64     `json:"latency_ms"`
+	Timestamp        time.Time          `json:"timestamp"`
+	RequestID        string             `json:"request_id"`
+	Team             string             `json:"team"`
+	Operation        string             `json:"operation"`
+	Model            string             `json:"model"`
+	RequestedModel   string             `json:"requested_model,omitempty"`
+	Alias            string             `json:"alias,omitempty"`
+	SelectionReason  string             `json:"selection_reason,omitempty"`
+	Experiment       string             `json:"experiment,omitempty"`
+	Variant          string             `json:"variant,omitempty"`
+	Status           string             `json:"status"`
+	DenyReason       string             `json:"deny_reason,omitempty"`
+	RedactedInput    string             `json:"redacted_input"`
+	RedactedMessages []Message          `json:"redacted_messages,omitempty"`
+	CostUSD          float64            `json:"cost_usd"`
+	LatencyMS        int64              `json:"latency_ms"`
+	Tools            []string           `json:"tools,omitempty"`
+	ToolCalls        []string           `json:"tool_calls,omitempty"`
+	Attempts         []AuditAttemptView `json:"attempts,omitempty"`
+}
+
+// AuditAttemptView describes one upstream attempt made while serving a request.
+type AuditAttemptView struct {
+	Model     string  `json:"model"`
+	Status    string  `json:"status"`
+	Reason    string  `json:"reason,omitempty"`
+	LatencyMS int64   `json:"latency_ms"`
+	CostUSD   float64 `json:"cost_usd,omitempty"`
+}
+
+// ShadowPairView is a completion next to its shadow model's answer, as
+// returned by /v1/shadow. Input and outputs are redacted.
+type ShadowPairView struct {
+	Timestamp        time.Time `json:"timestamp"`
+	RequestID        string    `json:"request_id"`
+	Team             string    `json:"team"`
+	PrimaryModel     string    `json:"primary_model"`
+	ShadowModel      string    `json:"shadow_model"`
+	RedactedInput    string    `json:"redacted_input"`
+	PrimaryOutput    string    `json:"primary_output"`
+	ShadowOutput     string    `json:"shadow_output,omitempty"`
+	ShadowError      string    `json:"shadow_error,omitempty"`
+	PrimaryLatencyMS int64     `json:"primary_latency_ms"`
+	ShadowLatencyMS  int64     `json:"shadow_latency_ms"`
+	PrimaryCostUSD   float64   `json:"primary_cost_usd"`
+	ShadowCostUSD    float64   `json:"shadow_cost_usd"`
+}
+
+// JobView is an asynchronous completion job as returned by /v1/jobs and sent
+// to its webhook. Result is set once the job succeeded and Error once it
+// failed.
+type JobView struct {
+	ID            string              `json:"id"`
+	Status        string              `json:"status"`
+	Model         string              `json:"model"`
+	RequestID     string              `json:"request_id"`
+	Result        *CompletionResponse `json:"result,omitempty"`
+	Error         *ErrorResponse      `json:"error,omitempty"`
+	WebhookStatus string              `json:"webhook_status,omitempty"`
+	CreatedAt     time.Time           `json:"created_at"`
+	StartedAt     *time.Time          `json:"started_at,omitempty"`
+	FinishedAt    *time.Time          `json:"finished_at,omitempty"`
 }
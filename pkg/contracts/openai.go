@@ -0,0 +1,304 @@
+package contracts
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ChatCompletionRequest is the OpenAI-compatible request accepted by
+// /v1/chat/completions. Fields the gateway does not act on are accepted so
+// that stock SDKs work unchanged.
+type ChatCompletionRequest struct {
+	Model               string              `json:"model"`
+	Messages            []ChatMessage       `json:"messages"`
+	Temperature         *float64            `json:"temperature,omitempty"`
+	TopP                *float64            `json:"top_p,omitempty"`
+	MaxTokens           *int                `json:"max_tokens,omitempty"`
+	MaxCompletionTokens *int                `json:"max_completion_tokens,omitempty"`
+	Stop                StopSequences       `json:"stop,omitempty"`
+	N                   *int                `json:"n,omitempty"`
+	User                string              `json:"user,omitempty"`
+	Stream              bool                `json:"stream,omitempty"`
+	StreamOptions       *ChatStreamOptions  `json:"stream_options,omitempty"`
+	PresencePenalty     *float64            `json:"presence_penalty,omitempty"`
+	FrequencyPenalty    *float64            `json:"frequency_penalty,omitempty"`
+	Seed                *int                `json:"seed,omitempty"`
+	Metadata            map[string]string   `json:"metadata,omitempty"`
+	LogitBias           map[string]float64  `json:"logit_bias,omitempty"`
+	ResponseFormat      *ChatResponseFormat `json:"response_format,omitempty"`
+	ToolChoice          ChatToolChoice      `json:"tool_choice,omitempty"`
+	Tools               []ChatTool          `json:"tools,omitempty"`
+}
+
+// ChatResponseFormat is OpenAI's response_format: "text", "json_object" or
+// "json_schema" with the schema in JSONSchema.
+type ChatResponseFormat struct {
+	Type       string          `json:"type"`
+	JSONSchema *ChatJSONSchema `json:"json_schema,omitempty"`
+}
+
+// ChatJSONSchema names the schema a json_schema response must match. Strict
+// is accepted for compatibility; the gateway always validates.
+type ChatJSONSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema,omitempty"`
+	Strict *bool           `json:"strict,omitempty"`
+}
+
+// ChatTool declares a callable function in OpenAI format.
+type ChatTool struct {
+	Type     string       `json:"type"`
+	Function ChatFunction `json:"function"`
+}
+
+// ChatFunction describes a function tool; Parameters is a JSON Schema object.
+type ChatFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// ChatToolChoice accepts a mode string ("auto", "none", "required") or a
+// {"type":"function","function":{"name":...}} selector, which is reduced to
+// the function name.
+type ChatToolChoice string
+
+func (c *ChatToolChoice) UnmarshalJSON(b []byte) error {
+	var mode string
+	if err := json.Unmarshal(b, &mode); err == nil {
+		*c = ChatToolChoice(mode)
+		return nil
+	}
+	var selector struct {
+		Type     string `json:"type"`
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(b, &selector); err != nil || selector.Type != "function" || selector.Function.Name == "" {
+		return errors.New("tool_choice must be a mode string or a function selector")
+	}
+	*c = ChatToolChoice(selector.Function.Name)
+	return nil
+}
+
+// ChatToolCall is a function call made by the assistant.
+type ChatToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ChatFunctionCall `json:"function"`
+}
+
+// ChatFunctionCall names the function and carries its JSON-encoded arguments.
+type ChatFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// ChatToolCallDelta is a tool call inside a streamed delta.
+type ChatToolCallDelta struct {
+	Index    int              `json:"index"`
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ChatFunctionCall `json:"function"`
+}
+
+// ChatStreamOptions mirrors OpenAI's stream_options.
+type ChatStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// ChatMessage is one conversation turn in OpenAI format. Assistant turns may
+// carry ToolCalls; "tool" turns answer the call named by ToolCallID.
+type ChatMessage struct {
+	Role       string         `json:"role"`
+	Content    ChatContent    `json:"content"`
+	Name       string         `json:"name,omitempty"`
+	ToolCalls  []ChatToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+}
+
+// ChatContent accepts both a plain string and an array of text content parts.
+type ChatContent string
+
+func (c *ChatContent) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*c = ChatContent(s)
+		return nil
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(b, &parts); err != nil {
+		return errors.New("content must be a string or an array of content parts")
+	}
+	var sb strings.Builder
+	for _, p := range parts {
+		if p.Type != "text" {
+			return errors.New("only text content parts are supported")
+		}
+		sb.WriteString(p.Text)
+	}
+	*c = ChatContent(sb.String())
+	return nil
+}
+
+// StopSequences accepts either a single stop string or a list of them.
+type StopSequences []string
+
+func (s *StopSequences) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*s = StopSequences{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return errors.New("stop must be a string or an array of strings")
+	}
+	*s = many
+	return nil
+}
+
+// ChatCompletionResponse is the OpenAI-compatible non-streaming response.
+type ChatCompletionResponse struct {
+	ID      string       `json:"id"`
+	Object  string       `json:"object"`
+	Created int64        `json:"created"`
+	Model   string       `json:"model"`
+	Choices []ChatChoice `json:"choices"`
+	Usage   ChatUsage    `json:"usage"`
+}
+
+// ChatChoice is one generated alternative.
+type ChatChoice struct {
+	Index        int                 `json:"index"`
+	Message      ChatResponseMessage `json:"message"`
+	FinishReason string              `json:"finish_reason"`
+}
+
+// ChatResponseMessage is the assistant turn returned in a choice.
+type ChatResponseMessage struct {
+	Role      string         `json:"role"`
+	Content   string         `json:"content"`
+	ToolCalls []ChatToolCall `json:"tool_calls,omitempty"`
+}
+
+// ChatUsage reports token usage in OpenAI format.
+type ChatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ChatCompletionChunk is one streamed event in OpenAI format.
+type ChatCompletionChunk struct {
+	ID      string            `json:"id"`
+	Object  string            `json:"object"`
+	Created int64             `json:"created"`
+	Model   string            `json:"model"`
+	Choices []ChatChunkChoice `json:"choices"`
+	Usage   *ChatUsage        `json:"usage,omitempty"`
+}
+
+// ChatChunkChoice carries a streamed delta.
+type ChatChunkChoice struct {
+	Index        int       `json:"index"`
+	Delta        ChatDelta `json:"delta"`
+	FinishReason *string   `json:"finish_reason"`
+}
+
+// ChatDelta is the incremental part of a streamed assistant turn.
+type ChatDelta struct {
+	Role      string              `json:"role,omitempty"`
+	Content   string              `json:"content,omitempty"`
+	ToolCalls []ChatToolCallDelta `json:"tool_calls,omitempty"`
+}
+
+// OpenAIErrorResponse is the OpenAI error envelope.
+type OpenAIErrorResponse struct {
+	Error OpenAIError `json:"error"`
+}
+
+// OpenAIError describes a failed request in OpenAI format. Code carries the
+// gateway error code.
+type OpenAIError struct {
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Param   *string `json:"param"`
+	Code    string  `json:"code"`
+}
+
+// EmbeddingRequest is the OpenAI-compatible /v1/embeddings request. Only
+// float encoding is supported.
+type EmbeddingRequest struct {
+	Model          string         `json:"model"`
+	Input          EmbeddingInput `json:"input"`
+	EncodingFormat string         `json:"encoding_format,omitempty"`
+	Dimensions     *int           `json:"dimensions,omitempty"`
+	User           string         `json:"user,omitempty"`
+}
+
+// EmbeddingInput accepts either a single text or a list of texts.
+type EmbeddingInput []string
+
+func (in *EmbeddingInput) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*in = EmbeddingInput{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return errors.New("input must be a string or an array of strings")
+	}
+	*in = many
+	return nil
+}
+
+// EmbeddingResponse is the OpenAI-compatible embeddings response.
+type EmbeddingResponse struct {
+	Object string          `json:"object"`
+	Data   []EmbeddingData `json:"data"`
+	Model  string          `json:"model"`
+	Usage  EmbeddingUsage  `json:"usage"`
+}
+
+// EmbeddingData is the vector for the input at Index.
+type EmbeddingData struct {
+	Object    string    `json:"object"`
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// EmbeddingUsage reports token usage for an embeddings call.
+type EmbeddingUsage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+// ModelList is the OpenAI-compatible response of GET /v1/models.
+type ModelList struct {
+	Object string      `json:"object"`
+	Data   []ModelCard `json:"data"`
+}
+
+// ModelCard describes one model the caller may use. Besides OpenAI's fields
+// it carries the gateway's catalog data and effective prices; catalog fields
+// are omitted for models without a catalog entry.
+type ModelCard struct {
+	ID                  string   `json:"id"`
+	Object              string   `json:"object"`
+	Created             int64    `json:"created"`
+	OwnedBy             string   `json:"owned_by"`
+	ContextWindow       int      `json:"context_window,omitempty"`
+	MaxOutputTokens     int      `json:"max_output_tokens,omitempty"`
+	Modalities          []string `json:"modalities,omitempty"`
+	SupportsStreaming   *bool    `json:"supports_streaming,omitempty"`
+	SupportsTools       *bool    `json:"supports_tools,omitempty"`
+	InputPricePer1KUSD  float64  `json:"input_price_per_1k_usd"`
+	OutputPricePer1KUSD float64  `json:"output_price_per_1k_usd"`
+}
@@ -0,0 +1,39 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/app"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+)
+
+func TestCassetteRecordThenReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "completions.json")
+	serve := func(provider config.ProviderConfig) *httptest.Server {
+		cfg := config.Default()
+		cfg.Providers = []config.ProviderConfig{provider}
+		router, err := app.NewRouter(cfg, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv := newTestServerWithClient(t, cfg, router)
+		t.Cleanup(srv.Close)
+		return srv
+	}
+
+	recorder := serve(config.ProviderConfig{Name: "simulated", Type: "simulated", Cassette: path})
+	if code, out := chatOutcome(t, recorder, "demo-red-key", "gpt-4o-mini"); code != http.StatusOK {
+		t.Fatalf("recording run failed: %d %+v", code, out)
+	}
+
+	replay := serve(config.ProviderConfig{Name: "simulated", Type: "replay", Cassette: path})
+	if code, out := chatOutcome(t, replay, "demo-red-key", "gpt-4o-mini"); code != http.StatusOK || out.Model != "gpt-4o-mini" {
+		t.Fatalf("expected the recorded completion to replay, got %d %+v", code, out)
+	}
+	if code, out := chatOutcome(t, replay, "demo-red-key", "gpt-4.1-mini"); code != http.StatusInternalServerError || out.Error.Code != app.CodeCassetteMiss {
+		t.Fatalf("expected an unrecorded request to fail with %s, got %d %+v", app.CodeCassetteMiss, code, out)
+	}
+}
@@ -0,0 +1,86 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+)
+
+func postEmbeddings(t *testing.T, url, apiKey, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url+"/v1/embeddings", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestEmbeddingsEndpointBillsTeam(t *testing.T) {
+	srv := newTestServer(t, config.Default())
+	defer srv.Close()
+
+	resp := postEmbeddings(t, srv.URL, "demo-red-key", `{"model":"text-embedding-3-small","input":["runbook: reset MFA","runbook: rotate keys"]}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var out struct {
+		Object string `json:"object"`
+		Data   []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Object != "list" || len(out.Data) != 2 || len(out.Data[0].Embedding) == 0 || out.Usage.PromptTokens == 0 {
+		t.Fatalf("unexpected embeddings response: %+v", out)
+	}
+
+	usageReq, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/teams/me/usage", nil)
+	usageReq.Header.Set("X-API-Key", "demo-red-key")
+	usageResp, err := http.DefaultClient.Do(usageReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer usageResp.Body.Close()
+	var usage struct {
+		PerModel map[string]float64 `json:"per_model_cost_usd"`
+	}
+	if err := json.NewDecoder(usageResp.Body).Decode(&usage); err != nil {
+		t.Fatal(err)
+	}
+	if usage.PerModel["text-embedding-3-small"] <= 0 {
+		t.Fatalf("expected embedding cost in usage, got %+v", usage.PerModel)
+	}
+}
+
+func TestEmbeddingsEndpointEnforcesAllowlist(t *testing.T) {
+	srv := newTestServer(t, config.Default())
+	defer srv.Close()
+
+	// blue-team is not allowed to use the embedding model.
+	resp := postEmbeddings(t, srv.URL, "demo-blue-key", `{"model":"text-embedding-3-small","input":"hello"}`)
+	defer resp.Body.Close()
+	var out struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusForbidden || out.Error.Code != "policy_denied" {
+		t.Fatalf("expected 403 policy_denied, got %d %s", resp.StatusCode, out.Error.Code)
+	}
+}
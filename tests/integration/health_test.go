@@ -0,0 +1,70 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/app"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+func getReadiness(t *testing.T, url string) (int, contracts.ReadinessResponse) {
+	t.Helper()
+	resp, err := http.Get(url + "/readyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out contracts.ReadinessResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, out
+}
+
+func TestReadyzReportsProviderHealth(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer upstream.Close()
+
+	cfg := config.Default()
+	cfg.Providers = []config.ProviderConfig{{Name: "openai", Type: "openai", BaseURL: upstream.URL}}
+	cfg.ModelRoutes = map[string]string{"gpt-*": "openai"}
+	cfg.HealthCheck = config.HealthCheckConfig{IntervalMS: 10, UnhealthyThreshold: 1}
+	router, err := app.NewRouter(cfg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newTestServerWithClient(t, cfg, router)
+	defer srv.Close()
+
+	if code, out := getReadiness(t, srv.URL); code != http.StatusOK || out.Providers[0].Status != app.HealthUnknown {
+		t.Fatalf("expected ready before the first probe, got %d %+v", code, out)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go router.RunHealthChecks(ctx)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		code, out := getReadiness(t, srv.URL)
+		if code == http.StatusServiceUnavailable {
+			p := out.Providers[0]
+			if out.Status != "not_ready" || p.Provider != "openai" || p.Status != app.HealthUnhealthy || p.LastError != app.CodeUpstreamUnavailable || p.LastCheckedAt == nil {
+				t.Fatalf("unexpected readiness: %+v", out)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("provider never marked unhealthy: %+v", out)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
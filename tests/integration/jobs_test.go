@@ -0,0 +1,55 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/app"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+func TestJobsEndpointQueuesAndCancels(t *testing.T) {
+	srv := newTestServer(t, config.Default())
+	defer srv.Close()
+
+	call := func(method, path, key, body string) (int, contracts.JobView, contracts.ErrorResponse) {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		req.Header.Set("X-API-Key", key)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var raw json.RawMessage
+		_ = json.NewDecoder(resp.Body).Decode(&raw)
+		var job contracts.JobView
+		var errResp contracts.ErrorResponse
+		_ = json.Unmarshal(raw, &job)
+		_ = json.Unmarshal(raw, &errResp)
+		return resp.StatusCode, job, errResp
+	}
+
+	code, job, _ := call(http.MethodPost, "/v1/jobs", "demo-red-key", `{"model":"gpt-4o-mini","input":"summarize the login burst"}`)
+	if code != http.StatusAccepted || job.ID == "" || job.Status != "queued" {
+		t.Fatalf("expected a queued job, got %d %+v", code, job)
+	}
+	if code, _, _ = call(http.MethodPost, "/v1/jobs", "demo-red-key", `{"model":"claude-3-5-sonnet","input":"hi"}`); code != http.StatusForbidden {
+		t.Fatalf("expected the policy check at submission, got %d", code)
+	}
+	if code, got, _ := call(http.MethodGet, "/v1/jobs/"+job.ID, "demo-red-key", ""); code != http.StatusOK || got.ID != job.ID {
+		t.Fatalf("expected the job, got %d %+v", code, got)
+	}
+	if code, _, errResp := call(http.MethodGet, "/v1/jobs/"+job.ID, "demo-blue-key", ""); code != http.StatusNotFound || errResp.Code != app.CodeJobNotFound {
+		t.Fatalf("expected another team's job to be hidden, got %d %+v", code, errResp)
+	}
+	if code, got, _ := call(http.MethodDelete, "/v1/jobs/"+job.ID, "demo-red-key", ""); code != http.StatusOK || got.Status != "cancelled" {
+		t.Fatalf("expected the job to be cancelled, got %d %+v", code, got)
+	}
+	if code, _, errResp := call(http.MethodDelete, "/v1/jobs/"+job.ID, "demo-red-key", ""); code != http.StatusConflict || errResp.Code != app.CodeJobFinished {
+		t.Fatalf("expected %s, got %d %+v", app.CodeJobFinished, code, errResp)
+	}
+}
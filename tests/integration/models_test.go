@@ -0,0 +1,54 @@
+package integration
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/app"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+	"github.com/WaiperOK/llm-gateway-control-plane/pkg/contracts"
+)
+
+func TestModelsEndpointListsAllowedModels(t *testing.T) {
+	srv := newTestServer(t, config.Default())
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer demo-blue-key")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out contracts.ModelList
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || out.Object != "list" || len(out.Data) != 2 {
+		t.Fatalf("unexpected model list: %d %+v", resp.StatusCode, out)
+	}
+	claude := out.Data[0]
+	if claude.ID != "claude-3-5-sonnet" || claude.OwnedBy != "anthropic" || claude.ContextWindow != 200000 || claude.InputPricePer1KUSD != 0.006 {
+		t.Fatalf("unexpected catalog entry: %+v", claude)
+	}
+}
+
+func TestCompletionBeyondContextWindowRejected(t *testing.T) {
+	cfg := config.Default()
+	info := cfg.ModelCatalog["gpt-4o-mini"]
+	info.ContextWindow = 50
+	cfg.ModelCatalog["gpt-4o-mini"] = info
+	srv := newTestServer(t, cfg)
+	defer srv.Close()
+
+	body := `{"model":"gpt-4o-mini","messages":[{"role":"user","content":"` + strings.Repeat("login burst ", 30) + `"}]}`
+	resp := postChatCompletion(t, srv.URL, "demo-red-key", body)
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(raw), app.CodeContextLengthExceeded) {
+		t.Fatalf("expected %s, got %d %s", app.CodeContextLengthExceeded, resp.StatusCode, raw)
+	}
+}
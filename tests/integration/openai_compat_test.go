@@ -0,0 +1,238 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+)
+
+func postChatCompletion(t *testing.T, url, apiKey, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url+"/v1/chat/completions", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestChatCompletionsFacade(t *testing.T) {
+	srv := newTestServer(t, config.Default())
+	defer srv.Close()
+
+	resp := postChatCompletion(t, srv.URL, "demo-red-key", `{
+		"model": "gpt-4o-mini",
+		"messages": [
+			{"role": "system", "content": "You are a triage assistant."},
+			{"role": "user", "content": [{"type": "text", "text": "Summarize the login burst"}]}
+		],
+		"temperature": 0.2,
+		"max_tokens": 64,
+		"stop": "END",
+		"user": "analyst-7"
+	}`)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, b)
+	}
+	var out struct {
+		Object  string `json:"object"`
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage struct {
+			TotalTokens int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Object != "chat.completion" || out.Model != "gpt-4o-mini" || len(out.Choices) != 1 {
+		t.Fatalf("unexpected response: %+v", out)
+	}
+	if out.Choices[0].Message.Role != "assistant" || out.Choices[0].Message.Content == "" || out.Usage.TotalTokens == 0 {
+		t.Fatalf("unexpected choice/usage: %+v", out)
+	}
+}
+
+func TestChatCompletionsErrorShape(t *testing.T) {
+	srv := newTestServer(t, config.Default())
+	defer srv.Close()
+
+	resp := postChatCompletion(t, srv.URL, "demo-red-key", `{"model":"claude-3-5-sonnet","messages":[{"role":"user","content":"hi"}]}`)
+	defer resp.Body.Close()
+
+	var out struct {
+		Error struct {
+			Type string `json:"type"`
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusForbidden || out.Error.Type != "permission_error" || out.Error.Code != "policy_denied" {
+		t.Fatalf("unexpected error: %d %+v", resp.StatusCode, out)
+	}
+}
+
+func TestChatCompletionsStream(t *testing.T) {
+	srv := newTestServer(t, config.Default())
+	defer srv.Close()
+
+	resp := postChatCompletion(t, srv.URL, "demo-red-key", `{"model":"gpt-4o-mini","stream":true,"stream_options":{"include_usage":true},"messages":[{"role":"user","content":"hello"}]}`)
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	text := string(body)
+	if !strings.Contains(text, `"object":"chat.completion.chunk"`) || !strings.Contains(text, `"finish_reason":"stop"`) {
+		t.Fatalf("expected OpenAI chunks, got: %s", text)
+	}
+	if !strings.Contains(text, `"usage":{`) || !strings.HasSuffix(strings.TrimSpace(text), "data: [DONE]") {
+		t.Fatalf("expected usage chunk and [DONE] terminator, got: %s", text)
+	}
+}
+
+func TestChatCompletionsStreamReportsServingModel(t *testing.T) {
+	srv := newTestServer(t, config.Default())
+	defer srv.Close()
+
+	resp := postChatCompletion(t, srv.URL, "demo-red-key", `{"model":"fast","stream":true,"stream_options":{"include_usage":true},"messages":[{"role":"user","content":"hello"}]}`)
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	chunks := 0
+	for _, line := range strings.Split(string(body), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var chunk struct {
+			Model string `json:"model"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatal(err)
+		}
+		if chunk.Model != "gpt-4o-mini" {
+			t.Fatalf("expected every chunk to name the serving model, got %q in: %s", chunk.Model, body)
+		}
+		chunks++
+	}
+	if chunks < 3 {
+		t.Fatalf("expected content, finish and usage chunks, got: %s", body)
+	}
+}
+
+func TestChatCompletionsToolCalling(t *testing.T) {
+	srv := newTestServer(t, config.Default())
+	defer srv.Close()
+
+	resp := postChatCompletion(t, srv.URL, "demo-red-key", `{
+		"model": "gpt-4o-mini",
+		"messages": [{"role": "user", "content": "Where did 10.0.0.8 log in from?"}],
+		"tools": [{"type": "function", "function": {"name": "lookup_ip", "parameters": {"type": "object", "properties": {"input": {"type": "string"}}}}}],
+		"tool_choice": {"type": "function", "function": {"name": "lookup_ip"}}
+	}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, b)
+	}
+	var out struct {
+		Choices []struct {
+			Message struct {
+				ToolCalls []struct {
+					ID       string `json:"id"`
+					Type     string `json:"type"`
+					Function struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Choices) != 1 || out.Choices[0].FinishReason != "tool_calls" || len(out.Choices[0].Message.ToolCalls) != 1 {
+		t.Fatalf("unexpected response: %+v", out)
+	}
+	call := out.Choices[0].Message.ToolCalls[0]
+	if call.Type != "function" || call.Function.Name != "lookup_ip" || call.ID == "" {
+		t.Fatalf("unexpected tool call: %+v", call)
+	}
+
+	// blue-team has no tools on its allowlist.
+	denied := postChatCompletion(t, srv.URL, "demo-blue-key", `{
+		"model": "gpt-4o-mini",
+		"messages": [{"role": "user", "content": "hi"}],
+		"tools": [{"type": "function", "function": {"name": "run_shell"}}]
+	}`)
+	defer denied.Body.Close()
+	if denied.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for disallowed tool, got %d", denied.StatusCode)
+	}
+}
+
+func TestChatCompletionsStructuredOutput(t *testing.T) {
+	srv := newTestServer(t, config.Default())
+	defer srv.Close()
+
+	resp := postChatCompletion(t, srv.URL, "demo-red-key", `{
+		"model": "gpt-4o-mini",
+		"messages": [{"role": "user", "content": "Classify the login burst from 10.0.0.8"}],
+		"response_format": {"type": "json_schema", "json_schema": {"name": "triage", "strict": true, "schema": {
+			"type": "object",
+			"properties": {"severity": {"type": "string", "enum": ["low", "high"]}, "score": {"type": "integer", "minimum": 1}},
+			"required": ["severity", "score"]
+		}}}
+	}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, b)
+	}
+	var out struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Severity string `json:"severity"`
+		Score    int    `json:"score"`
+	}
+	if len(out.Choices) != 1 || json.Unmarshal([]byte(out.Choices[0].Message.Content), &doc) != nil || doc.Severity != "low" || doc.Score != 1 {
+		t.Fatalf("unexpected structured output: %+v", out)
+	}
+
+	invalid := postChatCompletion(t, srv.URL, "demo-red-key", `{
+		"model": "gpt-4o-mini",
+		"messages": [{"role": "user", "content": "hi"}],
+		"response_format": {"type": "json_schema", "json_schema": {"name": "x", "schema": {"$ref": "#/$defs/x"}}}
+	}`)
+	defer invalid.Body.Close()
+	if invalid.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unsupported schema, got %d", invalid.StatusCode)
+	}
+}
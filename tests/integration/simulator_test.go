@@ -0,0 +1,84 @@
+package integration
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/app"
+	"github.com/WaiperOK/llm-gateway-control-plane/internal/config"
+)
+
+// newScenarioServer serves cfg with its simulated provider following scenario,
+// behind the production router and retry stack.
+func newScenarioServer(t *testing.T, cfg config.Config, timeoutMS int, scenario config.SimulationScenario) *httptest.Server {
+	t.Helper()
+	cfg.Providers = []config.ProviderConfig{{Name: "simulated", Type: "simulated", TimeoutMS: timeoutMS, Scenario: &scenario}}
+	cfg.Retry = config.RetryConfig{MaxAttempts: 3, BaseDelayMS: 1, MaxDelayMS: 2}
+	router, err := app.NewRouter(cfg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newTestServerWithClient(t, cfg, app.NewRetryingClient(router, cfg.Retry, nil))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+type chatResult struct {
+	Model string `json:"model"`
+	Error struct {
+		Code string `json:"code"`
+	} `json:"error"`
+}
+
+func chatOutcome(t *testing.T, srv *httptest.Server, apiKey, model string) (int, chatResult) {
+	t.Helper()
+	resp := postChatCompletion(t, srv.URL, apiKey, `{"model":"`+model+`","messages":[{"role":"user","content":"triage the login burst"}]}`)
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	var out chatResult
+	if err := json.Unmarshal(raw, &out); err != nil {
+		t.Fatalf("decode %s: %v", raw, err)
+	}
+	return resp.StatusCode, out
+}
+
+func TestScenarioTransientFailuresAreRetried(t *testing.T) {
+	srv := newScenarioServer(t, config.Default(), 0, config.SimulationScenario{Models: map[string]config.SimulatedBehavior{
+		"gpt-4o-mini": {Script: []string{app.FaultUnavailable, app.FaultRateLimited}},
+	}})
+	if code, out := chatOutcome(t, srv, "demo-red-key", "gpt-4o-mini"); code != http.StatusOK || out.Model != "gpt-4o-mini" {
+		t.Fatalf("expected the third attempt to succeed, got %d %+v", code, out)
+	}
+}
+
+func TestScenarioPersistentFailureFallsBack(t *testing.T) {
+	srv := newScenarioServer(t, config.Default(), 0, config.SimulationScenario{Models: map[string]config.SimulatedBehavior{
+		"gpt-4.1-mini": {ErrorRate: 1, Errors: []string{app.FaultUnreachable}},
+	}})
+	if code, out := chatOutcome(t, srv, "demo-red-key", "gpt-4.1-mini"); code != http.StatusOK || out.Model != "gpt-4o-mini" {
+		t.Fatalf("expected the fallback model to serve, got %d %+v", code, out)
+	}
+}
+
+func TestScenarioTimeoutSurfaces(t *testing.T) {
+	srv := newScenarioServer(t, config.Default(), 20, config.SimulationScenario{Models: map[string]config.SimulatedBehavior{
+		"*": {Script: []string{app.FaultTimeout, app.FaultTimeout, app.FaultTimeout}},
+	}})
+	if code, out := chatOutcome(t, srv, "demo-blue-key", "claude-3-5-sonnet"); code != http.StatusGatewayTimeout || out.Error.Code != app.CodeUpstreamTimeout {
+		t.Fatalf("expected %s, got %d %+v", app.CodeUpstreamTimeout, code, out)
+	}
+}
+
+func TestScenarioLongOutputOverrunsBudget(t *testing.T) {
+	cfg := config.Default()
+	cfg.Teams[0].MonthlyBudgetUSD = 0.005
+	srv := newScenarioServer(t, cfg, 0, config.SimulationScenario{Models: map[string]config.SimulatedBehavior{
+		"*": {OutputTokens: 5000},
+	}})
+	if code, out := chatOutcome(t, srv, "demo-red-key", "gpt-4o-mini"); code != http.StatusPaymentRequired || out.Error.Code != "budget_exceeded" {
+		t.Fatalf("expected the long answer to overrun the budget, got %d %+v", code, out)
+	}
+}